@@ -0,0 +1,118 @@
+package amplitude
+
+import (
+	"context"
+	"time"
+
+	of "github.com/open-feature/go-sdk/openfeature"
+)
+
+// AuditRecord describes a flag decision, for recording in an audit trail.
+// See [WithDecisionAuditSink].
+type AuditRecord struct {
+	// UserID is the Amplitude user ID the flag was evaluated for.
+	// If no user was built (because of an error or a forced default),
+	// it is the targeting key from the evaluation context.
+	UserID string
+	// DeviceID is the Amplitude device ID the flag was evaluated for, if any.
+	DeviceID string
+	// Flag is the key of the evaluated flag.
+	Flag string
+	// Variant is the key of the variant the user was assigned, which is empty if the evaluation failed.
+	Variant string
+	// Reason is the reason for the decision.
+	Reason of.Reason
+	// Timestamp is when the decision was made.
+	Timestamp time.Time
+	// FlagVersion is the version of the flag config which produced the variant,
+	// or zero if Amplitude did not report it (see [MetadataKeyFlagVersion]).
+	FlagVersion int64
+	// Err is the resolution error, if the evaluation failed.
+	// Failed evaluations are only recorded if [AuditErrors] is set.
+	Err error
+}
+
+// AuditConfig configures the decision audit sink.
+type AuditConfig struct {
+	// Sink receives a record of each flag decision.
+	Sink func(ctx context.Context, record AuditRecord)
+	// Async calls the sink from a goroutine owned by the provider, so that it doesn't add latency
+	// to evaluation; see [AuditAsync].
+	Async bool
+	// BufferSize is the number of records buffered for the sink with Async; see [AuditBufferSize].
+	// If zero, 1,024 records are buffered.
+	BufferSize int
+	// IncludeErrors records failed evaluations as well as successful ones.
+	IncludeErrors bool
+}
+
+// AuditOption configures the decision audit sink.
+type AuditOption func(*AuditConfig)
+
+// AuditAsync calls the audit sink from a goroutine owned by the provider, one record at a time,
+// so that it doesn't add latency to evaluation. Records are buffered (see [AuditBufferSize]) and
+// dropped when the buffer is full, as counted by [Provider.DroppedAuditRecords]. The context passed
+// to the sink has the values of the evaluation's context, but isn't cancelled with it.
+// [Provider.Close] waits for the buffered records to be passed to the sink.
+// A panic in the sink is recovered and logged.
+func AuditAsync() AuditOption {
+	return func(c *AuditConfig) {
+		c.Async = true
+	}
+}
+
+// AuditBufferSize sets the number of records buffered for the sink with [AuditAsync];
+// decisions made while the buffer is full aren't recorded.
+func AuditBufferSize(size int) AuditOption {
+	return func(c *AuditConfig) {
+		c.BufferSize = size
+	}
+}
+
+// AuditErrors records failed evaluations (with [AuditRecord.Err] set) as well as successful ones.
+func AuditErrors() AuditOption {
+	return func(c *AuditConfig) {
+		c.IncludeErrors = true
+	}
+}
+
+// auditDecision sends a record of the decision to the audit sink.
+func (p *Provider) auditDecision(ctx context.Context, flag string, evalCtx of.FlattenedContext, eval *flagEvaluation, resErr *resolutionError) {
+	audit := p.config.DecisionAudit
+	if resErr != nil && !audit.IncludeErrors {
+		return
+	}
+
+	record := AuditRecord{
+		Flag:      flag,
+		Timestamp: time.Now(),
+	}
+	if eval != nil && eval.user != nil {
+		record.UserID = eval.user.UserId
+		record.DeviceID = eval.user.DeviceId
+	} else {
+		record.UserID, _ = evalCtx[of.TargetingKey].(string)
+	}
+	record.Reason = decisionReason(eval, resErr)
+	if resErr != nil {
+		record.Err = resErr.ResolutionError
+	} else {
+		record.Variant = eval.evaluated.Key
+		record.FlagVersion, _ = flagVersion(eval.evaluated.Metadata["flagVersion"])
+	}
+
+	if p.auditSink != nil {
+		p.auditSink.send(ctx, record)
+		return
+	}
+	audit.Sink(ctx, record)
+}
+
+// DroppedAuditRecords returns the number of audit records which were dropped rather than
+// passed to the sink with [AuditAsync], because its buffer was full (see [AuditBufferSize]).
+func (p *Provider) DroppedAuditRecords() uint64 {
+	if p.auditSink == nil {
+		return 0
+	}
+	return p.auditSink.dropped.Load()
+}
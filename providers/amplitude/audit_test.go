@@ -0,0 +1,169 @@
+package amplitude
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/amplitude/experiment-go-server/pkg/experiment"
+	of "github.com/open-feature/go-sdk/openfeature"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProvider_DecisionAuditSink(t *testing.T) {
+	variantMock := func(variant experiment.Variant) *mockClientAdapter {
+		return &mockClientAdapter{
+			EvaluateFunc: func(_ context.Context, _ *experiment.User, flagKeys []string) (map[string]experiment.Variant, error) {
+				return map[string]experiment.Variant{flagKeys[0]: variant}, nil
+			},
+		}
+	}
+
+	t.Run("records successful decisions synchronously", func(t *testing.T) {
+		var records []AuditRecord
+		variant := makeVariant("on", "on", true)
+		variant.Metadata = map[string]any{"flagVersion": float64(7)}
+		provider := newTestProvider(t, variantMock(variant),
+			WithDecisionAuditSink(func(_ context.Context, record AuditRecord) {
+				records = append(records, record)
+			}),
+			WithReasonMapper(func(string, *experiment.Variant) of.Reason { return of.TargetingMatchReason }),
+		)
+
+		before := time.Now()
+		provider.BooleanEvaluation(context.Background(), "test-flag", false, of.FlattenedContext{of.TargetingKey: "user-1"})
+
+		require.Len(t, records, 1)
+		record := records[0]
+		assert.Equal(t, "user-1", record.UserID)
+		assert.Equal(t, "test-flag", record.Flag)
+		assert.Equal(t, "on", record.Variant)
+		assert.Equal(t, of.TargetingMatchReason, record.Reason)
+		assert.Equal(t, int64(7), record.FlagVersion)
+		assert.False(t, record.Timestamp.Before(before))
+		assert.NoError(t, record.Err)
+	})
+
+	t.Run("records off decisions with the off reason", func(t *testing.T) {
+		var records []AuditRecord
+		provider := newTestProvider(t, variantMock(experiment.Variant{Key: "off"}),
+			WithDecisionAuditSink(func(_ context.Context, record AuditRecord) {
+				records = append(records, record)
+			}),
+		)
+
+		provider.BooleanEvaluation(context.Background(), "test-flag", false, of.FlattenedContext{of.TargetingKey: "user-1"})
+
+		require.Len(t, records, 1)
+		assert.Equal(t, "off", records[0].Variant)
+		assert.Equal(t, of.DefaultReason, records[0].Reason)
+	})
+
+	t.Run("records forced defaults", func(t *testing.T) {
+		var records []AuditRecord
+		provider := newTestProvider(t, &mockClientAdapter{},
+			WithForcedDefaults("killed-flag"),
+			WithDecisionAuditSink(func(_ context.Context, record AuditRecord) {
+				records = append(records, record)
+			}),
+		)
+
+		provider.BooleanEvaluation(context.Background(), "killed-flag", false, of.FlattenedContext{of.TargetingKey: "user-1"})
+
+		require.Len(t, records, 1)
+		assert.Equal(t, "user-1", records[0].UserID)
+		assert.Empty(t, records[0].Variant)
+		assert.Equal(t, of.DisabledReason, records[0].Reason)
+	})
+
+	t.Run("skips errors by default", func(t *testing.T) {
+		var records []AuditRecord
+		provider := newTestProvider(t, &mockClientAdapter{},
+			WithDecisionAuditSink(func(_ context.Context, record AuditRecord) {
+				records = append(records, record)
+			}),
+		)
+
+		provider.BooleanEvaluation(context.Background(), "missing-flag", false, of.FlattenedContext{of.TargetingKey: "user-1"})
+
+		assert.Empty(t, records)
+	})
+
+	t.Run("records errors with AuditErrors", func(t *testing.T) {
+		var records []AuditRecord
+		provider := newTestProvider(t, &mockClientAdapter{},
+			WithDecisionAuditSink(func(_ context.Context, record AuditRecord) {
+				records = append(records, record)
+			}, AuditErrors()),
+		)
+
+		provider.BooleanEvaluation(context.Background(), "missing-flag", false, of.FlattenedContext{of.TargetingKey: "user-1"})
+
+		require.Len(t, records, 1)
+		assert.Equal(t, "user-1", records[0].UserID)
+		assert.Equal(t, "missing-flag", records[0].Flag)
+		assert.Empty(t, records[0].Variant)
+		assert.Equal(t, of.ErrorReason, records[0].Reason)
+		assert.ErrorContains(t, records[0].Err, string(of.FlagNotFoundCode))
+	})
+
+	t.Run("calls the sink asynchronously with AuditAsync", func(t *testing.T) {
+		release := make(chan struct{})
+		var wg sync.WaitGroup
+		wg.Add(1)
+		var record AuditRecord
+		provider := newTestProvider(t, variantMock(makeVariant("on", "on", true)),
+			WithDecisionAuditSink(func(ctx context.Context, r AuditRecord) {
+				defer wg.Done()
+				<-release
+				assert.NoError(t, ctx.Err())
+				record = r
+			}, AuditAsync()),
+		)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		result := provider.BooleanEvaluation(ctx, "test-flag", false, of.FlattenedContext{of.TargetingKey: "user-1"})
+		cancel()
+		close(release)
+		wg.Wait()
+
+		assert.True(t, result.Value)
+		assert.Equal(t, "on", record.Variant)
+	})
+
+	t.Run("drops records when the async buffer is full", func(t *testing.T) {
+		release := make(chan struct{})
+		var passed atomic.Int32
+		provider := newTestProvider(t, variantMock(makeVariant("on", "on", true)),
+			WithDecisionAuditSink(func(context.Context, AuditRecord) {
+				<-release
+				passed.Add(1)
+			}, AuditAsync(), AuditBufferSize(2)),
+		)
+		goroutines := runtime.NumGoroutine()
+
+		evaluated := make(chan struct{})
+		go func() {
+			defer close(evaluated)
+			for range 10 {
+				provider.BooleanEvaluation(context.Background(), "test-flag", false, of.FlattenedContext{of.TargetingKey: "user-1"})
+			}
+		}()
+		select {
+		case <-evaluated:
+		case <-time.After(time.Second):
+			t.Fatal("a blocking audit sink stalled evaluation")
+		}
+		assert.Less(t, runtime.NumGoroutine(), goroutines+5, "the sink should not be called from a goroutine per decision")
+		close(release)
+		require.NoError(t, provider.Close())
+
+		// The worker holds one record and the buffer two more, so at least 7 of the 10 are dropped.
+		assert.GreaterOrEqual(t, provider.DroppedAuditRecords(), uint64(7))
+		assert.EqualValues(t, 10, uint64(passed.Load())+provider.DroppedAuditRecords())
+	})
+}
@@ -0,0 +1,38 @@
+package amplitude
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/amplitude/experiment-go-server/pkg/experiment"
+	of "github.com/open-feature/go-sdk/openfeature"
+)
+
+// EvaluateAsOf evaluates a flag for a historical context, such as one reconstructed from
+// past events, to backfill experiment exposures. Rather than a real-time exposure, the exposure
+// event (if tracking is enabled; see [WithTrackingEnabled]) is backdated to at.
+//
+// The Amplitude SDKs only hold the current flag configs, and Amplitude doesn't serve the
+// config versions which were active in the past, so the flag is evaluated with its current config.
+// The version used is reported in the "flagVersion" metadata of the variant, to help callers
+// detect a config which changed since at.
+//
+// Like [Provider.EvaluateFlags], the variant is returned as is, including the "off" variant.
+// It returns a [of.FlagNotFoundCode] resolution error if the flag doesn't exist.
+func (p *Provider) EvaluateAsOf(ctx context.Context, evalCtx of.FlattenedContext, flag string, at time.Time) (experiment.Variant, error) {
+	if at.IsZero() {
+		return experiment.Variant{}, errors.New("the time to evaluate the flag as of must be set")
+	}
+	variants, user, err := p.evaluateFlags(ctx, evalCtx, []string{flag})
+	if err != nil {
+		return experiment.Variant{}, err
+	}
+	variant, ok := variants[flag]
+	if !ok {
+		return experiment.Variant{}, of.NewFlagNotFoundResolutionError(fmt.Sprintf("flag %s not found", flag))
+	}
+	p.trackExposure(ctx, evalCtx, user, flag, variant, at)
+	return variant, nil
+}
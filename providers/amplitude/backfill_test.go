@@ -0,0 +1,70 @@
+package amplitude
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/amplitude/experiment-go-server/pkg/experiment"
+	of "github.com/open-feature/go-sdk/openfeature"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProvider_EvaluateAsOf(t *testing.T) {
+	at := time.Date(2024, time.March, 1, 12, 30, 0, 0, time.UTC)
+	evalCtx := of.FlattenedContext{of.TargetingKey: "user-1"}
+	newProvider := func(t *testing.T) (*Provider, *mockAnalyticsClient) {
+		t.Helper()
+		variant := makeVariant("treatment", "treatment", nil)
+		variant.Metadata = map[string]any{"flagVersion": 7}
+		return newTrackingTestProvider(t, &mockClientAdapter{
+			EvaluateFunc: func(_ context.Context, _ *experiment.User, _ []string) (map[string]experiment.Variant, error) {
+				return map[string]experiment.Variant{"test-flag": variant}, nil
+			},
+		})
+	}
+
+	t.Run("tracks a backdated exposure", func(t *testing.T) {
+		provider, analyticsClient := newProvider(t)
+
+		variant, err := provider.EvaluateAsOf(context.Background(), evalCtx, "test-flag", at)
+
+		require.NoError(t, err)
+		assert.Equal(t, "treatment", variant.Key)
+		assert.Equal(t, 7, variant.Metadata["flagVersion"])
+		require.Len(t, analyticsClient.events, 1)
+		event := analyticsClient.events[0]
+		assert.Equal(t, "$exposure", event.EventType)
+		assert.Equal(t, "user-1", event.UserID)
+		assert.Equal(t, at.UnixMilli(), event.Time)
+		assert.Equal(t, "test-flag", event.EventProperties["flag_key"])
+	})
+
+	t.Run("real-time exposures are not backdated", func(t *testing.T) {
+		provider, analyticsClient := newProvider(t)
+
+		provider.StringEvaluation(context.Background(), "test-flag", "default", evalCtx)
+
+		require.Len(t, analyticsClient.events, 1)
+		assert.Zero(t, analyticsClient.events[0].Time)
+	})
+
+	t.Run("unknown flag", func(t *testing.T) {
+		provider, analyticsClient := newProvider(t)
+
+		_, err := provider.EvaluateAsOf(context.Background(), evalCtx, "missing-flag", at)
+
+		assert.ErrorContains(t, err, string(of.FlagNotFoundCode))
+		assert.Empty(t, analyticsClient.events)
+	})
+
+	t.Run("the time is required", func(t *testing.T) {
+		provider, analyticsClient := newProvider(t)
+
+		_, err := provider.EvaluateAsOf(context.Background(), evalCtx, "test-flag", time.Time{})
+
+		assert.Error(t, err)
+		assert.Empty(t, analyticsClient.events)
+	})
+}
@@ -0,0 +1,162 @@
+package amplitude
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/amplitude/experiment-go-server/pkg/experiment"
+	of "github.com/open-feature/go-sdk/openfeature"
+)
+
+// defaultBatchConcurrency is the number of users evaluated at once by [Provider.EvaluateForUsers],
+// unless configured with [WithBatchConcurrency].
+// It keeps local evaluation busy without flooding Amplitude with remote evaluation requests.
+const defaultBatchConcurrency = 8
+
+// BatchError is returned by [Provider.EvaluateForUsers] when the evaluation failed for some users.
+type BatchError struct {
+	// Errs has an entry for each user, in the same order as the users:
+	// nil if the evaluation succeeded, and otherwise why it failed.
+	Errs []error
+}
+
+// Error summarizes the failures, including the first one.
+func (e *BatchError) Error() string {
+	failed := 0
+	var first error
+	firstIndex := 0
+	for i, err := range e.Errs {
+		if err == nil {
+			continue
+		}
+		if first == nil {
+			first, firstIndex = err, i
+		}
+		failed++
+	}
+	return fmt.Sprintf("failed to evaluate flags for %d of %d users (user %d: %v)", failed, len(e.Errs), firstIndex, first)
+}
+
+// Unwrap returns the errors of the users for which the evaluation failed.
+func (e *BatchError) Unwrap() []error {
+	var errs []error
+	for _, err := range e.Errs {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// EvaluateForUsers evaluates the given flags for each of the given contexts, for batch jobs such as
+// offline experiment analysis. If flagKeys is empty, all flags are evaluated, as with [Provider.EvaluateAll];
+// otherwise only the given flags are returned, as with [Provider.EvaluateFlags].
+// Results are in the same order as users. No exposure events are tracked.
+//
+// Users are evaluated by a pool of workers (see [WithBatchConcurrency]): local evaluation uses
+// the resident flag configs, and remote evaluation makes at most one request per worker at once,
+// each retried according to the remote config, and sharing the remote evaluation cache
+// (see [WithRemoteEvaluationCache]), if any.
+//
+// A failure for one user doesn't fail the batch: the user's result is nil, and a [*BatchError]
+// describing every failure is returned along with the results. If ctx is done before the batch
+// completes, remote evaluation requests in flight are cancelled, and the users which weren't
+// evaluated fail with the context's error.
+func (p *Provider) EvaluateForUsers(ctx context.Context, users []of.FlattenedContext, flagKeys []string) ([]map[string]experiment.Variant, error) {
+	if p.state != of.ReadyState {
+		return nil, p.stateError()
+	}
+
+	concurrency := p.config.BatchConcurrency
+	if concurrency <= 0 {
+		concurrency = defaultBatchConcurrency
+	}
+	results := make([]map[string]experiment.Variant, len(users))
+	errs := make([]error, len(users))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for range min(concurrency, len(users)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				if err := ctx.Err(); err != nil {
+					errs[i] = err
+					continue
+				}
+				results[i], errs[i] = p.evaluateUser(ctx, users[i], flagKeys)
+			}
+		}()
+	}
+	for i := range users {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return results, &BatchError{Errs: errs}
+		}
+	}
+	return results, nil
+}
+
+// evaluateUser evaluates the given flags (or all flags, if flagKeys is empty) for a single user of a batch.
+func (p *Provider) evaluateUser(ctx context.Context, evalCtx of.FlattenedContext, flagKeys []string) (map[string]experiment.Variant, error) {
+	if len(flagKeys) == 0 {
+		variants, _, err := p.evaluateAll(ctx, evalCtx)
+		return variants, err
+	}
+	variants, _, err := p.evaluateFlags(ctx, evalCtx, flagKeys)
+	return variants, err
+}
+
+// BatchEvaluation evaluates the given flags for a single context, such as the flags needed to render a page,
+// with a single evaluation by the Amplitude client (one request, with remote evaluation),
+// and returns the resolution of each flag keyed by flag key.
+// Each flag is resolved exactly as [Provider.ObjectEvaluation] would with a nil default value,
+// so flags which are off have a nil value and the off reason, payloads are decoded as configured,
+// and an exposure event is tracked for each flag.
+// If the evaluation fails, every flag has the error. While the provider isn't ready,
+// flags with a not-ready default (see [WithNotReadyDefaults]) resolve to it.
+func (p *Provider) BatchEvaluation(ctx context.Context, flags []string, evalCtx of.FlattenedContext) map[string]of.InterfaceResolutionDetail {
+	results := make(map[string]of.InterfaceResolutionDetail, len(flags))
+	if len(flags) == 0 {
+		return results
+	}
+	// While the provider isn't ready, there's nothing to evaluate at once: each flag resolves
+	// to its not-ready default (see [WithNotReadyDefaults]) or the state error.
+	if p.state != of.ReadyState {
+		for _, flag := range flags {
+			results[flag] = p.ObjectEvaluation(ctx, flag, nil, evalCtx)
+		}
+		return results
+	}
+
+	variants, user, err := p.evaluateFlags(ctx, evalCtx, flags)
+	if err != nil {
+		// evaluateFlags omits flags which aren't found rather than failing, so the error is never
+		// a FLAG_NOT_FOUND error which the fallback provider could resolve; its code isn't needed.
+		resErr := &resolutionError{}
+		if !errors.As(err, &resErr.ResolutionError) {
+			resErr = newResolutionError(of.GeneralCode, err.Error())
+		}
+		for _, flag := range flags {
+			p.notifyFallback(flag, resErr)
+			results[flag] = p.objectResolution(ctx, flag, nil, evalCtx, nil, resErr)
+		}
+		return results
+	}
+
+	// Resolve the flags from the variants just evaluated, rather than evaluating them again.
+	if memoCtx, err := contextWithEvaluatedVariants(ctx, user, variants); err == nil {
+		ctx = memoCtx
+	}
+	for _, flag := range flags {
+		results[flag] = p.ObjectEvaluation(ctx, flag, nil, evalCtx)
+	}
+	return results
+}
@@ -0,0 +1,271 @@
+package amplitude
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/amplitude/experiment-go-server/pkg/experiment"
+	"github.com/amplitude/experiment-go-server/pkg/experiment/remote"
+	"github.com/amplitude/experiment-go-server/pkg/logger"
+	of "github.com/open-feature/go-sdk/openfeature"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProvider_EvaluateForUsers(t *testing.T) {
+	// The mock returns the user ID as the variant of every flag, like remote evaluation returns all flags.
+	var inFlight, maxInFlight atomic.Int32
+	mock := &mockClientAdapter{
+		EvaluateFunc: func(_ context.Context, user *experiment.User, _ []string) (map[string]experiment.Variant, error) {
+			n := inFlight.Add(1)
+			defer inFlight.Add(-1)
+			for {
+				current := maxInFlight.Load()
+				if n <= current || maxInFlight.CompareAndSwap(current, n) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond)
+			if user.UserId == "bad-user" {
+				return nil, errMockEvaluate
+			}
+			return map[string]experiment.Variant{
+				"flag-a": {Key: user.UserId},
+				"flag-b": {Key: user.UserId},
+			}, nil
+		},
+	}
+	provider := newTestProvider(t, mock)
+	users := make([]of.FlattenedContext, 50)
+	for i := range users {
+		users[i] = of.FlattenedContext{of.TargetingKey: fmt.Sprintf("user-%d", i)}
+	}
+
+	t.Run("results align with users", func(t *testing.T) {
+		results, err := provider.EvaluateForUsers(context.Background(), users, []string{"flag-a"})
+
+		require.NoError(t, err)
+		require.Len(t, results, len(users))
+		for i, result := range results {
+			assert.Equal(t, map[string]experiment.Variant{"flag-a": {Key: users[i][of.TargetingKey].(string)}}, result)
+		}
+		assert.LessOrEqual(t, maxInFlight.Load(), int32(defaultBatchConcurrency))
+		assert.Len(t, mock.evaluateCalls, len(users))
+		assert.Greater(t, maxInFlight.Load(), int32(1), "users should be evaluated concurrently")
+	})
+
+	t.Run("all flags when none are given", func(t *testing.T) {
+		results, err := provider.EvaluateForUsers(context.Background(), users[:1], nil)
+
+		require.NoError(t, err)
+		assert.Len(t, results[0], 2)
+	})
+
+	t.Run("errors are reported per user", func(t *testing.T) {
+		batch := []of.FlattenedContext{users[0], {of.TargetingKey: "bad-user"}, users[1]}
+
+		results, err := provider.EvaluateForUsers(context.Background(), batch, []string{"flag-a"})
+
+		var batchErr *BatchError
+		require.ErrorAs(t, err, &batchErr)
+		assert.ErrorContains(t, batchErr.Errs[1], errMockEvaluate.Error())
+		assert.ErrorContains(t, err, "failed to evaluate flags for 1 of 3 users (user 1:")
+		assert.NoError(t, batchErr.Errs[0])
+		assert.Error(t, batchErr.Errs[1])
+		assert.NoError(t, batchErr.Errs[2])
+		require.Len(t, results, 3)
+		assert.NotNil(t, results[0])
+		assert.Nil(t, results[1])
+		assert.NotNil(t, results[2])
+	})
+
+	t.Run("cancelled context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		results, err := provider.EvaluateForUsers(ctx, users, nil)
+
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Len(t, results, len(users))
+	})
+
+	t.Run("not ready", func(t *testing.T) {
+		notReady := &Provider{client: mock, state: of.NotReadyState}
+
+		_, err := notReady.EvaluateForUsers(context.Background(), users, nil)
+
+		assert.ErrorContains(t, err, providerNotReady)
+	})
+}
+
+func TestProvider_EvaluateForUsers_RemoteCancellation(t *testing.T) {
+	provider := newSlowRemoteProvider(t)
+	users := make([]of.FlattenedContext, 20)
+	for i := range users {
+		users[i] = of.FlattenedContext{of.TargetingKey: fmt.Sprintf("user-%d", i)}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := provider.EvaluateForUsers(ctx, users, nil)
+
+	var batchErr *BatchError
+	require.ErrorAs(t, err, &batchErr)
+	for i, userErr := range batchErr.Errs {
+		assert.ErrorContains(t, userErr, context.DeadlineExceeded.Error(), "user %d", i)
+	}
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestProvider_EvaluateForUsers_BatchConcurrency(t *testing.T) {
+	var inFlight, maxInFlight atomic.Int32
+	mock := &mockClientAdapter{
+		EvaluateFunc: func(context.Context, *experiment.User, []string) (map[string]experiment.Variant, error) {
+			n := inFlight.Add(1)
+			defer inFlight.Add(-1)
+			for {
+				current := maxInFlight.Load()
+				if n <= current || maxInFlight.CompareAndSwap(current, n) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond)
+			return map[string]experiment.Variant{}, nil
+		},
+	}
+	provider := newTestProvider(t, mock, WithBatchConcurrency(2))
+	users := make([]of.FlattenedContext, 20)
+	for i := range users {
+		users[i] = of.FlattenedContext{of.TargetingKey: fmt.Sprintf("user-%d", i)}
+	}
+
+	_, err := provider.EvaluateForUsers(context.Background(), users, nil)
+
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, maxInFlight.Load())
+}
+
+func TestProvider_BatchEvaluation(t *testing.T) {
+	// The mock returns all flags, like remote evaluation.
+	mock := &mockClientAdapter{
+		EvaluateFunc: func(context.Context, *experiment.User, []string) (map[string]experiment.Variant, error) {
+			return map[string]experiment.Variant{
+				"object-flag":  makeVariant("on", "on", map[string]any{"color": "blue"}),
+				"off-flag":     makeVariant("off", "", nil),
+				"string-flag":  makeVariant("control", "control", "stringified"),
+				"unrequested":  makeVariant("on", "on", nil),
+				"empty-object": makeVariant("on", "on", nil),
+			}, nil
+		},
+	}
+	evalCtx := of.FlattenedContext{of.TargetingKey: "user-1"}
+
+	t.Run("evaluates all flags at once", func(t *testing.T) {
+		mock.evaluateCalls = nil
+		provider := newTestProvider(t, mock)
+
+		results := provider.BatchEvaluation(context.Background(), []string{"object-flag", "off-flag", "string-flag", "empty-object", "missing-flag"}, evalCtx)
+
+		require.Len(t, mock.evaluateCalls, 1)
+		assert.Equal(t, []string{"object-flag", "off-flag", "string-flag", "empty-object", "missing-flag"}, mock.evaluateCalls[0].FlagKeys)
+		require.Len(t, results, 5)
+		assert.Equal(t, map[string]any{"color": "blue"}, results["object-flag"].Value)
+		assert.Equal(t, "on", results["object-flag"].Variant)
+		assert.Nil(t, results["off-flag"].Value)
+		assert.Equal(t, of.DefaultReason, results["off-flag"].Reason)
+		assert.Equal(t, "stringified", results["string-flag"].Value)
+		assert.Nil(t, results["empty-object"].Value)
+		assert.Equal(t, of.ErrorReason, results["missing-flag"].Reason)
+		assert.ErrorContains(t, results["missing-flag"].ResolutionError, "FLAG_NOT_FOUND")
+	})
+
+	t.Run("matches the single-flag path", func(t *testing.T) {
+		provider := newTestProvider(t, mock, WithUnwrapStringifiedObjects())
+		flags := []string{"object-flag", "off-flag", "string-flag"}
+
+		results := provider.BatchEvaluation(context.Background(), flags, evalCtx)
+
+		for _, flag := range flags {
+			expected := provider.ObjectEvaluation(context.Background(), flag, nil, evalCtx)
+			assert.Equal(t, expected.Value, results[flag].Value, flag)
+			assert.Equal(t, expected.Variant, results[flag].Variant, flag)
+			assert.Equal(t, expected.Reason, results[flag].Reason, flag)
+		}
+	})
+
+	t.Run("evaluation errors are reported for every flag", func(t *testing.T) {
+		failing := &mockClientAdapter{
+			EvaluateFunc: func(context.Context, *experiment.User, []string) (map[string]experiment.Variant, error) {
+				return nil, errMockEvaluate
+			},
+		}
+		provider := newTestProvider(t, failing)
+
+		results := provider.BatchEvaluation(context.Background(), []string{"flag-a", "flag-b"}, evalCtx)
+
+		assert.Len(t, failing.evaluateCalls, 1)
+		for _, flag := range []string{"flag-a", "flag-b"} {
+			assert.Equal(t, of.ErrorReason, results[flag].Reason)
+			assert.ErrorContains(t, results[flag].ResolutionError, errMockEvaluate.Error())
+		}
+	})
+
+	t.Run("not ready", func(t *testing.T) {
+		notReady := &Provider{client: mock, state: of.NotReadyState}
+
+		results := notReady.BatchEvaluation(context.Background(), []string{"flag-a"}, evalCtx)
+
+		assert.ErrorContains(t, results["flag-a"].ResolutionError, "PROVIDER_NOT_READY")
+	})
+
+	t.Run("not ready with safe values", func(t *testing.T) {
+		mock.evaluateCalls = nil
+		notReady, err := New(context.Background(), "test-key", withMockClient(mock),
+			WithNotReadyDefaults(map[string]any{"flag-a": map[string]any{"color": "grey"}}))
+		require.NoError(t, err)
+
+		results := notReady.BatchEvaluation(context.Background(), []string{"flag-a", "flag-b"}, evalCtx)
+
+		assert.Empty(t, mock.evaluateCalls)
+		assert.NoError(t, results["flag-a"].Error())
+		assert.Equal(t, map[string]any{"color": "grey"}, results["flag-a"].Value)
+		assert.Equal(t, NotReadyDefaultReason, results["flag-a"].Reason)
+		assert.ErrorContains(t, results["flag-b"].ResolutionError, "PROVIDER_NOT_READY")
+	})
+
+	t.Run("no flags", func(t *testing.T) {
+		provider := newTestProvider(t, mock)
+
+		assert.Empty(t, provider.BatchEvaluation(context.Background(), nil, evalCtx))
+	})
+
+	t.Run("remote evaluation fetches once", func(t *testing.T) {
+		var requests atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			requests.Add(1)
+			_, _ = w.Write([]byte(`{"flag-a":{"key":"on","value":"on"},"flag-b":{"key":"treatment","value":"treatment"}}`))
+		}))
+		t.Cleanup(server.Close)
+		provider, err := New(context.Background(), uniqueDeploymentKey(t),
+			WithRemoteConfig(remote.Config{ServerUrl: server.URL, LogLevel: logger.Warn, LoggerProvider: &recordingLoggerProvider{}}),
+			WithRemoteFetchRetries(-1),
+			WithRemoteReadinessProbe(false),
+		)
+		require.NoError(t, err)
+		require.NoError(t, provider.Init(of.EvaluationContext{}))
+		t.Cleanup(provider.Shutdown)
+
+		results := provider.BatchEvaluation(context.Background(), []string{"flag-a", "flag-b", "flag-c"}, evalCtx)
+
+		assert.EqualValues(t, 1, requests.Load())
+		assert.Equal(t, "on", results["flag-a"].Variant)
+		assert.Equal(t, "treatment", results["flag-b"].Variant)
+		assert.ErrorContains(t, results["flag-c"].ResolutionError, "FLAG_NOT_FOUND")
+	})
+}
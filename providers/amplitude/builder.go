@@ -0,0 +1,89 @@
+package amplitude
+
+import (
+	"context"
+
+	analytics "github.com/amplitude/analytics-go/amplitude"
+	"github.com/amplitude/experiment-go-server/pkg/experiment/local"
+	"github.com/amplitude/experiment-go-server/pkg/experiment/remote"
+)
+
+// Builder builds a [Provider] using a fluent chain of method calls.
+// It is an alternative to [New] which is easier to read for complex configurations,
+// and which validates the combination of settings when [Builder.Build] is called.
+// Options without a dedicated method can be applied using [Builder.WithOptions].
+type Builder struct {
+	deploymentKey string
+	options       []Option
+}
+
+// NewBuilder creates a new [Builder] for a provider using the given deployment key.
+func NewBuilder(deploymentKey string) *Builder {
+	return &Builder{deploymentKey: deploymentKey}
+}
+
+// WithOptions applies the given options to the provider configuration.
+func (b *Builder) WithOptions(options ...Option) *Builder {
+	b.options = append(b.options, options...)
+	return b
+}
+
+// WithLocalConfig configures the provider to use local evaluation. See [WithLocalConfig].
+func (b *Builder) WithLocalConfig(localConfig local.Config) *Builder {
+	return b.WithOptions(WithLocalConfig(localConfig))
+}
+
+// WithRemoteConfig configures the provider to use remote evaluation. See [WithRemoteConfig].
+func (b *Builder) WithRemoteConfig(remoteConfig remote.Config) *Builder {
+	return b.WithOptions(WithRemoteConfig(remoteConfig))
+}
+
+// WithRemoteEvaluationCache sets the cache for remote evaluation. See [WithRemoteEvaluationCache].
+func (b *Builder) WithRemoteEvaluationCache(cache Cache) *Builder {
+	return b.WithOptions(WithRemoteEvaluationCache(cache))
+}
+
+// WithTrackingEnabled enables event and exposure tracking. See [WithTrackingEnabled].
+func (b *Builder) WithTrackingEnabled(config analytics.Config) *Builder {
+	return b.WithOptions(WithTrackingEnabled(config))
+}
+
+// WithKeyMap sets the key map. See [WithKeyMap].
+func (b *Builder) WithKeyMap(keyMap map[string]Key) *Builder {
+	return b.WithOptions(WithKeyMap(keyMap))
+}
+
+// WithUserNormalizer sets the user normalizer. See [WithUserNormalizer].
+func (b *Builder) WithUserNormalizer(userNormalizer func(ctx context.Context, context UserNormalizationContext) error) *Builder {
+	return b.WithOptions(WithUserNormalizer(userNormalizer))
+}
+
+// WithEventNormalizer sets the event normalizer. See [WithEventNormalizer].
+func (b *Builder) WithEventNormalizer(eventNormalizer func(ctx context.Context, normContext EventNormalizationContext) error) *Builder {
+	return b.WithOptions(WithEventNormalizer(eventNormalizer))
+}
+
+// Config returns the configuration built so far.
+func (b *Builder) Config() Config {
+	config := Config{
+		DeploymentKey: b.deploymentKey,
+	}
+	for _, option := range b.options {
+		option(&config)
+	}
+	return config
+}
+
+// Validate checks the combination of settings, returning an error describing every problem found.
+// See [Config.Validate].
+func (b *Builder) Validate() error {
+	return b.Config().Validate()
+}
+
+// Build validates the configuration and creates the [Provider].
+func (b *Builder) Build(ctx context.Context) (*Provider, error) {
+	if err := b.Validate(); err != nil {
+		return nil, err
+	}
+	return NewFromConfig(ctx, b.Config())
+}
@@ -0,0 +1,96 @@
+package amplitude
+
+import (
+	"context"
+	"testing"
+
+	analytics "github.com/amplitude/analytics-go/amplitude"
+	"github.com/amplitude/experiment-go-server/pkg/experiment/local"
+	"github.com/amplitude/experiment-go-server/pkg/experiment/remote"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuilder_Build(t *testing.T) {
+	mock := &mockClientAdapter{}
+	cache := &mockCache{}
+	keyMap := map[string]Key{"uid": KeyUserID}
+
+	provider, err := NewBuilder("test-key").
+		WithRemoteConfig(remote.Config{Debug: true}).
+		WithRemoteEvaluationCache(cache).
+		WithKeyMap(keyMap).
+		WithUserNormalizer(func(_ context.Context, _ UserNormalizationContext) error { return nil }).
+		WithOptions(withMockClient(mock)).
+		Build(context.Background())
+
+	require.NoError(t, err)
+	require.NotNil(t, provider)
+	assert.Equal(t, "test-key", provider.config.DeploymentKey)
+	require.NotNil(t, provider.config.RemoteConfig)
+	assert.True(t, provider.config.RemoteConfig.Debug)
+	assert.Equal(t, cache, provider.config.RemoteEvaluationCache)
+	assert.Equal(t, keyMap, provider.config.KeyMap)
+	assert.NotNil(t, provider.config.UserNormalizer)
+	assert.Equal(t, mock, provider.client)
+}
+
+func TestBuilder_Validate(t *testing.T) {
+	tests := []struct {
+		name           string
+		builder        *Builder
+		expectedErrors []string
+	}{
+		{
+			name:    "valid configuration",
+			builder: NewBuilder("test-key").WithLocalConfig(local.Config{}),
+		},
+		{
+			name:           "missing deployment key",
+			builder:        NewBuilder(""),
+			expectedErrors: []string{"you must provide a deployment key"},
+		},
+		{
+			name: "local and remote",
+			builder: NewBuilder("test-key").
+				WithLocalConfig(local.Config{}).
+				WithRemoteConfig(remote.Config{}),
+			expectedErrors: []string{"both local and remote evaluation"},
+		},
+		{
+			name:           "tracking without an API key",
+			builder:        NewBuilder("test-key").WithTrackingEnabled(analytics.Config{}),
+			expectedErrors: []string{"tracking requires an analytics API key"},
+		},
+		{
+			name: "all problems are reported together",
+			builder: NewBuilder("").
+				WithLocalConfig(local.Config{}).
+				WithRemoteConfig(remote.Config{}).
+				WithTrackingEnabled(analytics.Config{}),
+			expectedErrors: []string{
+				"you must provide a deployment key",
+				"both local and remote evaluation",
+				"tracking requires an analytics API key",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.builder.Validate()
+			if len(tt.expectedErrors) == 0 {
+				require.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			for _, expected := range tt.expectedErrors {
+				assert.Contains(t, err.Error(), expected)
+			}
+
+			provider, buildErr := tt.builder.Build(context.Background())
+			assert.Nil(t, provider)
+			assert.Equal(t, err, buildErr)
+		})
+	}
+}
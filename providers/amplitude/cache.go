@@ -1,15 +1,222 @@
 package amplitude
 
-import "context"
+import (
+	"container/list"
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
 
 // Cache is an interface for a cache.
-// You may want to provide an implementation using a library like github.com/hashicorp/golang-lru/v2,
-// or an implementation which expects a mutable value to be added to the context
+// [NewTTLCache] returns a bounded cache with expiry; you may also want to provide an implementation
+// using a library like github.com/hashicorp/golang-lru/v2.
+// [NewRequestCache] returns an implementation which expects a mutable value to be added to the context
 // early in the request pipeline and then uses it to cache values for the duration of the request.
 // This will mean that flags are evaluated once per request, rather than once per flag evaluation.
 type Cache interface {
 	// Set sets the value for the given key.
 	Set(ctx context.Context, key string, value any) error
-	// Get gets the value for the given key.
+	// Get gets the value for the given key. It must return the value as it was set
+	// (with [WithRemoteEvaluationCodec], the serialized value as a []byte or a string);
+	// values of other types (e.g. serialized by an external store) are logged and treated as misses.
 	Get(ctx context.Context, key string) (any, error)
 }
+
+var _ Cache = (*MemoryCache)(nil)
+
+// MemoryCache is a simple, unbounded, concurrency-safe in-memory [Cache]
+// which counts hits and misses, so you can see whether caching is helping.
+// Because it never evicts entries, it is intended to live for the duration of a single request
+// (for example, created by middleware and stored in the request's context, where it is
+// found by the [Cache] given to [WithRemoteEvaluationCache]).
+// Don't use it as a long-lived process-wide cache; use a bounded cache with expiry for that,
+// such as a [TTLCache].
+type MemoryCache struct {
+	mu     sync.Mutex
+	values map[string]any
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+// MemoryCacheStats are the lookup counts of a [MemoryCache].
+type MemoryCacheStats struct {
+	// Hits is the number of lookups which found a value.
+	Hits int64
+	// Misses is the number of lookups which didn't find a value.
+	Misses int64
+}
+
+// NewMemoryCache returns an empty [MemoryCache].
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{values: make(map[string]any)}
+}
+
+// Set sets the value for the given key.
+func (c *MemoryCache) Set(_ context.Context, key string, value any) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.values[key] = value
+	return nil
+}
+
+// Get gets the value for the given key, or nil if there is none.
+func (c *MemoryCache) Get(_ context.Context, key string) (any, error) {
+	c.mu.Lock()
+	value, ok := c.values[key]
+	c.mu.Unlock()
+	if ok {
+		c.hits.Add(1)
+	} else {
+		c.misses.Add(1)
+	}
+	return value, nil
+}
+
+// Stats returns the number of hits and misses so far.
+func (c *MemoryCache) Stats() MemoryCacheStats {
+	return MemoryCacheStats{
+		Hits:   c.hits.Load(),
+		Misses: c.misses.Load(),
+	}
+}
+
+// requestCache is the [Cache] returned by [NewRequestCache].
+type requestCache struct {
+	// key is the context key under which the cache finds the [MemoryCache] of the current request.
+	key *requestCacheKey
+}
+
+// requestCacheKey is the type of the context keys of request caches. Each request cache
+// has its own key; it isn't empty, so that pointers to different keys are never equal.
+type requestCacheKey struct {
+	_ byte
+}
+
+// NewRequestCache returns a [Cache] which caches values for the duration of a request, so that
+// flags are evaluated once per request, and a function which seeds a context with an empty cache for
+// a request, typically called by middleware early in the request pipeline:
+//
+//	cache, withRequestCache := amplitude.NewRequestCache()
+//	provider, err := amplitude.New(ctx, "deployment-key",
+//	    amplitude.WithRemoteConfig(remote.Config{}),
+//	    amplitude.WithRemoteEvaluationCache(cache),
+//	)
+//	...
+//	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+//	    next.ServeHTTP(w, r.WithContext(withRequestCache(r.Context())))
+//	})
+//
+// The cache reads and writes the values of the context passed to Get and Set, which is the context
+// passed to the evaluation. If the context wasn't seeded, nothing is cached, and every evaluation
+// fetches the variants.
+func NewRequestCache() (Cache, func(ctx context.Context) context.Context) {
+	cache := &requestCache{key: &requestCacheKey{}}
+	return cache, func(ctx context.Context) context.Context {
+		return context.WithValue(ctx, cache.key, NewMemoryCache())
+	}
+}
+
+// Set sets the value for the given key in the cache of the request, if the context was seeded.
+func (c *requestCache) Set(ctx context.Context, key string, value any) error {
+	if values, ok := ctx.Value(c.key).(*MemoryCache); ok {
+		return values.Set(ctx, key, value)
+	}
+	return nil
+}
+
+// Get gets the value for the given key from the cache of the request,
+// or nil if there is none or the context wasn't seeded.
+func (c *requestCache) Get(ctx context.Context, key string) (any, error) {
+	if values, ok := ctx.Value(c.key).(*MemoryCache); ok {
+		return values.Get(ctx, key)
+	}
+	return nil, nil
+}
+
+var _ Cache = (*TTLCache)(nil)
+
+// TTLCache is a bounded, concurrency-safe in-memory [Cache] whose entries expire, suitable as
+// a long-lived process-wide cache for [WithRemoteEvaluationCache]. When it is full, the least
+// recently used entry is evicted. Expired and missing entries are returned as nil, so that
+// remote evaluation falls through to a fetch.
+type TTLCache struct {
+	maxEntries int
+	ttl        time.Duration
+	// now returns the current time; it is replaced in tests.
+	now func() time.Time
+
+	mu sync.Mutex
+	// entries maps keys to their elements in recent.
+	entries map[string]*list.Element
+	// recent lists the entries, most recently used first.
+	recent *list.List
+}
+
+// ttlCacheEntry is the value of the elements of TTLCache.recent.
+type ttlCacheEntry struct {
+	key       string
+	value     any
+	expiresAt time.Time
+}
+
+// NewTTLCache returns an empty [TTLCache] holding up to maxEntries entries, each for ttl after it is set.
+// If maxEntries isn't positive, the cache is unbounded; if ttl isn't positive, entries don't expire.
+func NewTTLCache(maxEntries int, ttl time.Duration) *TTLCache {
+	return &TTLCache{
+		maxEntries: maxEntries,
+		ttl:        ttl,
+		now:        time.Now,
+		entries:    make(map[string]*list.Element),
+		recent:     list.New(),
+	}
+}
+
+// Set sets the value for the given key, evicting the least recently used entry if the cache is full.
+func (c *TTLCache) Set(_ context.Context, key string, value any) error {
+	var expiresAt time.Time
+	if c.ttl > 0 {
+		expiresAt = c.now().Add(c.ttl)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if element, ok := c.entries[key]; ok {
+		entry := element.Value.(*ttlCacheEntry)
+		entry.value = value
+		entry.expiresAt = expiresAt
+		c.recent.MoveToFront(element)
+		return nil
+	}
+	c.entries[key] = c.recent.PushFront(&ttlCacheEntry{key: key, value: value, expiresAt: expiresAt})
+	if c.maxEntries > 0 && c.recent.Len() > c.maxEntries {
+		oldest := c.recent.Remove(c.recent.Back()).(*ttlCacheEntry)
+		delete(c.entries, oldest.key)
+	}
+	return nil
+}
+
+// Get gets the value for the given key, or nil if there is none or it has expired.
+func (c *TTLCache) Get(_ context.Context, key string) (any, error) {
+	now := c.now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, nil
+	}
+	entry := element.Value.(*ttlCacheEntry)
+	if !entry.expiresAt.IsZero() && !now.Before(entry.expiresAt) {
+		c.recent.Remove(element)
+		delete(c.entries, key)
+		return nil, nil
+	}
+	c.recent.MoveToFront(element)
+	return entry.value, nil
+}
+
+// Len returns the number of entries in the cache, including expired entries which haven't been evicted yet.
+func (c *TTLCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.recent.Len()
+}
@@ -0,0 +1,107 @@
+package amplitude
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	experiment "github.com/amplitude/experiment-go-server/pkg/experiment"
+)
+
+// CacheCodec serializes the variants stored in the remote evaluation cache, so that the cache
+// can be backed by an out-of-process store such as Redis or memcached; see [WithRemoteEvaluationCodec].
+type CacheCodec struct {
+	// Marshal encodes the variants of a user.
+	Marshal func(variants map[string]experiment.Variant) ([]byte, error)
+	// Unmarshal decodes variants encoded by Marshal.
+	Unmarshal func(data []byte) (map[string]experiment.Variant, error)
+}
+
+// JSONCacheCodec encodes variants as JSON.
+// Numbers in variant payloads and metadata are decoded as float64, as by [json.Unmarshal].
+var JSONCacheCodec = CacheCodec{
+	Marshal: func(variants map[string]experiment.Variant) ([]byte, error) {
+		return json.Marshal(variants)
+	},
+	Unmarshal: func(data []byte) (map[string]experiment.Variant, error) {
+		var variants map[string]experiment.Variant
+		if err := json.Unmarshal(data, &variants); err != nil {
+			return nil, err
+		}
+		return variants, nil
+	},
+}
+
+// encodedCacheEntry is the serialized form of a [cacheEntry], wrapping the variants
+// encoded by the codec so that stale-while-revalidate works with serializing caches.
+type encodedCacheEntry struct {
+	Variants  []byte    `json:"variants"`
+	FetchedAt time.Time `json:"fetched_at"`
+	Version   string    `json:"version,omitempty"`
+}
+
+// encodeCacheValue serializes a value to be stored in the cache: the variants, or a [cacheEntry]
+// when stale-while-revalidate is enabled.
+func (c *clientAdapterRemote) encodeCacheValue(value any) ([]byte, error) {
+	codec := c.config.Codec
+	switch value := value.(type) {
+	case cacheEntry:
+		variants, err := codec.Marshal(value.variants)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(encodedCacheEntry{
+			Variants:  variants,
+			FetchedAt: value.fetchedAt,
+			Version:   value.version,
+		})
+	case map[string]experiment.Variant:
+		return codec.Marshal(value)
+	default:
+		return nil, fmt.Errorf("unexpected cache value of type %T", value)
+	}
+}
+
+// decodeCacheData deserializes data read from the cache, returning a [cacheEntry]
+// when stale-while-revalidate is enabled, and the variants otherwise.
+func (c *clientAdapterRemote) decodeCacheData(data []byte) (any, error) {
+	codec := c.config.Codec
+	if c.config.SoftTTL > 0 {
+		var entry encodedCacheEntry
+		if err := json.Unmarshal(data, &entry); err != nil {
+			return nil, err
+		}
+		variants, err := codec.Unmarshal(entry.Variants)
+		if err != nil {
+			return nil, err
+		}
+		return cacheEntry{
+			variants:  variants,
+			fetchedAt: entry.FetchedAt,
+			version:   entry.Version,
+		}, nil
+	}
+	return codec.Unmarshal(data)
+}
+
+// decodeCachedValue decodes a serialized value read from the cache. Values which aren't
+// serialized are returned as they are; values which fail to decode are logged and
+// returned as nil, so that they are treated as misses.
+func (c *clientAdapterRemote) decodeCachedValue(value any) any {
+	var data []byte
+	switch value := value.(type) {
+	case []byte:
+		data = value
+	case string:
+		// Stores such as Redis commonly return strings.
+		data = []byte(value)
+	default:
+		return value
+	}
+	decoded, err := c.decodeCacheData(data)
+	if err != nil {
+		c.logError("amplitude: failed to decode cached variants; fetching the variants: %v", err)
+		return nil
+	}
+	return decoded
+}
@@ -0,0 +1,210 @@
+package amplitude
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/amplitude/experiment-go-server/pkg/experiment"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryCache(t *testing.T) {
+	ctx := context.Background()
+	cache := NewMemoryCache()
+
+	value, err := cache.Get(ctx, "missing")
+	require.NoError(t, err)
+	assert.Nil(t, value)
+
+	require.NoError(t, cache.Set(ctx, "key", "value"))
+	value, err = cache.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.Equal(t, "value", value)
+
+	assert.Equal(t, MemoryCacheStats{Hits: 1, Misses: 1}, cache.Stats())
+}
+
+func TestMemoryCache_Concurrent(t *testing.T) {
+	ctx := context.Background()
+	cache := NewMemoryCache()
+
+	var wg sync.WaitGroup
+	for i := range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			key := fmt.Sprintf("key-%d", i%10)
+			if value, _ := cache.Get(ctx, key); value == nil {
+				assert.NoError(t, cache.Set(ctx, key, i))
+			}
+		}()
+	}
+	wg.Wait()
+
+	stats := cache.Stats()
+	assert.EqualValues(t, 50, stats.Hits+stats.Misses)
+	assert.GreaterOrEqual(t, stats.Misses, int64(10))
+}
+
+func TestMemoryCache_RemoteEvaluation(t *testing.T) {
+	cache := NewMemoryCache()
+	evaluator := &mockRemoteEvaluator{fetchFunc: func(*experiment.User) (map[string]experiment.Variant, error) {
+		return map[string]experiment.Variant{"flag-1": {Key: "on"}}, nil
+	}}
+	client := &clientAdapterRemote{evaluator: evaluator, cache: cache, config: remoteConfig{Cache: cache}}
+	user := &experiment.User{UserId: "user-1"}
+
+	for range 3 {
+		variants, err := client.Evaluate(context.Background(), user, nil)
+		require.NoError(t, err)
+		assert.Equal(t, "on", variants["flag-1"].Key)
+	}
+
+	assert.Len(t, evaluator.fetchCalls, 1)
+	assert.Equal(t, MemoryCacheStats{Hits: 2, Misses: 1}, cache.Stats())
+}
+
+func TestRequestCache(t *testing.T) {
+	cache, withRequestCache := NewRequestCache()
+
+	t.Run("values are cached per request", func(t *testing.T) {
+		first := withRequestCache(context.Background())
+		second := withRequestCache(context.Background())
+		require.NoError(t, cache.Set(first, "key", "value"))
+
+		value, err := cache.Get(first, "key")
+		require.NoError(t, err)
+		assert.Equal(t, "value", value)
+		value, err = cache.Get(second, "key")
+		require.NoError(t, err)
+		assert.Nil(t, value)
+	})
+
+	t.Run("nothing is cached without a seeded context", func(t *testing.T) {
+		require.NoError(t, cache.Set(context.Background(), "key", "value"))
+
+		value, err := cache.Get(context.Background(), "key")
+		require.NoError(t, err)
+		assert.Nil(t, value)
+	})
+
+	t.Run("request caches don't share contexts", func(t *testing.T) {
+		otherCache, _ := NewRequestCache()
+		ctx := withRequestCache(context.Background())
+		require.NoError(t, cache.Set(ctx, "key", "value"))
+
+		value, err := otherCache.Get(ctx, "key")
+		require.NoError(t, err)
+		assert.Nil(t, value)
+	})
+}
+
+func TestRequestCache_RemoteEvaluation(t *testing.T) {
+	cache, withRequestCache := NewRequestCache()
+	evaluator := &mockRemoteEvaluator{fetchFunc: func(*experiment.User) (map[string]experiment.Variant, error) {
+		return map[string]experiment.Variant{"flag-1": {Key: "on"}}, nil
+	}}
+	client := &clientAdapterRemote{evaluator: evaluator, cache: cache, config: remoteConfig{Cache: cache}}
+	user := &experiment.User{UserId: "user-1"}
+
+	for range 2 {
+		ctx := withRequestCache(context.Background())
+		for range 3 {
+			_, err := client.Evaluate(ctx, user, nil)
+			require.NoError(t, err)
+		}
+	}
+
+	assert.Len(t, evaluator.fetchCalls, 2, "flags are fetched once per request")
+}
+
+func TestTTLCache(t *testing.T) {
+	ctx := context.Background()
+	newCache := func(maxEntries int, ttl time.Duration) (*TTLCache, *time.Time) {
+		cache := NewTTLCache(maxEntries, ttl)
+		now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		cache.now = func() time.Time { return now }
+		return cache, &now
+	}
+
+	t.Run("entries expire after the TTL", func(t *testing.T) {
+		cache, now := newCache(10, time.Minute)
+		require.NoError(t, cache.Set(ctx, "key", "value"))
+
+		*now = now.Add(59 * time.Second)
+		value, err := cache.Get(ctx, "key")
+		require.NoError(t, err)
+		assert.Equal(t, "value", value)
+
+		*now = now.Add(time.Second)
+		value, err = cache.Get(ctx, "key")
+		require.NoError(t, err)
+		assert.Nil(t, value)
+		assert.Zero(t, cache.Len(), "expired entries are evicted")
+	})
+
+	t.Run("setting an entry again renews it", func(t *testing.T) {
+		cache, now := newCache(10, time.Minute)
+		require.NoError(t, cache.Set(ctx, "key", "old"))
+		*now = now.Add(30 * time.Second)
+		require.NoError(t, cache.Set(ctx, "key", "new"))
+		*now = now.Add(45 * time.Second)
+
+		value, err := cache.Get(ctx, "key")
+		require.NoError(t, err)
+		assert.Equal(t, "new", value)
+		assert.Equal(t, 1, cache.Len())
+	})
+
+	t.Run("the least recently used entries are evicted", func(t *testing.T) {
+		cache, _ := newCache(2, time.Minute)
+		require.NoError(t, cache.Set(ctx, "a", 1))
+		require.NoError(t, cache.Set(ctx, "b", 2))
+		_, _ = cache.Get(ctx, "a")
+		require.NoError(t, cache.Set(ctx, "c", 3))
+
+		a, _ := cache.Get(ctx, "a")
+		b, _ := cache.Get(ctx, "b")
+		c, _ := cache.Get(ctx, "c")
+		assert.Equal(t, 1, a)
+		assert.Nil(t, b)
+		assert.Equal(t, 3, c)
+	})
+
+	t.Run("non-positive bounds are unbounded", func(t *testing.T) {
+		cache, now := newCache(0, 0)
+		for i := range 100 {
+			require.NoError(t, cache.Set(ctx, fmt.Sprintf("key-%d", i), i))
+		}
+		*now = now.Add(24 * time.Hour)
+
+		value, err := cache.Get(ctx, "key-0")
+		require.NoError(t, err)
+		assert.Equal(t, 0, value)
+		assert.Equal(t, 100, cache.Len())
+	})
+}
+
+func TestTTLCache_Concurrent(t *testing.T) {
+	ctx := context.Background()
+	cache := NewTTLCache(5, time.Minute)
+
+	var wg sync.WaitGroup
+	for i := range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			key := fmt.Sprintf("key-%d", i%10)
+			if value, _ := cache.Get(ctx, key); value == nil {
+				assert.NoError(t, cache.Set(ctx, key, i))
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.LessOrEqual(t, cache.Len(), 5)
+}
@@ -0,0 +1,139 @@
+package amplitude
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	experiment "github.com/amplitude/experiment-go-server/pkg/experiment"
+)
+
+// clientAdapterHybrid routes each flag to local or remote evaluation:
+// the configured remote flags are evaluated remotely, and all other flags locally.
+type clientAdapterHybrid struct {
+	local  clientAdapter
+	remote clientAdapter
+	// remoteFlags are the keys of the flags which are evaluated remotely.
+	remoteFlags []string
+}
+
+// newClientAdapterHybrid creates a client adapter which evaluates the given flags
+// with the remote adapter, and all other flags with the local adapter.
+func newClientAdapterHybrid(local, remote clientAdapter, remoteFlags []string) *clientAdapterHybrid {
+	return &clientAdapterHybrid{
+		local:       local,
+		remote:      remote,
+		remoteFlags: slices.Clone(remoteFlags),
+	}
+}
+
+// Start starts both the local and remote evaluation clients.
+func (c *clientAdapterHybrid) Start() error {
+	if err := c.local.Start(); err != nil {
+		return fmt.Errorf("failed to start local evaluation: %w", err)
+	}
+	if err := c.remote.Start(); err != nil {
+		return fmt.Errorf("failed to start remote evaluation: %w", err)
+	}
+	return nil
+}
+
+// Stop stops both the local and remote evaluation clients.
+func (c *clientAdapterHybrid) Stop() error {
+	return errors.Join(c.local.Stop(), c.remote.Stop())
+}
+
+// Evaluate evaluates the remote flags among the given flags remotely, and the others locally.
+// If flagKeys is empty, all flags are evaluated locally, and the results of the remote flags
+// are replaced with their remote results. Amplitude is only called if a remote flag is evaluated.
+func (c *clientAdapterHybrid) Evaluate(ctx context.Context, user *experiment.User, flagKeys []string) (map[string]experiment.Variant, error) {
+	var localFlags, remoteFlags []string
+	if len(flagKeys) == 0 {
+		remoteFlags = c.remoteFlags
+	} else {
+		for _, flagKey := range flagKeys {
+			if c.isRemote(flagKey) {
+				remoteFlags = append(remoteFlags, flagKey)
+			} else {
+				localFlags = append(localFlags, flagKey)
+			}
+		}
+	}
+
+	variants := make(map[string]experiment.Variant)
+	if len(flagKeys) == 0 || len(localFlags) > 0 {
+		localVariants, err := c.local.Evaluate(ctx, user, localFlags)
+		if err != nil {
+			return nil, err
+		}
+		for flagKey, variant := range localVariants {
+			if !c.isRemote(flagKey) {
+				variants[flagKey] = variant
+			}
+		}
+	}
+	if len(remoteFlags) > 0 {
+		remoteVariants, err := c.remote.Evaluate(ctx, user, remoteFlags)
+		if err != nil {
+			return nil, err
+		}
+		// Remote evaluation returns all flags, so only keep the remote ones.
+		for _, flagKey := range remoteFlags {
+			if variant, ok := remoteVariants[flagKey]; ok {
+				variants[flagKey] = variant
+			}
+		}
+	}
+	return variants, nil
+}
+
+// isRemote returns true if the flag is evaluated remotely.
+func (c *clientAdapterHybrid) isRemote(flagKey string) bool {
+	return slices.Contains(c.remoteFlags, flagKey)
+}
+
+// flagConfigStats returns the statistics of the local flag configs.
+func (c *clientAdapterHybrid) flagConfigStats() (FlagConfigStats, error) {
+	statsProvider, ok := c.local.(flagConfigStatsProvider)
+	if !ok {
+		return FlagConfigStats{}, nil
+	}
+	return statsProvider.flagConfigStats()
+}
+
+// forceSync refreshes the local flag configs.
+func (c *clientAdapterHybrid) forceSync() error {
+	syncer, ok := c.local.(flagConfigSyncer)
+	if !ok {
+		return nil
+	}
+	return syncer.forceSync()
+}
+
+// flagStatus returns the status of the flag from the local flag configs.
+func (c *clientAdapterHybrid) flagStatus(flag string) FlagStatus {
+	statusProvider, ok := c.local.(flagStatusProvider)
+	if !ok {
+		return FlagStatus{}
+	}
+	return statusProvider.flagStatus(flag)
+}
+
+// probeReadiness probes the readiness of the remote adapter.
+func (c *clientAdapterHybrid) probeReadiness() error {
+	prober, ok := c.remote.(readinessProber)
+	if !ok {
+		return nil
+	}
+	return prober.probeReadiness()
+}
+
+// cacheKey returns the key under which remote results for the user are cached.
+func (c *clientAdapterHybrid) cacheKey(user *experiment.User, flagKeys []string) (string, error) {
+	keyer, ok := c.remote.(cacheKeyer)
+	if !ok {
+		return "", ErrCacheKeyUnavailable
+	}
+	return keyer.cacheKey(user, flagKeys)
+}
@@ -0,0 +1,153 @@
+package amplitude
+
+import (
+	"context"
+	"testing"
+
+	experiment "github.com/amplitude/experiment-go-server/pkg/experiment"
+	"github.com/amplitude/experiment-go-server/pkg/experiment/local"
+	"github.com/amplitude/experiment-go-server/pkg/experiment/remote"
+	of "github.com/open-feature/go-sdk/openfeature"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientAdapterHybrid_Evaluate(t *testing.T) {
+	newAdapters := func() (*clientAdapterHybrid, *mockClientAdapter, *mockClientAdapter) {
+		localClient := &mockClientAdapter{
+			EvaluateFunc: func(_ context.Context, _ *experiment.User, _ []string) (map[string]experiment.Variant, error) {
+				return map[string]experiment.Variant{
+					"local-flag":  makeVariant("on", "on", nil),
+					"remote-flag": makeVariant("off", "", nil),
+				}, nil
+			},
+		}
+		remoteClient := &mockClientAdapter{
+			EvaluateFunc: func(_ context.Context, _ *experiment.User, _ []string) (map[string]experiment.Variant, error) {
+				return map[string]experiment.Variant{
+					"local-flag":  makeVariant("remote", "remote", nil),
+					"remote-flag": makeVariant("resolved", "resolved", nil),
+				}, nil
+			},
+		}
+		return newClientAdapterHybrid(localClient, remoteClient, []string{"remote-flag"}), localClient, remoteClient
+	}
+	user := &experiment.User{UserId: "user-1"}
+
+	t.Run("all flags", func(t *testing.T) {
+		adapter, localClient, remoteClient := newAdapters()
+
+		variants, err := adapter.Evaluate(context.Background(), user, nil)
+
+		require.NoError(t, err)
+		assert.Equal(t, "on", variants["local-flag"].Key)
+		assert.Equal(t, "resolved", variants["remote-flag"].Key)
+		require.Len(t, localClient.evaluateCalls, 1)
+		assert.Empty(t, localClient.evaluateCalls[0].FlagKeys)
+		require.Len(t, remoteClient.evaluateCalls, 1)
+		assert.Equal(t, []string{"remote-flag"}, remoteClient.evaluateCalls[0].FlagKeys)
+	})
+
+	t.Run("local flags only", func(t *testing.T) {
+		adapter, localClient, remoteClient := newAdapters()
+
+		variants, err := adapter.Evaluate(context.Background(), user, []string{"local-flag"})
+
+		require.NoError(t, err)
+		assert.Equal(t, map[string]experiment.Variant{"local-flag": makeVariant("on", "on", nil)}, variants)
+		require.Len(t, localClient.evaluateCalls, 1)
+		assert.Equal(t, []string{"local-flag"}, localClient.evaluateCalls[0].FlagKeys)
+		assert.Empty(t, remoteClient.evaluateCalls, "Amplitude should not be called for local flags")
+	})
+
+	t.Run("remote flags only", func(t *testing.T) {
+		adapter, localClient, remoteClient := newAdapters()
+
+		variants, err := adapter.Evaluate(context.Background(), user, []string{"remote-flag"})
+
+		require.NoError(t, err)
+		assert.Equal(t, map[string]experiment.Variant{"remote-flag": makeVariant("resolved", "resolved", nil)}, variants)
+		assert.Empty(t, localClient.evaluateCalls)
+		assert.Len(t, remoteClient.evaluateCalls, 1)
+	})
+
+	t.Run("remote errors are returned", func(t *testing.T) {
+		adapter, _, remoteClient := newAdapters()
+		remoteClient.EvaluateFunc = func(context.Context, *experiment.User, []string) (map[string]experiment.Variant, error) {
+			return nil, errMockEvaluate
+		}
+
+		_, err := adapter.Evaluate(context.Background(), user, []string{"local-flag", "remote-flag"})
+
+		assert.ErrorIs(t, err, errMockEvaluate)
+	})
+}
+
+func TestClientAdapterHybrid_StartStop(t *testing.T) {
+	t.Run("starts and stops both clients", func(t *testing.T) {
+		localClient, remoteClient := &mockClientAdapter{}, &mockClientAdapter{}
+		adapter := newClientAdapterHybrid(localClient, remoteClient, []string{"remote-flag"})
+
+		require.NoError(t, adapter.Start())
+		require.NoError(t, adapter.Stop())
+
+		assert.True(t, localClient.startCalled)
+		assert.True(t, remoteClient.startCalled)
+		assert.True(t, localClient.stopCalled)
+		assert.True(t, remoteClient.stopCalled)
+	})
+
+	t.Run("local start errors are returned", func(t *testing.T) {
+		localClient := &mockClientAdapter{StartFunc: func() error { return errMockStart }}
+		adapter := newClientAdapterHybrid(localClient, &mockClientAdapter{}, []string{"remote-flag"})
+
+		assert.ErrorIs(t, adapter.Start(), errMockStart)
+	})
+}
+
+func TestNewFromConfig_RemoteFlags(t *testing.T) {
+	cache := &syncCache{}
+	config := Config{
+		DeploymentKey:         "test-key",
+		LocalConfig:           &local.Config{},
+		RemoteConfig:          &remote.Config{},
+		RemoteEvaluationCache: cache,
+		RemoteFlags:           []string{"remote-flag"},
+		ConfigValidation:      ValidationError,
+	}
+	require.NoError(t, config.Validate())
+
+	provider, err := NewFromConfig(context.Background(), config)
+
+	require.NoError(t, err)
+	hybrid, ok := provider.client.(*clientAdapterHybrid)
+	require.True(t, ok, "the provider should use a hybrid client")
+	assert.IsType(t, &clientAdapterLocal{}, hybrid.local)
+	assert.IsType(t, &clientAdapterRemote{}, hybrid.remote)
+	assert.Equal(t, []string{"remote-flag"}, hybrid.remoteFlags)
+	assert.Same(t, cache, hybrid.remote.(*clientAdapterRemote).cache)
+}
+
+func TestProvider_RemoteFlags(t *testing.T) {
+	localClient := &mockClientAdapter{
+		EvaluateFunc: func(_ context.Context, _ *experiment.User, _ []string) (map[string]experiment.Variant, error) {
+			return map[string]experiment.Variant{"local-flag": makeVariant("on", "on", nil)}, nil
+		},
+	}
+	remoteClient := &mockClientAdapter{
+		EvaluateFunc: func(_ context.Context, _ *experiment.User, _ []string) (map[string]experiment.Variant, error) {
+			return map[string]experiment.Variant{"remote-flag": makeVariant("on", "on", nil)}, nil
+		},
+	}
+	provider := newTestProvider(t, &mockClientAdapter{})
+	provider.client = newClientAdapterHybrid(localClient, remoteClient, []string{"remote-flag"})
+	evalCtx := of.FlattenedContext{of.TargetingKey: "user-1"}
+
+	localResult := provider.BooleanEvaluation(context.Background(), "local-flag", false, evalCtx)
+	remoteResult := provider.BooleanEvaluation(context.Background(), "remote-flag", false, evalCtx)
+
+	assert.True(t, localResult.Value)
+	assert.True(t, remoteResult.Value)
+	assert.Len(t, localClient.evaluateCalls, 1)
+	assert.Len(t, remoteClient.evaluateCalls, 1)
+}
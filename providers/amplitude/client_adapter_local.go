@@ -2,40 +2,105 @@ package amplitude
 
 import (
 	"context"
+	"errors"
+	"fmt"
 
 	experiment "github.com/amplitude/experiment-go-server/pkg/experiment"
 	"github.com/amplitude/experiment-go-server/pkg/experiment/local"
 )
 
+// localEvaluator is an interface for the local evaluation client.
+// This allows for testing with a mock implementation.
+type localEvaluator interface {
+	Start() error
+	EvaluateV2(user *experiment.User, flagKeys []string) (map[string]experiment.Variant, error)
+	FlagMetadata(flagKey string) map[string]interface{}
+}
+
 // LocalClient wraps the Amplitude local evaluation client to implement ExperimentClient.
 type clientAdapterLocal struct {
-	client *local.Client
+	client localEvaluator
+	// watcher reports flag config changes, if a change callback was configured.
+	watcher *flagConfigWatcher
+	// cohortSync is true if the client also syncs cohorts, which prevents forcing a sync.
+	cohortSync bool
 }
 
 // localConfig contains configuration for local evaluation.
 type localConfig struct {
 	local.Config
+	// FlagConfigChangeCallback is called with the keys of flags whose config changed.
+	FlagConfigChangeCallback func(changed []string)
 }
 
 // newClientAdapterLocal creates a new LocalClient with the given deployment key, config, and logger.
 // The client must be started by calling Start() before use.
 func newClientAdapterLocal(deploymentKey string, config localConfig) *clientAdapterLocal {
-	return &clientAdapterLocal{
-		client: local.Initialize(deploymentKey, &config.Config),
+	adapter := &clientAdapterLocal{
+		client:     local.Initialize(deploymentKey, &config.Config),
+		cohortSync: config.CohortSyncConfig != nil,
+	}
+	if config.FlagConfigChangeCallback != nil {
+		interval := config.FlagConfigPollerInterval
+		if interval <= 0 {
+			interval = local.DefaultConfig.FlagConfigPollerInterval
+		}
+		adapter.watcher = newFlagConfigWatcher(adapter.client.FlagMetadata, config.FlagConfigChangeCallback, interval)
 	}
+	return adapter
 }
 
 // Start starts the local evaluation client, fetching flag configurations.
 func (c *clientAdapterLocal) Start() error {
-	return c.client.Start()
+	if err := c.client.Start(); err != nil {
+		return err
+	}
+	if c.watcher != nil {
+		c.watcher.start()
+	}
+	return nil
 }
 
 // Stop stops the local evaluation client.
+// The SDK can't stop polling for flag configs, so only the flag config watcher is stopped;
+// if a later SDK version adds a way to stop the client, it should be called here.
 func (c *clientAdapterLocal) Stop() error {
+	if c.watcher != nil {
+		c.watcher.stop()
+	}
 	return nil
 }
 
 // Evaluate evaluates the given flags for the given user using local evaluation.
 func (c *clientAdapterLocal) Evaluate(_ context.Context, user *experiment.User, flagKeys []string) (map[string]experiment.Variant, error) {
-	return c.client.EvaluateV2(user, flagKeys)
+	variants, err := c.client.EvaluateV2(user, flagKeys)
+	if err != nil {
+		return nil, err
+	}
+	if c.watcher != nil {
+		c.watcher.observe(variants)
+	}
+	return variants, nil
+}
+
+// flagConfigSyncer is implemented by client adapters which can refresh their flag configs on demand.
+type flagConfigSyncer interface {
+	forceSync() error
+}
+
+// forceSync fetches the flag configs immediately.
+// The local SDK has no refresh method, but starting the client again stops the poller,
+// fetches the flag configs, and restarts the poller (or reconnects the stream).
+// With cohort sync, each start would add another cohort poller, so it isn't supported.
+func (c *clientAdapterLocal) forceSync() error {
+	if c.cohortSync {
+		return errors.New("forcing a flag config sync is not supported with cohort sync")
+	}
+	if err := c.client.Start(); err != nil {
+		return fmt.Errorf("failed to sync flag configs: %w", err)
+	}
+	if c.watcher != nil {
+		c.watcher.check()
+	}
+	return nil
 }
@@ -0,0 +1,81 @@
+package amplitude
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/amplitude/experiment-go-server/pkg/experiment"
+	of "github.com/open-feature/go-sdk/openfeature"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProvider_ForceSync(t *testing.T) {
+	newReadyProvider := func(t *testing.T, client clientAdapter) *Provider {
+		t.Helper()
+		provider := &Provider{client: client, state: of.NotReadyState}
+		require.NoError(t, provider.Init(of.EvaluationContext{}))
+		return provider
+	}
+
+	t.Run("local evaluation restarts the client", func(t *testing.T) {
+		evaluator := &mockLocalEvaluator{}
+		provider := newReadyProvider(t, &clientAdapterLocal{client: evaluator})
+
+		require.NoError(t, provider.ForceSync(context.Background()))
+
+		assert.Equal(t, 2, evaluator.starts)
+	})
+
+	t.Run("change callbacks run before returning", func(t *testing.T) {
+		evaluator := &mockLocalEvaluator{
+			variants: map[string]experiment.Variant{"flag-a": {Key: "on"}},
+			metadata: map[string]map[string]any{"flag-a": {"flagVersion": 1}},
+		}
+		var changed []string
+		adapter := &clientAdapterLocal{client: evaluator}
+		adapter.watcher = newFlagConfigWatcher(evaluator.FlagMetadata, func(flags []string) { changed = flags }, time.Hour)
+		provider := newReadyProvider(t, adapter)
+		_, err := adapter.Evaluate(context.Background(), &experiment.User{}, nil)
+		require.NoError(t, err)
+		evaluator.setMetadata("flag-a", map[string]any{"flagVersion": 2})
+
+		require.NoError(t, provider.ForceSync(context.Background()))
+
+		assert.Equal(t, []string{"flag-a"}, changed)
+	})
+
+	t.Run("fetch errors are returned", func(t *testing.T) {
+		evaluator := &mockLocalEvaluator{}
+		provider := newReadyProvider(t, &clientAdapterLocal{client: evaluator})
+		evaluator.startErr = errors.New("network down")
+
+		err := provider.ForceSync(context.Background())
+
+		assert.ErrorContains(t, err, "network down")
+	})
+
+	t.Run("not supported with cohort sync", func(t *testing.T) {
+		evaluator := &mockLocalEvaluator{}
+		provider := newReadyProvider(t, &clientAdapterLocal{client: evaluator, cohortSync: true})
+
+		err := provider.ForceSync(context.Background())
+
+		assert.ErrorContains(t, err, "cohort sync")
+		assert.Equal(t, 1, evaluator.starts)
+	})
+
+	t.Run("remote evaluation does nothing", func(t *testing.T) {
+		provider := newReadyProvider(t, &mockClientAdapter{})
+
+		assert.NoError(t, provider.ForceSync(context.Background()))
+	})
+
+	t.Run("fails before init", func(t *testing.T) {
+		provider := &Provider{client: &clientAdapterLocal{client: &mockLocalEvaluator{}}, state: of.NotReadyState}
+
+		assert.ErrorContains(t, provider.ForceSync(context.Background()), providerNotReady)
+	})
+}
@@ -3,7 +3,12 @@ package amplitude
 import (
 	"context"
 	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
 
+	analytics "github.com/amplitude/analytics-go/amplitude"
 	experiment "github.com/amplitude/experiment-go-server/pkg/experiment"
 )
 
@@ -23,6 +28,8 @@ type mockClientAdapter struct {
 	stopCalled bool
 	// evaluateCalls tracks all calls to Evaluate.
 	evaluateCalls []mockEvaluateCall
+	// evaluateMu guards evaluateCalls for concurrent evaluations.
+	evaluateMu sync.Mutex
 }
 
 // mockEvaluateCall records the arguments to an Evaluate call.
@@ -52,11 +59,13 @@ func (m *mockClientAdapter) Stop() error {
 
 // Evaluate implements clientAdapter.
 func (m *mockClientAdapter) Evaluate(ctx context.Context, user *experiment.User, flagKeys []string) (map[string]experiment.Variant, error) {
+	m.evaluateMu.Lock()
 	m.evaluateCalls = append(m.evaluateCalls, mockEvaluateCall{
 		Ctx:      ctx,
 		User:     user,
 		FlagKeys: flagKeys,
 	})
+	m.evaluateMu.Unlock()
 	if m.EvaluateFunc != nil {
 		return m.EvaluateFunc(ctx, user, flagKeys)
 	}
@@ -79,3 +88,76 @@ func makeVariant(key string, value string, payload any) experiment.Variant {
 	}
 }
 
+// recordingLoggerProvider is a logger.LoggerProvider which records messages for testing.
+type recordingLoggerProvider struct {
+	mu       sync.Mutex
+	debugs   []string
+	warnings []string
+	errors   []string
+}
+
+func (r *recordingLoggerProvider) Verbose(string, ...any) {}
+func (r *recordingLoggerProvider) Info(string, ...any)    {}
+
+func (r *recordingLoggerProvider) Debug(message string, args ...any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.debugs = append(r.debugs, fmt.Sprintf(message, args...))
+}
+
+func (r *recordingLoggerProvider) Warn(message string, args ...any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.warnings = append(r.warnings, fmt.Sprintf(message, args...))
+}
+
+func (r *recordingLoggerProvider) Error(message string, args ...any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.errors = append(r.errors, fmt.Sprintf(message, args...))
+}
+
+// mockAnalyticsClient is a mock analytics.Client which records tracked events.
+// Methods which are not overridden panic, because the embedded interface is nil.
+type mockAnalyticsClient struct {
+	analytics.Client
+
+	// TrackFunc is called when Track is called, if set.
+	TrackFunc func(event analytics.Event)
+
+	mu     sync.Mutex
+	events []analytics.Event
+	// shutdownCalled tracks if Shutdown was called.
+	shutdownCalled bool
+}
+
+// Track implements analytics.Client.
+func (m *mockAnalyticsClient) Track(event analytics.Event) {
+	m.mu.Lock()
+	m.events = append(m.events, event)
+	m.mu.Unlock()
+	if m.TrackFunc != nil {
+		m.TrackFunc(event)
+	}
+}
+
+// Flush implements analytics.Client.
+func (m *mockAnalyticsClient) Flush() {}
+
+// Shutdown implements analytics.Client.
+func (m *mockAnalyticsClient) Shutdown() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.shutdownCalled = true
+}
+
+// deploymentKeys counts the deployment keys created by uniqueDeploymentKey.
+var deploymentKeys atomic.Int64
+
+// uniqueDeploymentKey returns a deployment key no other test run uses. The SDK shares one client
+// per deployment key for the life of the process, so a test pointing the client at its own server
+// needs a new key each run (such as with -count), or it reuses a client of a closed server.
+func uniqueDeploymentKey(t *testing.T) string {
+	t.Helper()
+	return fmt.Sprintf("test-key-%s-%d", t.Name(), deploymentKeys.Add(1))
+}
@@ -4,17 +4,28 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"maps"
+	"net/http"
+	"reflect"
+	"slices"
+	"sync"
+	"time"
 
 	experiment "github.com/amplitude/experiment-go-server/pkg/experiment"
 	"github.com/amplitude/experiment-go-server/pkg/experiment/remote"
 )
 
+// maxConcurrentRefreshes bounds the number of background refreshes
+// which may be in flight at once when stale-while-revalidate is enabled.
+const maxConcurrentRefreshes = 16
+
 // remoteEvaluator is an interface for the remote evaluation client.
 // This allows for testing with a mock implementation.
 type remoteEvaluator interface {
-	FetchV2(user *experiment.User) (map[string]experiment.Variant, error)
+	FetchV2WithContext(user *experiment.User, ctx context.Context) (map[string]experiment.Variant, error)
 }
 
 // RemoteClient wraps the Amplitude remote evaluation client to implement ExperimentClient.
@@ -22,12 +33,63 @@ type clientAdapterRemote struct {
 	evaluator remoteEvaluator
 	cache     Cache
 	config    remoteConfig
+
+	// now returns the current time; it is replaced in tests.
+	now func() time.Time
+	// refreshing tracks the cache keys with a background refresh in flight.
+	refreshing   map[string]struct{}
+	refreshingMu sync.Mutex
+	// refreshSlots bounds the number of concurrent background refreshes.
+	refreshSlots chan struct{}
 }
 
 // RemoteConfig contains configuration for remote evaluation.
 type remoteConfig struct {
 	remote.Config
 	Cache Cache
+	// SoftTTL is the age after which a cached result is refreshed in the background.
+	// If zero, stale-while-revalidate is disabled.
+	SoftTTL time.Duration
+	// HardTTL is the age after which a cached result is no longer used.
+	HardTTL time.Duration
+	// CacheKeyAttributes are the user attributes from which the cache key is computed.
+	// If empty, the whole user is used.
+	CacheKeyAttributes []Key
+	// Metrics optionally records cache lookups.
+	Metrics Metrics
+	// Codec optionally serializes cached values; if nil, the variants are cached as they are.
+	Codec *CacheCodec
+}
+
+// cacheEntry is the value stored in the cache when stale-while-revalidate is enabled.
+type cacheEntry struct {
+	variants map[string]experiment.Variant
+	// fetchedAt is when the variants were fetched from Amplitude.
+	fetchedAt time.Time
+	// version identifies the flag configs the variants were evaluated with,
+	// so that entries can be compared without comparing the variants.
+	// Remote evaluation responses have no ETag or other version token, so it is derived
+	// from the flag versions in the variant metadata; see [variantsVersion].
+	version string
+}
+
+// variantsVersion returns a digest of the flag versions of the variants,
+// or "" if none of the variants has a flag version.
+func variantsVersion(variants map[string]experiment.Variant) string {
+	hash := sha256.New()
+	versioned := false
+	for _, flagKey := range slices.Sorted(maps.Keys(variants)) {
+		version, ok := flagVersion(variants[flagKey].Metadata["flagVersion"])
+		if !ok {
+			continue
+		}
+		versioned = true
+		fmt.Fprintf(hash, "%s:%d\n", flagKey, version)
+	}
+	if !versioned {
+		return ""
+	}
+	return fmt.Sprintf("%x", hash.Sum(nil))
 }
 
 // NewRemoteClient creates a new RemoteClient with the given deployment key, config, and logger.
@@ -50,37 +112,276 @@ func (c *clientAdapterRemote) Stop() error {
 }
 
 // Evaluate evaluates the given flags for the given user using remote evaluation.
-// Note: Remote evaluation fetches all variants for the user; flagKeys is ignored.
-func (c *clientAdapterRemote) Evaluate(ctx context.Context, user *experiment.User, _ []string) (map[string]experiment.Variant, error) {
+// Remote evaluation fetches all variants for the user, which are cached whole;
+// the result is then narrowed to flagKeys, if any.
+func (c *clientAdapterRemote) Evaluate(ctx context.Context, user *experiment.User, flagKeys []string) (map[string]experiment.Variant, error) {
 	// Check if the cache has the variants for the given context
 	var cacheKey string
 	if c.cache != nil {
-		hasher := sha256.New()
-		encodeErr := json.NewEncoder(hasher).Encode(user)
-		if encodeErr != nil {
-			return nil, fmt.Errorf("failed to encode user to create cache key: %w", encodeErr)
+		var keyErr error
+		// The SDK fetches all flags whatever the flag keys, so the results are cached for the user
+		// alone and shared by every set of flag keys; only a fetch scoped to the flag keys would
+		// need them in its cache key.
+		cacheKey, keyErr = c.cacheKey(user, nil)
+		if keyErr != nil {
+			return nil, keyErr
 		}
-		cacheKey = string(hasher.Sum(nil))
 		cacheValue, cacheErr := c.cache.Get(ctx, cacheKey)
+		if cacheErr == nil && cacheValue != nil && c.config.Codec != nil {
+			cacheValue = c.decodeCachedValue(cacheValue)
+		}
 		if cacheErr == nil && cacheValue != nil {
-			return cacheValue.(map[string]experiment.Variant), nil
+			switch cached := cacheValue.(type) {
+			case cacheEntry:
+				if variants, ok := c.fromCacheEntry(ctx, cacheKey, user, cached); ok {
+					c.recordCacheLookup(ctx, true)
+					return selectVariants(variants, flagKeys), nil
+				}
+			case map[string]experiment.Variant:
+				c.recordCacheLookup(ctx, true)
+				return selectVariants(cached, flagKeys), nil
+			default:
+				// A cache backed by a serializing store may return another type, such as []byte;
+				// treat it as a miss rather than failing the evaluation.
+				c.logError("amplitude: ignoring cached value of unexpected type %T; fetching the variants", cacheValue)
+			}
 		}
+		c.recordCacheLookup(ctx, false)
 	}
-	variants, fetchErr := c.evaluator.FetchV2(user)
+	variants, fetchErr := c.fetch(ctx, user)
 	if fetchErr != nil {
 		return nil, fetchErr
 	}
 
 	// Store the variants in the cache (best effort - log errors but don't fail evaluation)
 	if c.cache != nil {
-		if setErr := c.cache.Set(ctx, cacheKey, variants); setErr != nil {
-			if c.config.LoggerProvider != nil {
-				c.config.LoggerProvider.Error("amplitude: failed to store variants in cache: %s", setErr)
-			} else {
-				log.Printf("amplitude: failed to store variants in cache: %v", setErr)
-			}
+		c.storeVariants(ctx, cacheKey, variants)
+	}
+
+	return selectVariants(variants, flagKeys), nil
+}
+
+// selectVariants returns the variants of the given flags, or all variants without flag keys.
+func selectVariants(variants map[string]experiment.Variant, flagKeys []string) map[string]experiment.Variant {
+	if len(flagKeys) == 0 {
+		return variants
+	}
+	selected := make(map[string]experiment.Variant, len(flagKeys))
+	for _, flagKey := range flagKeys {
+		if variant, ok := variants[flagKey]; ok {
+			selected[flagKey] = variant
+		}
+	}
+	return selected
+}
+
+// readinessProber is implemented by client adapters which can check that Amplitude accepts
+// the deployment key; see [WithRemoteReadinessProbe].
+type readinessProber interface {
+	probeReadiness() error
+}
+
+// readinessProbeUserID is the user ID of the synthetic user evaluated by readiness probes.
+const readinessProbeUserID = "openfeature-amplitude-readiness-probe"
+
+// probeReadiness fetches the variants of a synthetic user, bypassing the cache.
+func (c *clientAdapterRemote) probeReadiness() error {
+	_, err := c.evaluator.FetchV2WithContext(&experiment.User{UserId: readinessProbeUserID}, context.Background())
+	return err
+}
+
+// isAuthError returns true if the error is a remote evaluation request rejected as unauthorized.
+func isAuthError(err error) bool {
+	statusCode, ok := errorStatusCode(err)
+	return ok && (statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden)
+}
+
+// statusCoder is implemented by errors which carry the HTTP status code of a failed request.
+type statusCoder interface {
+	StatusCode() int
+}
+
+// errorStatusCode returns the HTTP status code carried by the error or an error it wraps.
+// The remote evaluation SDK's error type is unexported and has no methods but Error,
+// so failing a [statusCoder], its exported StatusCode field is read by reflection.
+func errorStatusCode(err error) (int, bool) {
+	var coder statusCoder
+	if errors.As(err, &coder) {
+		return coder.StatusCode(), true
+	}
+	for ; err != nil; err = errors.Unwrap(err) {
+		value := reflect.ValueOf(err)
+		if value.Kind() != reflect.Pointer || value.Elem().Kind() != reflect.Struct {
+			continue
+		}
+		statusCode := value.Elem().FieldByName("StatusCode")
+		if statusCode.IsValid() && statusCode.Kind() == reflect.Int {
+			return int(statusCode.Int()), true
+		}
+	}
+	return 0, false
+}
+
+// cacheKeyer is implemented by client adapters which cache results by a key computed from the user.
+type cacheKeyer interface {
+	cacheKey(user *experiment.User, flagKeys []string) (string, error)
+}
+
+// cacheKey returns the cache key for the user: a hash of the whole user,
+// or of only the configured cache key attributes, and of the sorted flag keys, if any,
+// so that results fetched for different sets of flags aren't mixed up.
+// Without flag keys, the key is a hash of the user alone.
+func (c *clientAdapterRemote) cacheKey(user *experiment.User, flagKeys []string) (string, error) {
+	var subject any = user
+	if len(c.config.CacheKeyAttributes) > 0 {
+		attributes, err := selectUserAttributes(user, c.config.CacheKeyAttributes)
+		if err != nil {
+			return "", fmt.Errorf("failed to select user attributes to create cache key: %w", err)
+		}
+		subject = attributes
+	}
+
+	hasher := sha256.New()
+	encoder := json.NewEncoder(hasher)
+	encodeErr := encoder.Encode(subject)
+	if encodeErr != nil {
+		return "", fmt.Errorf("failed to encode user to create cache key: %w", encodeErr)
+	}
+	if len(flagKeys) > 0 {
+		if encodeErr := encoder.Encode(slices.Sorted(slices.Values(flagKeys))); encodeErr != nil {
+			return "", fmt.Errorf("failed to encode flag keys to create cache key: %w", encodeErr)
+		}
+	}
+	return string(hasher.Sum(nil)), nil
+}
+
+// selectUserAttributes returns the JSON-encoded values of the given attributes of the user.
+// Attributes which are unset are omitted.
+func selectUserAttributes(user *experiment.User, keys []Key) (map[Key]json.RawMessage, error) {
+	userJSON, err := json.Marshal(user)
+	if err != nil {
+		return nil, err
+	}
+	var all map[Key]json.RawMessage
+	if err := json.Unmarshal(userJSON, &all); err != nil {
+		return nil, err
+	}
+
+	selected := make(map[Key]json.RawMessage, len(keys))
+	for _, key := range keys {
+		if value, ok := all[key]; ok {
+			selected[key] = value
+		}
+	}
+	return selected, nil
+}
+
+// fromCacheEntry returns the variants from a cache entry, unless the entry is past the hard TTL.
+// If the entry is past the soft TTL, a background refresh is started.
+func (c *clientAdapterRemote) fromCacheEntry(ctx context.Context, cacheKey string, user *experiment.User, entry cacheEntry) (map[string]experiment.Variant, bool) {
+	age := c.getNow().Sub(entry.fetchedAt)
+	if c.config.HardTTL > 0 && age >= c.config.HardTTL {
+		return nil, false
+	}
+	if c.config.SoftTTL > 0 && age >= c.config.SoftTTL {
+		c.refresh(ctx, cacheKey, user)
+	}
+	return entry.variants, true
+}
+
+// refresh fetches the variants for the user in the background and stores them in the cache.
+// At most one refresh per cache key is in flight at a time, and the number of
+// concurrent refreshes is bounded; if no slot is available the refresh is skipped,
+// and will be retried by a later evaluation.
+func (c *clientAdapterRemote) refresh(ctx context.Context, cacheKey string, user *experiment.User) {
+	c.refreshingMu.Lock()
+	if c.refreshing == nil {
+		c.refreshing = make(map[string]struct{})
+		c.refreshSlots = make(chan struct{}, maxConcurrentRefreshes)
+	}
+	if _, ok := c.refreshing[cacheKey]; ok {
+		c.refreshingMu.Unlock()
+		return
+	}
+	select {
+	case c.refreshSlots <- struct{}{}:
+	default:
+		c.refreshingMu.Unlock()
+		return
+	}
+	c.refreshing[cacheKey] = struct{}{}
+	c.refreshingMu.Unlock()
+
+	// The refresh outlives the evaluation, so it must not be cancelled with it.
+	refreshCtx := context.WithoutCancel(ctx)
+	go func() {
+		defer func() {
+			c.refreshingMu.Lock()
+			delete(c.refreshing, cacheKey)
+			<-c.refreshSlots
+			c.refreshingMu.Unlock()
+		}()
+
+		variants, fetchErr := c.fetch(refreshCtx, user)
+		if fetchErr != nil {
+			c.logError("amplitude: failed to refresh cached variants: %v", fetchErr)
+			return
+		}
+		c.storeVariants(refreshCtx, cacheKey, variants)
+	}()
+}
+
+// fetch fetches the variants for a copy of the user: the SDK sets the library on the user it's given,
+// which would change the user's cache and memo keys after the fetch.
+func (c *clientAdapterRemote) fetch(ctx context.Context, user *experiment.User) (map[string]experiment.Variant, error) {
+	fetchUser := *user
+	return c.evaluator.FetchV2WithContext(&fetchUser, ctx)
+}
+
+// storeVariants stores the variants in the cache, logging any error.
+func (c *clientAdapterRemote) storeVariants(ctx context.Context, cacheKey string, variants map[string]experiment.Variant) {
+	var value any = variants
+	if c.config.SoftTTL > 0 {
+		value = cacheEntry{
+			variants:  variants,
+			fetchedAt: c.getNow(),
+			version:   variantsVersion(variants),
+		}
+	}
+	if c.config.Codec != nil {
+		encoded, encodeErr := c.encodeCacheValue(value)
+		if encodeErr != nil {
+			c.logError("amplitude: failed to encode variants for the cache: %v", encodeErr)
+			return
 		}
+		value = encoded
+	}
+	if setErr := c.cache.Set(ctx, cacheKey, value); setErr != nil {
+		c.logError("amplitude: failed to store variants in cache: %v", setErr)
+	}
+}
+
+// recordCacheLookup records a cache lookup, if metrics are configured.
+func (c *clientAdapterRemote) recordCacheLookup(ctx context.Context, hit bool) {
+	if c.config.Metrics != nil {
+		c.config.Metrics.RecordCacheLookup(ctx, hit)
 	}
+}
 
-	return variants, nil
+// logError logs an error using the configured logger provider,
+// falling back to the standard library logger.
+func (c *clientAdapterRemote) logError(format string, args ...any) {
+	if c.config.LoggerProvider != nil {
+		c.config.LoggerProvider.Error(format, args...)
+	} else {
+		log.Printf(format, args...)
+	}
+}
+
+// getNow returns the current time.
+func (c *clientAdapterRemote) getNow() time.Time {
+	if c.now == nil {
+		return time.Now()
+	}
+	return c.now()
 }
@@ -3,9 +3,19 @@ package amplitude
 import (
 	"context"
 	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	experiment "github.com/amplitude/experiment-go-server/pkg/experiment"
+	"github.com/amplitude/experiment-go-server/pkg/experiment/remote"
+	"github.com/amplitude/experiment-go-server/pkg/logger"
+	of "github.com/open-feature/go-sdk/openfeature"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -53,7 +63,7 @@ type mockRemoteEvaluator struct {
 	fetchCalls []*experiment.User
 }
 
-func (m *mockRemoteEvaluator) FetchV2(user *experiment.User) (map[string]experiment.Variant, error) {
+func (m *mockRemoteEvaluator) FetchV2WithContext(user *experiment.User, _ context.Context) (map[string]experiment.Variant, error) {
 	m.fetchCalls = append(m.fetchCalls, user)
 	if m.fetchFunc != nil {
 		return m.fetchFunc(user)
@@ -257,3 +267,754 @@ func TestClientAdapterRemote_Evaluate_CacheGetError_StillFetches(t *testing.T) {
 	assert.Len(t, evaluator.fetchCalls, 1)
 }
 
+// serializingCache is a Cache which stores values as strings, like a cache backed by an external store.
+type serializingCache struct {
+	syncCache
+}
+
+func (c *serializingCache) Set(ctx context.Context, key string, value any) error {
+	return c.syncCache.Set(ctx, key, fmt.Sprint(value))
+}
+
+func TestClientAdapterRemote_Evaluate_UnexpectedCachedType_StillFetches(t *testing.T) {
+	expectedVariants := map[string]experiment.Variant{
+		"flag-1": {Key: "on", Value: "enabled"},
+	}
+	evaluator := &mockRemoteEvaluator{
+		fetchFunc: func(user *experiment.User) (map[string]experiment.Variant, error) {
+			return expectedVariants, nil
+		},
+	}
+	loggerProvider := &recordingLoggerProvider{}
+	client := &clientAdapterRemote{
+		evaluator: evaluator,
+		cache:     &serializingCache{},
+		config:    remoteConfig{Config: remote.Config{LoggerProvider: loggerProvider}},
+	}
+	user := &experiment.User{UserId: "user-1"}
+
+	for range 2 {
+		var result map[string]experiment.Variant
+		var err error
+		require.NotPanics(t, func() {
+			result, err = client.Evaluate(context.Background(), user, nil)
+		})
+		require.NoError(t, err)
+		assert.Equal(t, expectedVariants, result)
+	}
+
+	assert.Len(t, evaluator.fetchCalls, 2, "the unusable cached value is treated as a miss")
+	require.Len(t, loggerProvider.errors, 1)
+	assert.Contains(t, loggerProvider.errors[0], "unexpected type string")
+}
+
+func TestClientAdapterRemote_Evaluate_Codec(t *testing.T) {
+	user := &experiment.User{UserId: "user-1"}
+
+	t.Run("variants are stored serialized and decoded on hits", func(t *testing.T) {
+		evaluator := &countingRemoteEvaluator{}
+		cache := &syncCache{}
+		client := &clientAdapterRemote{
+			evaluator: evaluator,
+			cache:     cache,
+			config:    remoteConfig{Codec: &JSONCacheCodec},
+		}
+
+		first, err := client.Evaluate(context.Background(), user, nil)
+		require.NoError(t, err)
+		second, err := client.Evaluate(context.Background(), user, nil)
+		require.NoError(t, err)
+
+		assert.Equal(t, first, second)
+		assert.EqualValues(t, 1, evaluator.fetches.Load())
+		require.Len(t, cache.data, 1)
+		for _, value := range cache.data {
+			assert.IsType(t, []byte{}, value)
+		}
+	})
+
+	t.Run("string values are decoded", func(t *testing.T) {
+		evaluator := &countingRemoteEvaluator{}
+		cache := &syncCache{}
+		client := &clientAdapterRemote{
+			evaluator: evaluator,
+			cache:     cache,
+			config:    remoteConfig{Codec: &JSONCacheCodec},
+		}
+		cacheKey, err := client.cacheKey(user, nil)
+		require.NoError(t, err)
+		require.NoError(t, cache.Set(context.Background(), cacheKey, `{"flag-1":{"key":"cached","value":"cached"}}`))
+
+		result, err := client.Evaluate(context.Background(), user, nil)
+
+		require.NoError(t, err)
+		assert.Equal(t, "cached", result["flag-1"].Value)
+		assert.EqualValues(t, 0, evaluator.fetches.Load())
+	})
+
+	t.Run("undecodable values are treated as misses", func(t *testing.T) {
+		evaluator := &countingRemoteEvaluator{}
+		cache := &syncCache{}
+		loggerProvider := &recordingLoggerProvider{}
+		client := &clientAdapterRemote{
+			evaluator: evaluator,
+			cache:     cache,
+			config: remoteConfig{
+				Config: remote.Config{LoggerProvider: loggerProvider},
+				Codec:  &JSONCacheCodec,
+			},
+		}
+		cacheKey, err := client.cacheKey(user, nil)
+		require.NoError(t, err)
+		require.NoError(t, cache.Set(context.Background(), cacheKey, []byte("not json")))
+
+		result, err := client.Evaluate(context.Background(), user, nil)
+
+		require.NoError(t, err)
+		assert.Equal(t, "1", result["flag-1"].Value)
+		assert.EqualValues(t, 1, evaluator.fetches.Load())
+		require.Len(t, loggerProvider.errors, 1)
+		assert.Contains(t, loggerProvider.errors[0], "failed to decode cached variants")
+	})
+
+	t.Run("encoding errors skip the cache", func(t *testing.T) {
+		evaluator := &countingRemoteEvaluator{}
+		cache := &syncCache{}
+		loggerProvider := &recordingLoggerProvider{}
+		client := &clientAdapterRemote{
+			evaluator: evaluator,
+			cache:     cache,
+			config: remoteConfig{
+				Config: remote.Config{LoggerProvider: loggerProvider},
+				Codec: &CacheCodec{
+					Marshal: func(map[string]experiment.Variant) ([]byte, error) {
+						return nil, errors.New("boom")
+					},
+					Unmarshal: JSONCacheCodec.Unmarshal,
+				},
+			},
+		}
+
+		_, err := client.Evaluate(context.Background(), user, nil)
+
+		require.NoError(t, err)
+		assert.Empty(t, cache.data)
+		require.Len(t, loggerProvider.errors, 1)
+		assert.Contains(t, loggerProvider.errors[0], "boom")
+	})
+
+	t.Run("stale-while-revalidate keeps the fetch time", func(t *testing.T) {
+		now := time.Now()
+		evaluator := &countingRemoteEvaluator{}
+		cache := &syncCache{}
+		client := &clientAdapterRemote{
+			evaluator: evaluator,
+			cache:     cache,
+			config: remoteConfig{
+				Cache:   cache,
+				SoftTTL: time.Minute,
+				HardTTL: time.Hour,
+				Codec:   &JSONCacheCodec,
+			},
+			now: func() time.Time { return now },
+		}
+
+		_, err := client.Evaluate(context.Background(), user, nil)
+		require.NoError(t, err)
+		now = now.Add(time.Minute - time.Second)
+		result, err := client.Evaluate(context.Background(), user, nil)
+		require.NoError(t, err)
+		assert.Equal(t, "1", result["flag-1"].Value)
+		assert.EqualValues(t, 1, evaluator.fetches.Load())
+
+		now = now.Add(time.Hour)
+		result, err = client.Evaluate(context.Background(), user, nil)
+		require.NoError(t, err)
+		assert.Equal(t, "2", result["flag-1"].Value, "hard-expired entries are not served")
+		assert.EqualValues(t, 2, evaluator.fetches.Load())
+	})
+}
+
+// syncCache is a concurrency-safe Cache for testing.
+type syncCache struct {
+	mu   sync.Mutex
+	data map[string]any
+}
+
+func (c *syncCache) Get(_ context.Context, key string) (any, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.data[key], nil
+}
+
+func (c *syncCache) Set(_ context.Context, key string, value any) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.data == nil {
+		c.data = make(map[string]any)
+	}
+	c.data[key] = value
+	return nil
+}
+
+// countingRemoteEvaluator is a concurrency-safe remoteEvaluator which counts fetches,
+// returning a variant whose value is the fetch number.
+type countingRemoteEvaluator struct {
+	fetches atomic.Int32
+	// release, if set, blocks fetches until it is closed.
+	release chan struct{}
+	// fetched, if set, is signalled after each fetch.
+	fetched chan struct{}
+}
+
+func (e *countingRemoteEvaluator) FetchV2WithContext(_ *experiment.User, _ context.Context) (map[string]experiment.Variant, error) {
+	n := e.fetches.Add(1)
+	if e.release != nil {
+		<-e.release
+	}
+	if e.fetched != nil {
+		defer func() { e.fetched <- struct{}{} }()
+	}
+	return map[string]experiment.Variant{
+		"flag-1": {Key: "on", Value: strconv.Itoa(int(n))},
+	}, nil
+}
+
+func TestClientAdapterRemote_Evaluate_StaleWhileRevalidate(t *testing.T) {
+	user := &experiment.User{UserId: "user-1"}
+	newClient := func(evaluator remoteEvaluator, now *time.Time) *clientAdapterRemote {
+		cache := &syncCache{}
+		return &clientAdapterRemote{
+			evaluator: evaluator,
+			cache:     cache,
+			config: remoteConfig{
+				Cache:   cache,
+				SoftTTL: time.Minute,
+				HardTTL: time.Hour,
+			},
+			now: func() time.Time { return *now },
+		}
+	}
+
+	t.Run("fresh entries are served from the cache", func(t *testing.T) {
+		now := time.Now()
+		evaluator := &countingRemoteEvaluator{}
+		client := newClient(evaluator, &now)
+
+		_, err := client.Evaluate(context.Background(), user, nil)
+		require.NoError(t, err)
+		now = now.Add(time.Minute - time.Second)
+		result, err := client.Evaluate(context.Background(), user, nil)
+
+		require.NoError(t, err)
+		assert.Equal(t, "1", result["flag-1"].Value)
+		assert.EqualValues(t, 1, evaluator.fetches.Load())
+	})
+
+	t.Run("soft-expired entries are served immediately and refreshed exactly once", func(t *testing.T) {
+		now := time.Now()
+		evaluator := &countingRemoteEvaluator{}
+		client := newClient(evaluator, &now)
+		_, err := client.Evaluate(context.Background(), user, nil)
+		require.NoError(t, err)
+
+		// Block the refresh so that all concurrent evaluations observe the stale entry.
+		evaluator.release = make(chan struct{})
+		evaluator.fetched = make(chan struct{}, 10)
+		now = now.Add(2 * time.Minute)
+
+		var wg sync.WaitGroup
+		for range 20 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				ctx, cancel := context.WithCancel(context.Background())
+				result, err := client.Evaluate(ctx, user, nil)
+				cancel()
+				assert.NoError(t, err)
+				assert.Equal(t, "1", result["flag-1"].Value, "stale entry should be served")
+			}()
+		}
+		wg.Wait()
+		close(evaluator.release)
+
+		select {
+		case <-evaluator.fetched:
+		case <-time.After(time.Second):
+			t.Fatal("expected a background refresh")
+		}
+		assert.EqualValues(t, 2, evaluator.fetches.Load(), "expected exactly one refresh")
+
+		// The refreshed entry is served once the refresh completes.
+		assert.Eventually(t, func() bool {
+			result, err := client.Evaluate(context.Background(), user, nil)
+			return err == nil && result["flag-1"].Value == "2"
+		}, time.Second, time.Millisecond)
+		assert.EqualValues(t, 2, evaluator.fetches.Load())
+	})
+
+	t.Run("hard-expired entries are fetched synchronously", func(t *testing.T) {
+		now := time.Now()
+		evaluator := &countingRemoteEvaluator{}
+		client := newClient(evaluator, &now)
+		_, err := client.Evaluate(context.Background(), user, nil)
+		require.NoError(t, err)
+
+		now = now.Add(time.Hour)
+		result, err := client.Evaluate(context.Background(), user, nil)
+
+		require.NoError(t, err)
+		assert.Equal(t, "2", result["flag-1"].Value)
+		assert.EqualValues(t, 2, evaluator.fetches.Load())
+	})
+}
+
+func TestVariantsVersion(t *testing.T) {
+	variants := func(versionA, versionB any) map[string]experiment.Variant {
+		return map[string]experiment.Variant{
+			"flag-a": {Key: "on", Metadata: map[string]any{"flagVersion": versionA}},
+			"flag-b": {Key: "off", Metadata: map[string]any{"flagVersion": versionB}},
+		}
+	}
+
+	version := variantsVersion(variants(float64(3), float64(7)))
+
+	assert.NotEmpty(t, version)
+	assert.Equal(t, version, variantsVersion(variants(3, int64(7))), "numeric types shouldn't matter")
+	assert.NotEqual(t, version, variantsVersion(variants(float64(4), float64(7))))
+	assert.Empty(t, variantsVersion(map[string]experiment.Variant{"flag-a": {Key: "on"}}))
+	assert.Empty(t, variantsVersion(nil))
+}
+
+func TestClientAdapterRemote_CacheEntryMetadata(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	fetched := map[string]experiment.Variant{
+		"flag-1": {Key: "on", Metadata: map[string]any{"flagVersion": float64(2)}},
+	}
+	cache := &syncCache{}
+	client := &clientAdapterRemote{
+		evaluator: &mockRemoteEvaluator{fetchFunc: func(*experiment.User) (map[string]experiment.Variant, error) {
+			return fetched, nil
+		}},
+		cache:  cache,
+		config: remoteConfig{Cache: cache, SoftTTL: time.Minute},
+		now:    func() time.Time { return now },
+	}
+
+	_, err := client.Evaluate(context.Background(), &experiment.User{UserId: "user-1"}, nil)
+	require.NoError(t, err)
+
+	require.Len(t, cache.data, 1)
+	for _, value := range cache.data {
+		entry, ok := value.(cacheEntry)
+		require.True(t, ok)
+		assert.Equal(t, now, entry.fetchedAt)
+		assert.Equal(t, variantsVersion(fetched), entry.version)
+		assert.NotEmpty(t, entry.version)
+	}
+}
+
+func TestNew_StaleWhileRevalidateValidation(t *testing.T) {
+	tests := []struct {
+		name        string
+		options     []Option
+		expectedErr string
+	}{
+		{
+			name:        "requires a cache",
+			options:     []Option{WithStaleWhileRevalidate(time.Minute, time.Hour)},
+			expectedErr: "requires a remote evaluation cache",
+		},
+		{
+			name:        "requires a positive soft TTL",
+			options:     []Option{WithRemoteEvaluationCache(&syncCache{}), WithStaleWhileRevalidate(0, time.Hour)},
+			expectedErr: "must be positive",
+		},
+		{
+			name:        "requires the soft TTL to be no greater than the hard TTL",
+			options:     []Option{WithRemoteEvaluationCache(&syncCache{}), WithStaleWhileRevalidate(time.Hour, time.Minute)},
+			expectedErr: "no greater than the hard TTL",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := append([]Option{WithRemoteConfig(remote.Config{})}, tt.options...)
+			_, err := New(context.Background(), "test-deployment-key", options...)
+			assert.ErrorContains(t, err, tt.expectedErr)
+		})
+	}
+
+	t.Run("valid configuration", func(t *testing.T) {
+		provider, err := New(context.Background(), "test-deployment-key",
+			WithRemoteConfig(remote.Config{}),
+			WithRemoteEvaluationCache(&syncCache{}),
+			WithStaleWhileRevalidate(time.Minute, time.Hour),
+		)
+		require.NoError(t, err)
+		remoteClient := provider.client.(*clientAdapterRemote)
+		assert.Equal(t, time.Minute, remoteClient.config.SoftTTL)
+		assert.Equal(t, time.Hour, remoteClient.config.HardTTL)
+	})
+}
+
+func TestClientAdapterRemote_CacheKeyAttributes(t *testing.T) {
+	userA := &experiment.User{
+		UserId:         "user-1",
+		Platform:       PlatformIOS,
+		UserProperties: map[string]any{"request_id": "abc"},
+	}
+	userB := &experiment.User{
+		UserId:         "user-1",
+		Platform:       PlatformIOS,
+		UserProperties: map[string]any{"request_id": "def"},
+	}
+	userC := &experiment.User{
+		UserId:   "user-1",
+		Platform: PlatformAndroid,
+	}
+
+	t.Run("whole user by default", func(t *testing.T) {
+		client := &clientAdapterRemote{}
+
+		keyA, err := client.cacheKey(userA, nil)
+		require.NoError(t, err)
+		keyB, err := client.cacheKey(userB, nil)
+		require.NoError(t, err)
+
+		assert.NotEqual(t, keyA, keyB)
+	})
+
+	t.Run("only the listed attributes", func(t *testing.T) {
+		client := &clientAdapterRemote{config: remoteConfig{CacheKeyAttributes: []Key{KeyUserID, KeyPlatform}}}
+
+		keyA, err := client.cacheKey(userA, nil)
+		require.NoError(t, err)
+		keyB, err := client.cacheKey(userB, nil)
+		require.NoError(t, err)
+		keyC, err := client.cacheKey(userC, nil)
+		require.NoError(t, err)
+
+		assert.Equal(t, keyA, keyB, "users differing only in an excluded attribute should share a cache key")
+		assert.NotEqual(t, keyA, keyC, "users differing in a listed attribute should not share a cache key")
+	})
+
+	t.Run("cached results are shared", func(t *testing.T) {
+		evaluator := &countingRemoteEvaluator{}
+		client := &clientAdapterRemote{
+			evaluator: evaluator,
+			cache:     &syncCache{},
+			config:    remoteConfig{CacheKeyAttributes: []Key{KeyUserID}},
+		}
+
+		_, err := client.Evaluate(context.Background(), userA, nil)
+		require.NoError(t, err)
+		_, err = client.Evaluate(context.Background(), userB, nil)
+		require.NoError(t, err)
+
+		assert.EqualValues(t, 1, evaluator.fetches.Load())
+	})
+}
+
+func TestClientAdapterRemote_CacheKeyFlagKeys(t *testing.T) {
+	user := &experiment.User{UserId: "user-1"}
+	client := &clientAdapterRemote{}
+
+	t.Run("flag keys are part of the key", func(t *testing.T) {
+		keyA, err := client.cacheKey(user, []string{"flag-a"})
+		require.NoError(t, err)
+		keyAB, err := client.cacheKey(user, []string{"flag-a", "flag-b"})
+		require.NoError(t, err)
+		keyBA, err := client.cacheKey(user, []string{"flag-b", "flag-a"})
+		require.NoError(t, err)
+		keyUser, err := client.cacheKey(user, nil)
+		require.NoError(t, err)
+
+		assert.NotEqual(t, keyA, keyAB)
+		assert.Equal(t, keyAB, keyBA, "the order of the flag keys doesn't matter")
+		assert.NotEqual(t, keyUser, keyA)
+	})
+
+	t.Run("unscoped fetches are shared by all flag keys", func(t *testing.T) {
+		evaluator := &mockRemoteEvaluator{
+			fetchFunc: func(*experiment.User) (map[string]experiment.Variant, error) {
+				return map[string]experiment.Variant{
+					"flag-a": {Key: "on", Value: "a"},
+					"flag-b": {Key: "on", Value: "b"},
+				}, nil
+			},
+		}
+		client := &clientAdapterRemote{evaluator: evaluator, cache: &syncCache{}}
+
+		variantsA, err := client.Evaluate(context.Background(), user, []string{"flag-a"})
+		require.NoError(t, err)
+		variantsB, err := client.Evaluate(context.Background(), user, []string{"flag-b", "flag-c"})
+		require.NoError(t, err)
+		variantsAll, err := client.Evaluate(context.Background(), user, nil)
+		require.NoError(t, err)
+
+		assert.Len(t, evaluator.fetchCalls, 1)
+		assert.Equal(t, map[string]experiment.Variant{"flag-a": {Key: "on", Value: "a"}}, variantsA)
+		assert.Equal(t, map[string]experiment.Variant{"flag-b": {Key: "on", Value: "b"}}, variantsB)
+		assert.Len(t, variantsAll, 2)
+	})
+}
+
+func TestProvider_CacheKeyForContext(t *testing.T) {
+	evalCtx := of.FlattenedContext{of.TargetingKey: "user-1", "request_id": "abc"}
+
+	t.Run("matches the key used for caching", func(t *testing.T) {
+		cache := &syncCache{}
+		provider, err := New(context.Background(), "test-key",
+			WithRemoteConfig(remote.Config{}),
+			WithRemoteEvaluationCache(cache),
+			WithCacheKeyAttributes(KeyUserID),
+		)
+		require.NoError(t, err)
+		provider.client.(*clientAdapterRemote).evaluator = &countingRemoteEvaluator{}
+		require.NoError(t, provider.Init(of.EvaluationContext{}))
+
+		key, err := provider.CacheKeyForContext(context.Background(), evalCtx)
+		require.NoError(t, err)
+		_, err = provider.EvaluateAll(context.Background(), evalCtx)
+		require.NoError(t, err)
+
+		require.Len(t, cache.data, 1)
+		assert.Contains(t, cache.data, key)
+	})
+
+	t.Run("matches the key used for caching a flag", func(t *testing.T) {
+		cache := &syncCache{}
+		provider, err := New(context.Background(), "test-key",
+			WithRemoteConfig(remote.Config{}),
+			WithRemoteEvaluationCache(cache),
+		)
+		require.NoError(t, err)
+		provider.client.(*clientAdapterRemote).evaluator = &countingRemoteEvaluator{}
+		require.NoError(t, provider.Init(of.EvaluationContext{}))
+
+		key, err := provider.CacheKeyForContext(context.Background(), evalCtx)
+		require.NoError(t, err)
+		provider.StringEvaluation(context.Background(), "flag-1", "default", evalCtx)
+
+		require.Len(t, cache.data, 1)
+		assert.Contains(t, cache.data, key)
+	})
+
+	t.Run("respects the cache key attributes", func(t *testing.T) {
+		provider, err := New(context.Background(), "test-key",
+			WithRemoteConfig(remote.Config{}),
+			WithCacheKeyAttributes(KeyUserID),
+		)
+		require.NoError(t, err)
+
+		keyA, err := provider.CacheKeyForContext(context.Background(), evalCtx)
+		require.NoError(t, err)
+		keyB, err := provider.CacheKeyForContext(context.Background(), of.FlattenedContext{of.TargetingKey: "user-1", "request_id": "def"})
+		require.NoError(t, err)
+
+		assert.Equal(t, keyA, keyB)
+	})
+
+	t.Run("unavailable with local evaluation", func(t *testing.T) {
+		provider := newTestProvider(t, &mockClientAdapter{})
+
+		_, err := provider.CacheKeyForContext(context.Background(), evalCtx)
+
+		assert.ErrorIs(t, err, ErrCacheKeyUnavailable)
+	})
+}
+
+func TestConfig_getRemoteConfig_CacheKeyAttributes(t *testing.T) {
+	cfg := &Config{}
+	WithCacheKeyAttributes(KeyUserID, KeyDeviceID)(cfg)
+
+	assert.Equal(t, []Key{KeyUserID, KeyDeviceID}, cfg.getRemoteConfig().CacheKeyAttributes)
+}
+
+func TestConfig_getRemoteConfig_FetchSettings(t *testing.T) {
+	t.Run("timeout and retries are plumbed into the remote config", func(t *testing.T) {
+		cfg := &Config{}
+		WithRemoteFetchTimeout(150 * time.Millisecond)(cfg)
+		WithRemoteFetchRetries(3)(cfg)
+
+		result := cfg.getRemoteConfig()
+
+		assert.Equal(t, 150*time.Millisecond, result.FetchTimeout)
+		require.NotNil(t, result.RetryBackoff)
+		assert.Equal(t, 3, result.RetryBackoff.FetchRetries)
+		assert.Equal(t, remote.DefaultRetryBackoff.FetchRetryTimeout, result.RetryBackoff.FetchRetryTimeout)
+		assert.Equal(t, 1, remote.DefaultRetryBackoff.FetchRetries, "the SDK defaults must not be modified")
+	})
+
+	t.Run("retries can be disabled", func(t *testing.T) {
+		retryBackoff := &remote.RetryBackoff{FetchRetries: 2, FetchRetryTimeout: time.Second}
+		cfg := &Config{RemoteConfig: &remote.Config{RetryBackoff: retryBackoff}}
+		WithRemoteFetchRetries(-1)(cfg)
+
+		result := cfg.getRemoteConfig()
+
+		assert.Equal(t, 0, result.RetryBackoff.FetchRetries)
+		assert.Equal(t, time.Second, result.RetryBackoff.FetchRetryTimeout)
+		assert.Equal(t, 2, retryBackoff.FetchRetries, "the configured retry backoff must not be modified")
+	})
+
+	t.Run("the remote config is used by default", func(t *testing.T) {
+		cfg := &Config{RemoteConfig: &remote.Config{FetchTimeout: time.Second}}
+
+		result := cfg.getRemoteConfig()
+
+		assert.Equal(t, time.Second, result.FetchTimeout)
+		assert.Nil(t, result.RetryBackoff)
+	})
+}
+
+func TestProvider_RemoteReadinessProbe(t *testing.T) {
+	newServer := func(t *testing.T, status int) (*httptest.Server, *atomic.Int32) {
+		t.Helper()
+		var requests atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			requests.Add(1)
+			w.WriteHeader(status)
+			if status == http.StatusOK {
+				_, _ = w.Write([]byte(`{}`))
+			}
+		}))
+		t.Cleanup(server.Close)
+		return server, &requests
+	}
+	newProvider := func(t *testing.T, server *httptest.Server, loggerProvider logger.LoggerProvider, enabled bool) *Provider {
+		t.Helper()
+		provider, err := New(context.Background(), uniqueDeploymentKey(t),
+			WithRemoteConfig(remote.Config{ServerUrl: server.URL, LogLevel: logger.Warn, LoggerProvider: loggerProvider}),
+			WithRemoteFetchRetries(-1),
+			WithRemoteReadinessProbe(enabled),
+		)
+		require.NoError(t, err)
+		return provider
+	}
+
+	t.Run("a rejected deployment key fails Init", func(t *testing.T) {
+		server, requests := newServer(t, http.StatusUnauthorized)
+		provider := newProvider(t, server, &recordingLoggerProvider{}, true)
+
+		err := provider.Init(of.EvaluationContext{})
+
+		assert.ErrorIs(t, err, ErrDeploymentKeyRejected)
+		assert.ErrorContains(t, err, "401")
+		assert.Equal(t, of.ErrorState, provider.Status())
+		assert.EqualValues(t, 1, requests.Load())
+	})
+
+	t.Run("other failures are logged", func(t *testing.T) {
+		server, _ := newServer(t, http.StatusServiceUnavailable)
+		loggerProvider := &recordingLoggerProvider{}
+		provider := newProvider(t, server, loggerProvider, true)
+
+		err := provider.Init(of.EvaluationContext{})
+
+		require.NoError(t, err)
+		assert.Equal(t, of.ReadyState, provider.Status())
+		require.Len(t, loggerProvider.warnings, 1)
+		assert.Contains(t, loggerProvider.warnings[0], "the remote readiness probe failed")
+	})
+
+	t.Run("an accepted deployment key", func(t *testing.T) {
+		server, requests := newServer(t, http.StatusOK)
+		provider := newProvider(t, server, &recordingLoggerProvider{}, true)
+
+		require.NoError(t, provider.Init(of.EvaluationContext{}))
+		assert.Equal(t, of.ReadyState, provider.Status())
+		assert.EqualValues(t, 1, requests.Load())
+	})
+
+	t.Run("disabled by default", func(t *testing.T) {
+		server, requests := newServer(t, http.StatusUnauthorized)
+		provider := newProvider(t, server, &recordingLoggerProvider{}, false)
+
+		require.NoError(t, provider.Init(of.EvaluationContext{}))
+		assert.Zero(t, requests.Load())
+	})
+}
+
+// statusCodeError is an error carrying an HTTP status code through the statusCoder interface.
+type statusCodeError int
+
+func (e statusCodeError) Error() string   { return "status " + strconv.Itoa(int(e)) }
+func (e statusCodeError) StatusCode() int { return int(e) }
+
+// newSlowRemoteProvider returns a ready remote provider whose server doesn't answer
+// until the request is cancelled.
+func newSlowRemoteProvider(t *testing.T) *Provider {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	t.Cleanup(server.Close)
+	provider, err := New(context.Background(), uniqueDeploymentKey(t),
+		WithRemoteConfig(remote.Config{ServerUrl: server.URL, LogLevel: logger.Warn, LoggerProvider: &recordingLoggerProvider{}}),
+		WithRemoteFetchTimeout(5*time.Second),
+		WithRemoteFetchRetries(-1),
+		WithRemoteReadinessProbe(false),
+	)
+	require.NoError(t, err)
+	require.NoError(t, provider.Init(of.EvaluationContext{}))
+	t.Cleanup(provider.Shutdown)
+	return provider
+}
+
+func TestClientAdapterRemote_Evaluate_ContextDeadline(t *testing.T) {
+	provider := newSlowRemoteProvider(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := provider.EvaluateAll(ctx, of.FlattenedContext{of.TargetingKey: "user-1"})
+
+	assert.ErrorContains(t, err, context.DeadlineExceeded.Error())
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestIsAuthError(t *testing.T) {
+	// fetch pins the shape of the SDK's error for a rejected request.
+	fetch := func(t *testing.T, status int) error {
+		t.Helper()
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(status)
+		}))
+		defer server.Close()
+		client := remote.Initialize(uniqueDeploymentKey(t), &remote.Config{
+			ServerUrl:      server.URL,
+			LoggerProvider: &recordingLoggerProvider{},
+			RetryBackoff:   &remote.RetryBackoff{},
+		})
+		_, err := client.FetchV2(&experiment.User{UserId: "user-1"})
+		require.Error(t, err)
+		return err
+	}
+
+	t.Run("SDK errors", func(t *testing.T) {
+		assert.True(t, isAuthError(fetch(t, http.StatusUnauthorized)))
+		assert.True(t, isAuthError(fetch(t, http.StatusForbidden)))
+		assert.False(t, isAuthError(fetch(t, http.StatusServiceUnavailable)))
+	})
+
+	t.Run("wrapped SDK errors", func(t *testing.T) {
+		err := fmt.Errorf("probe failed: %w", fetch(t, http.StatusUnauthorized))
+
+		assert.True(t, isAuthError(err))
+	})
+
+	t.Run("errors with a status code method", func(t *testing.T) {
+		assert.True(t, isAuthError(fmt.Errorf("probe failed: %w", statusCodeError(http.StatusForbidden))))
+		assert.False(t, isAuthError(statusCodeError(http.StatusNotFound)))
+	})
+
+	t.Run("errors without a status code", func(t *testing.T) {
+		assert.False(t, isAuthError(errors.New("connection refused")))
+		assert.False(t, isAuthError(nil))
+	})
+}
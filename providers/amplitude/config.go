@@ -2,6 +2,9 @@ package amplitude
 
 import (
 	"context"
+	"maps"
+	"reflect"
+	"time"
 
 	analytics "github.com/amplitude/analytics-go/amplitude"
 	"github.com/amplitude/experiment-go-server/pkg/experiment"
@@ -16,27 +19,81 @@ import (
 type Config struct {
 	// DeploymentKey is the server deployment key from the Amplitude console.
 	DeploymentKey string
+	// ProviderName is the name returned by [Provider.Metadata].
+	// If unset, "Amplitude" is used.
+	ProviderName string
 	// LocalConfig is optional configuration for local evaluation.
 	// Local evaluation is the default behavior.
 	LocalConfig *local.Config
 	// RemoteConfig is optional configuration for remote evaluation.
-	// If set, remote evaluation will be used.
+	// If set, remote evaluation will be used (only for RemoteFlags, if they are set).
 	RemoteConfig *remote.Config
+	// RemoteFlags are the keys of flags which are evaluated remotely, while all other flags
+	// are evaluated locally; see [WithRemoteFlags].
+	// If set, both LocalConfig and RemoteConfig may be set.
+	RemoteFlags []string
 	// cache is an optional cache for remote evaluation.
 	// If set, the cache will be used to store the results of the evaluations.
 	RemoteEvaluationCache Cache
+	// RemoteEvaluationCodec optionally serializes the values stored in RemoteEvaluationCache,
+	// for caches backed by an out-of-process store; see [WithRemoteEvaluationCodec].
+	RemoteEvaluationCodec *CacheCodec
+	// StaleWhileRevalidateSoftTTL is the age after which a cached remote evaluation result
+	// is refreshed in the background, while still being served.
+	// If zero, cached results are served until the cache evicts them.
+	StaleWhileRevalidateSoftTTL time.Duration
+	// StaleWhileRevalidateHardTTL is the age after which a cached remote evaluation result
+	// is no longer served, and evaluation waits for a fresh result.
+	StaleWhileRevalidateHardTTL time.Duration
+	// RequiredAttributes are the attributes which the user must have to evaluate each flag,
+	// by flag key; see [WithRequiredAttributes].
+	RequiredAttributes map[string][]Key
+	// StickyBucketingKeys are the user fields which must be present for every remote evaluation,
+	// so that sticky bucketing sees a stable identity; see [WithStickyBucketing].
+	StickyBucketingKeys []Key
+	// RemoteFetchTimeout is the timeout of the first remote evaluation request for a user,
+	// overriding RemoteConfig.FetchTimeout. If zero, that (or the SDK default) is used.
+	RemoteFetchTimeout time.Duration
+	// RemoteFetchRetries is the number of times a failed remote evaluation request is retried,
+	// overriding RemoteConfig.RetryBackoff.FetchRetries. If zero, that (or the SDK default) is used;
+	// if negative, requests aren't retried.
+	RemoteFetchRetries int
+	// RemoteReadinessProbe makes Init check that Amplitude accepts the deployment key
+	// with a remote evaluation request; see [WithRemoteReadinessProbe].
+	RemoteReadinessProbe bool
+	// InitRetryAttempts is the number of times Init attempts to start the client before failing;
+	// see [WithInitRetry]. If zero or one, the client is started once.
+	InitRetryAttempts int
+	// InitRetryDelay is the delay before Init retries to start the client, doubling for each
+	// further retry.
+	InitRetryDelay time.Duration
+	// StartTimeout bounds the time Init spends starting the client, across the retries of
+	// [WithInitRetry]; see [WithStartTimeout]. If zero, only the context given to Init bounds it.
+	StartTimeout time.Duration
+	// CacheKeyAttributes are the user attributes from which remote evaluation cache keys are computed.
+	// If empty, cache keys are computed from the whole user.
+	CacheKeyAttributes []Key
 	// KeyMap is a map of string keys that might be in the evaluation context
 	// to the canonical key used by Amplitude.
 	// You can add keys to this map to automatically map the keys in the evaluation context
 	// to the canonical keys used by Amplitude.
 	// If multiple keys found in the evaluation context 
 	// map to the same canonical key, no error will be raised,
-	// one will simply override the other.
+	// one will simply override the other, except that the targeting key
+	// always takes precedence (and a conflicting value is logged as a warning).
 	// Any keys that are not mapped will be added to the User.UserProperties map.
 	// For more advanced normalization, use a hook to pre-process the evaluation context.
 	// If unset, [DefaultKeyMap] will be used.
 	KeyMap map[string]Key
 
+	// ContextEnricher is an optional function which augments the evaluation context
+	// before key mapping is applied.
+	ContextEnricher func(ctx context.Context, evalCtx of.FlattenedContext) (of.FlattenedContext, error)
+
+	// ContextValueExtractors pull attributes from the Go context during evaluation and tracking;
+	// see [WithContextValueExtractors].
+	ContextValueExtractors []ContextValueExtractor
+
 	// UserNormalizer is an optional function that normalizes the evaluation context into an Amplitude User.
 	// If set, it will be used to normalize the evaluation context into an Amplitude User,
 	// after key mapping has been applied. 
@@ -55,6 +112,167 @@ type Config struct {
 	// user or group properties.
 	EventNormalizer func(ctx context.Context, normContext EventNormalizationContext) error
 
+	// ExposureEventCustomizer is an optional function which modifies exposure events
+	// just before they are tracked; see [WithExposureEventCustomizer].
+	ExposureEventCustomizer func(event *analytics.Event)
+
+	// TargetingKeyField is the canonical key which the OpenFeature targeting key populates.
+	// It must be [KeyUserID], [KeyDeviceID], or [TargetingKeyIgnored].
+	// If unset, [KeyUserID] will be used.
+	TargetingKeyField Key
+
+	// CaseInsensitiveKeyMatching matches context keys which aren't in the key map
+	// regardless of case and separators; see [WithCaseInsensitiveKeyMatching].
+	CaseInsensitiveKeyMatching bool
+
+	// DeviceIDKey is an evaluation context key which populates [KeyDeviceID],
+	// in addition to any keys mapped to it by the key map.
+	DeviceIDKey string
+
+	// PayloadSource determines where the payload of a variant is read from; see [WithPayloadSource].
+	// If unset, the payload of the variant is used.
+	PayloadSource PayloadSource
+
+	// Base64JSONPayloads enables decoding string payloads containing base64-encoded JSON
+	// objects or arrays during object evaluation.
+	Base64JSONPayloads bool
+
+	// UnwrapStringifiedObjects enables decoding string payloads containing JSON
+	// objects or arrays (i.e. double-encoded payloads) during object evaluation.
+	UnwrapStringifiedObjects bool
+
+	// OffMeansFalse makes boolean evaluation return false, rather than the default value,
+	// when the variant is "off".
+	OffMeansFalse bool
+
+	// MissingBooleanFlagAsFalse makes boolean evaluation of a flag which doesn't exist
+	// return false, rather than a FLAG_NOT_FOUND error; see [WithMissingBooleanFlagAsFalse].
+	MissingBooleanFlagAsFalse bool
+
+	// OffVariantKeys are the variant keys which mean that the user is not in the rollout, so that
+	// the default value is used; see [WithOffVariantKeys]. If empty, only "off" does.
+	OffVariantKeys []string
+	// OffVariantKeysIgnoreCase makes OffVariantKeys match variant keys regardless of case.
+	OffVariantKeysIgnoreCase bool
+
+	// UseNumberDecoding makes JSON which the provider decodes itself
+	// represent numbers as [json.Number] rather than float64.
+	UseNumberDecoding bool
+
+	// ReasonMapper is an optional function which determines the reason
+	// reported for a successfully evaluated variant.
+	ReasonMapper func(flag string, variant *experiment.Variant) of.Reason
+
+	// FallbackProvider is an optional provider which evaluates flags that Amplitude doesn't have.
+	FallbackProvider of.FeatureProvider
+
+	// IdentityOnlyFlags are flags which are evaluated with a user containing only identity fields.
+	IdentityOnlyFlags []string
+
+	// ConfigValidation determines how [NewFromConfig] reacts to likely configuration mistakes,
+	// such as settings which have no effect (see [Config.Validate]).
+	// If unset, they are not reported, except that a remote evaluation cache
+	// with local evaluation is logged as a warning.
+	ConfigValidation ValidationMode
+
+	// LogRedaction makes the provider redact personal data from the messages logged through
+	// the LoggerProvider of LocalConfig or RemoteConfig; see [WithLogRedaction].
+	// Messages are always redacted if no LoggerProvider is configured.
+	LogRedaction bool
+
+	// PlatformValidation determines how the provider reacts when the platform of the
+	// Amplitude user is not one of the recognized platforms (see [PlatformIOS] etc.).
+	// If unset, platforms are not validated.
+	PlatformValidation ValidationMode
+
+	// FlagConfigChangeCallback is an optional function which is called
+	// with the keys of flags whose config changed (local evaluation only).
+	FlagConfigChangeCallback func(changed []string)
+
+	// EvaluationChangeCallback is an optional function which is called when a flag evaluates
+	// to a different variant for a user than it did before; see [WithEvaluationChangeCallback].
+	EvaluationChangeCallback func(flag, userID, oldVariant, newVariant string)
+	// EvaluationChangeTrackingSize is the number of user and flag pairs whose last variant is
+	// remembered for EvaluationChangeCallback. If zero, 10,000 pairs are remembered.
+	EvaluationChangeTrackingSize int
+
+	// FallbackCallback is an optional function which is called when an evaluation returns the default
+	// value because of an error; see [WithFallbackCallback].
+	FallbackCallback func(flag string, err error)
+
+	// MaxFlagsPerEvaluation is the maximum number of flags returned by [Provider.EvaluateAll].
+	// If zero or negative, there is no maximum.
+	MaxFlagsPerEvaluation int
+
+	// ErrorOnMaxFlagsExceeded makes [Provider.EvaluateAll] return [ErrMaxFlagsExceeded]
+	// instead of truncating the results when MaxFlagsPerEvaluation is exceeded.
+	ErrorOnMaxFlagsExceeded bool
+
+	// ExposureContextKeys are evaluation context keys which are copied into
+	// the properties of exposure events, in addition to [ContextKeySurface].
+	ExposureContextKeys []string
+
+	// ExposureEventPropertiesFunc computes additional properties of exposure events;
+	// see [WithExposureEventPropertiesFunc].
+	ExposureEventPropertiesFunc func(ctx context.Context, flag string, variant *experiment.Variant, user *experiment.User) map[string]any
+
+	// ExposureSink is an optional function which receives a record of each exposure,
+	// in addition to the exposure events tracked in Amplitude; see [WithExposureSink].
+	ExposureSink func(ctx context.Context, record ExposureRecord)
+	// ExposureSinkBufferSize is the number of exposure records buffered for ExposureSink.
+	// If zero, 1,024 records are buffered.
+	ExposureSinkBufferSize int
+
+	// ExposureDeduplicationTTL suppresses repeated exposures of a user to the same variant of a flag
+	// within the TTL, if positive; see [WithExposureDeduplication].
+	ExposureDeduplicationTTL time.Duration
+	// ExposureDeduplicationSize is the number of user and flag pairs whose last exposure is remembered
+	// for ExposureDeduplicationTTL. If zero, 10,000 pairs are remembered.
+	ExposureDeduplicationSize int
+
+	// BatchedExposures makes [Provider.EvaluateAll] and [Provider.EvaluateFlags] track
+	// a single exposure event covering all the evaluated flags; see [WithBatchedExposures].
+	BatchedExposures bool
+
+	// CombinedExposureAssignment tracks assignments with the exposure events rather than as
+	// separate assignment events; see [WithCombinedExposureAssignment].
+	CombinedExposureAssignment bool
+
+	// FlagAllowlist are the keys of the only flags which the provider evaluates, if not empty;
+	// see [WithFlagAllowlist].
+	FlagAllowlist []string
+
+	// NotReadyDefaults are the safe values which flags resolve to while the provider isn't ready;
+	// see [WithNotReadyDefaults].
+	NotReadyDefaults map[string]any
+
+	// ForcedDefaults are the keys of flags which always evaluate to the default value
+	// with [of.DisabledReason], without calling Amplitude or tracking exposures.
+	ForcedDefaults []string
+
+	// EmptyPayloadDefaults maps the kinds of flag values to the values which variants
+	// without a payload (other than "off") resolve to; see [WithEmptyPayloadDefaults].
+	EmptyPayloadDefaults map[reflect.Kind]any
+
+	// BatchConcurrency is the number of users [Provider.EvaluateForUsers] evaluates at once.
+	// If zero, 8 users are evaluated at once.
+	BatchConcurrency int
+
+	// ContextForcedVariants enables forcing flags to variants with the reserved
+	// evaluation context keys [ContextKeyForcedVariants] and [ContextKeyForcedVariantPrefix].
+	ContextForcedVariants bool
+
+	// AllowedStringValues maps flag keys to the only values [Provider.StringEvaluation]
+	// returns for them. Any other value results in the default value and an error.
+	// Flags which aren't in the map are not validated.
+	AllowedStringValues map[string][]string
+
+	// Metrics optionally receives measurements of evaluations and cache lookups.
+	Metrics Metrics
+
+	// DecisionAudit optionally configures a sink which records every flag decision.
+	DecisionAudit *AuditConfig
+
 	// AnalyticsConfig is an optional Amplitude analytics config.
 	// If set, it will be used to track events when the provider is used as a tracker.
 	// It will also automatically record exposure events for flags.
@@ -83,16 +301,156 @@ func WithRemoteConfig(remoteConfig remote.Config) Option {
 	}
 }
 
+// WithRemoteFlags evaluates the given flags remotely and all other flags locally,
+// so that the few flags which need remote evaluation (e.g. for ID resolution or user enrichment)
+// can have it, while the rest are evaluated quickly without a request to Amplitude.
+// [WithLocalConfig] and [WithRemoteConfig] configure the two kinds of evaluation,
+// and the remote evaluation cache (see [WithRemoteEvaluationCache]) applies to the remote flags.
+// Both clients are started by [Provider.Init]. Calling it again adds to the flags.
+func WithRemoteFlags(flags ...string) Option {
+	return func(c *Config) {
+		c.RemoteFlags = append(c.RemoteFlags, flags...)
+	}
+}
+
 // WithRemoteEvaluationCache sets the cache for remote evaluation.
 // This will be used to cache the variants available for a given context,
 // so subsequent evaluations for the same context don't need to 
 // re-fetch the variants from the server.
+// The cache is only used with remote evaluation (see [WithRemoteConfig]).
 func WithRemoteEvaluationCache(cache Cache) Option {
 	return func(c *Config) {
 		c.RemoteEvaluationCache = cache
 	}
 }
 
+// WithRemoteEvaluationCodec serializes the variants stored in the remote evaluation cache
+// (see [WithRemoteEvaluationCache]) with the given codec, so that the cache can be backed by an
+// out-of-process store which only holds bytes. The cache is given a []byte to store, and may
+// return a []byte or a string; values which fail to decode are logged and treated as misses.
+// [JSONCacheCodec] encodes the variants as JSON. By default, the variants are cached as they are.
+func WithRemoteEvaluationCodec(codec CacheCodec) Option {
+	return func(c *Config) {
+		c.RemoteEvaluationCodec = &codec
+	}
+}
+
+// WithCacheKeyAttributes computes remote evaluation cache keys (see [WithRemoteEvaluationCache])
+// from only the listed user attributes, rather than the whole user.
+// This greatly improves hit rates when evaluation contexts contain request-specific data
+// which doesn't affect targeting (e.g. timestamps or request IDs, which end up in [KeyUserProperties]).
+// Users which differ only in unlisted attributes share cached results, so you must list
+// every attribute your flags target on; by default the whole user is used, for correctness.
+func WithCacheKeyAttributes(keys ...Key) Option {
+	return func(c *Config) {
+		c.CacheKeyAttributes = keys
+	}
+}
+
+// WithStaleWhileRevalidate keeps remote evaluation latency low while staying reasonably fresh.
+// Cached results (see [WithRemoteEvaluationCache]) older than softTTL are served immediately
+// while a refresh is fetched in the background; results older than hardTTL are not served,
+// so evaluation waits for a fresh result.
+// The number of concurrent background refreshes is bounded, and only one refresh
+// per user is in flight at a time.
+// This requires a cache, and softTTL must be positive and no greater than hardTTL.
+func WithStaleWhileRevalidate(softTTL, hardTTL time.Duration) Option {
+	return func(c *Config) {
+		c.StaleWhileRevalidateSoftTTL = softTTL
+		c.StaleWhileRevalidateHardTTL = hardTTL
+	}
+}
+
+// WithRemoteReadinessProbe makes Init fetch the variants of a synthetic user with remote evaluation,
+// which otherwise needs no startup, so that a deployment key which Amplitude rejects is reported by Init
+// (and so by openfeature.SetProviderAndWait) rather than by the first evaluation.
+// If Amplitude rejects the key, the provider enters the error state and Init returns an error
+// wrapping [ErrDeploymentKeyRejected] and the error of the request. Other failures, such as
+// Amplitude being unreachable, are only logged as warnings, since evaluations may yet succeed.
+// The probe isn't cached and tracks no exposures. It has no effect with local evaluation.
+func WithRemoteReadinessProbe(enabled bool) Option {
+	return func(c *Config) {
+		c.RemoteReadinessProbe = enabled
+	}
+}
+
+// WithInitRetry makes Init retry starting the client (which downloads the flag configs for
+// local evaluation) if it fails, such as when Amplitude is briefly unreachable during a deploy.
+// The client is started up to maxAttempts times, waiting delay before the first retry and
+// doubling the delay (up to 30 seconds) for each further retry, so that Init fails after at most
+// delay * (2^(maxAttempts-1) - 1), plus the time taken by the attempts themselves.
+// Each attempt is bounded by the request timeout of the SDK (see [local.Config.FlagConfigPollerRequestTimeout]).
+// Retrying stops early when the context given to [Provider.InitWithContext] is done,
+// or when the budget set with [WithStartTimeout] runs out.
+// maxAttempts must not be negative, and delay must not be negative.
+func WithInitRetry(maxAttempts int, delay time.Duration) Option {
+	return func(c *Config) {
+		c.InitRetryAttempts = maxAttempts
+		c.InitRetryDelay = delay
+	}
+}
+
+// WithStartTimeout bounds the total time Init spends starting the client, including the waits
+// between the retries of [WithInitRetry]: once the budget runs out, Init stops retrying
+// and fails with an error wrapping [context.DeadlineExceeded] and the error of the last attempt.
+// An attempt in progress isn't interrupted, so Init may overrun the budget by up to one attempt.
+// The timeout must not be negative.
+func WithStartTimeout(timeout time.Duration) Option {
+	return func(c *Config) {
+		c.StartTimeout = timeout
+	}
+}
+
+// WithRequiredAttributes makes evaluating the flag fail if the user (after key mapping and the
+// user normalizer) is missing any of the given attributes, rather than silently evaluating
+// without them, so that integration bugs (such as forgetting to pass the country of a flag
+// which targets by country) surface as errors. The evaluation returns the default value
+// with a [of.InvalidContextCode] error naming the missing attributes.
+// Keys which aren't fields of the Amplitude user refer to user properties.
+// It applies to the typed evaluation methods and [Provider.EvaluateFull], not to [Provider.EvaluateAll].
+// It can be used multiple times, for different flags or to add attributes.
+func WithRequiredAttributes(flag string, keys ...Key) Option {
+	return func(c *Config) {
+		if c.RequiredAttributes == nil {
+			c.RequiredAttributes = make(map[string][]Key)
+		}
+		c.RequiredAttributes[flag] = append(c.RequiredAttributes[flag], keys...)
+	}
+}
+
+// WithStickyBucketing designates the user fields which identify users for sticky bucketing,
+// such as [KeyUserID] or [KeyDeviceID]. Sticky bucketing (a remote evaluation feature) keeps users
+// in the variant they were first assigned, but only if they are identified by the same fields
+// every time; an evaluation without them re-buckets the user.
+// With this option, evaluations whose user (after key mapping and the user normalizer) lacks any
+// of the fields fail with a [of.InvalidContextCode] error, and the default value is returned.
+// The fields must be string fields of the Amplitude user.
+func WithStickyBucketing(keys ...Key) Option {
+	return func(c *Config) {
+		c.StickyBucketingKeys = append(c.StickyBucketingKeys, keys...)
+	}
+}
+
+// WithRemoteFetchTimeout sets the timeout of the remote evaluation request for a user,
+// bounding the latency of an evaluation which isn't cached (before any retries; see [WithRemoteFetchRetries]).
+// The SDK's default is 500ms. The timeout must be positive.
+// The Amplitude SDK creates its own HTTP client, so connection settings can't be tuned.
+func WithRemoteFetchTimeout(timeout time.Duration) Option {
+	return func(c *Config) {
+		c.RemoteFetchTimeout = timeout
+	}
+}
+
+// WithRemoteFetchRetries sets the number of times a failed remote evaluation request is retried
+// (the SDK's default is once), each with the retry timeout of [remote.RetryBackoff].
+// Set it to a negative number to disable retries, so that the latency of a failed evaluation
+// is bounded by [WithRemoteFetchTimeout].
+func WithRemoteFetchRetries(retries int) Option {
+	return func(c *Config) {
+		c.RemoteFetchRetries = retries
+	}
+}
+
 // WithTrackingEnabled configures the Amplitude provider to track assignment and exposure events.
 // See documentation at https://amplitude.com/docs/feature-experiment/under-the-hood/event-tracking.
 // This option is automatically enabled if you're using local evaluation
@@ -104,6 +462,256 @@ func WithTrackingEnabled(config analytics.Config) Option {
 	}
 }
 
+// WithCombinedExposureAssignment tracks assignments with the exposure events, rather than
+// as separate "[Experiment] Assignment" events sent by the local evaluation SDK:
+// each "$exposure" event additionally gets the event and user properties of an assignment event
+// for the evaluated flag. This halves the number of events for analytics setups which prefer one event,
+// but assignments are only recorded when an exposure is tracked, so not for anonymous contexts
+// (see [ContextKeyAnonymous]), [Provider.EvaluateAll] without [WithBatchedExposures], or while tracking
+// is suspended (see [Provider.SetTrackingEnabled]). It requires tracking (see [WithTrackingEnabled])
+// and local evaluation, and can't be combined with [local.Config.AssignmentConfig].
+func WithCombinedExposureAssignment() Option {
+	return func(c *Config) {
+		c.CombinedExposureAssignment = true
+	}
+}
+
+// WithExposureContextKeys sets evaluation context keys which are copied into the properties
+// of exposure events when tracking is enabled (see [WithTrackingEnabled]),
+// tying exposures to where or how the flag was evaluated.
+// The reserved [ContextKeySurface] key is always copied; other context keys are
+// only copied if they are listed here, to avoid leaking context into events.
+func WithExposureContextKeys(keys ...string) Option {
+	return func(c *Config) {
+		c.ExposureContextKeys = keys
+	}
+}
+
+// WithExposureEventPropertiesFunc sets a function computing additional properties of the exposure event
+// of a flag when tracking is enabled (see [WithTrackingEnabled]), such as a session bucket derived from
+// the user, for properties which [WithExposureContextKeys] can't copy from the evaluation context as is.
+// The properties are added to the event, but don't replace its "flag_key", "variant" and "metadata"
+// properties. The function runs on the evaluation path of every flag evaluation tracking an exposure,
+// so it should be fast. It isn't called for the batched exposure events of [WithBatchedExposures].
+func WithExposureEventPropertiesFunc(fn func(ctx context.Context, flag string, variant *experiment.Variant, user *experiment.User) map[string]any) Option {
+	return func(c *Config) {
+		c.ExposureEventPropertiesFunc = fn
+	}
+}
+
+// WithExposureSink sets a function which receives an [ExposureRecord] for each exposure of a flag
+// evaluated by the typed evaluation methods (or [Provider.EvaluateAsOf]) to a variant other than "off",
+// in addition to the exposure event tracked in Amplitude, for example to mirror exposures to a data
+// warehouse. It is called even if tracking isn't configured (see [WithTrackingEnabled]), but not
+// for anonymous contexts, contexts without exposures (see [ContextWithoutExposures]), or while
+// tracking is suspended with [Provider.SetTrackingEnabled]. Exposures aren't deduplicated for it
+// (see [WithExposureDeduplication]), and the batched exposures of [WithBatchedExposures] aren't passed.
+//
+// The sink is called from a goroutine owned by the provider, one record at a time, so a slow sink
+// doesn't stall evaluations: records are buffered (see [WithExposureSinkBufferSize]) and dropped when
+// the buffer is full, as counted by [Provider.DroppedExposureRecords]. The context passed to the sink
+// has the values of the evaluation's context, but isn't canceled with it. [Provider.Close] waits for
+// the buffered records to be passed to the sink. A panic in the sink is recovered and logged.
+func WithExposureSink(sink func(ctx context.Context, record ExposureRecord)) Option {
+	return func(c *Config) {
+		c.ExposureSink = sink
+	}
+}
+
+// WithExposureSinkBufferSize sets the number of exposure records buffered for the sink set with
+// [WithExposureSink]; records exposed while the buffer is full are dropped.
+func WithExposureSinkBufferSize(size int) Option {
+	return func(c *Config) {
+		c.ExposureSinkBufferSize = size
+	}
+}
+
+// WithExposureDeduplication suppresses repeated exposure events of a user to the same variant of a flag
+// within ttl of the last one tracked, reducing the volume of exposure events while still tracking at least
+// one per user and flag. Amplitude deduplicates exposures too, so this only saves bandwidth and event volume.
+// A different variant for the same user and flag is tracked as a fresh exposure.
+// Users are identified by their user ID (or else their device ID); users without either are always tracked.
+// The backdated exposures of [Provider.EvaluateAsOf] and the batched exposures of [WithBatchedExposures]
+// are not deduplicated.
+//
+// Exposures are remembered in memory, per provider, bounded to the 10,000 most recently exposed
+// user and flag pairs (see [WithExposureDeduplicationSize]); a pair which has been forgotten is exposed again.
+func WithExposureDeduplication(ttl time.Duration) Option {
+	return func(c *Config) {
+		c.ExposureDeduplicationTTL = ttl
+	}
+}
+
+// WithExposureDeduplicationSize sets the number of user and flag pairs whose last exposure
+// is remembered for [WithExposureDeduplication].
+// Each pair uses on the order of 150 bytes plus the lengths of its user ID, flag key and variant key.
+func WithExposureDeduplicationSize(size int) Option {
+	return func(c *Config) {
+		c.ExposureDeduplicationSize = size
+	}
+}
+
+// WithBatchedExposures makes [Provider.EvaluateAll] and [Provider.EvaluateFlags] track
+// a single "$exposure" event when tracking is enabled (see [WithTrackingEnabled]),
+// listing the evaluated flag keys in its "flag_keys" property and their variant keys
+// in its "variants" property, keyed by flag key. Without it, they track no exposures.
+//
+// One event per evaluation keeps the event volume low when many flags are evaluated at once,
+// but Amplitude's experiment analysis attributes exposures using the "flag_key" property
+// of per-flag exposure events, so batched exposures are not counted as exposures to the
+// individual experiments, and they also report flags the user never actually saw.
+// Prefer the typed evaluation methods, which track one exposure per flag, for experiments
+// whose results you analyze in Amplitude.
+func WithBatchedExposures() Option {
+	return func(c *Config) {
+		c.BatchedExposures = true
+	}
+}
+
+// WithFlagAllowlist restricts the provider to evaluating the given flags, so that a service only
+// depends on the flags it is meant to consume. Evaluating any other flag fails with a
+// [of.FlagNotFoundCode] error without calling Amplitude (and so is delegated to the fallback provider,
+// if any; see [WithFallbackProvider]), and methods evaluating many flags, such as [Provider.EvaluateAll],
+// only return allowlisted flags. It can be given multiple times; the flags are added to the list.
+func WithFlagAllowlist(flags ...string) Option {
+	return func(c *Config) {
+		c.FlagAllowlist = append(c.FlagAllowlist, flags...)
+	}
+}
+
+// WithForcedDefaults makes the given flags always evaluate to the default value
+// with [of.DisabledReason], regardless of their config in Amplitude.
+// Amplitude is not called for them and no exposures are tracked,
+// so this acts as an immediate kill switch controlled by your own config.
+// It can be given multiple times; the flags are added to the list.
+func WithForcedDefaults(flags ...string) Option {
+	return func(c *Config) {
+		c.ForcedDefaults = append(c.ForcedDefaults, flags...)
+	}
+}
+
+// WithNotReadyDefaults sets safe values which the given flags resolve to while the provider isn't ready,
+// such as during startup before Init completes, after Shutdown, or after Init failed, instead of a
+// PROVIDER_NOT_READY (or GENERAL) error and the caller's default value. The values are reported with
+// [NotReadyDefaultReason]. Each value must suit the type the flag is evaluated as: a bool, string,
+// int or int64, float64, or any value for object evaluation. Flags which aren't listed still fail,
+// and once the provider is ready the values aren't used.
+// It can be given multiple times; the values are merged.
+func WithNotReadyDefaults(defaults map[string]any) Option {
+	return func(c *Config) {
+		if c.NotReadyDefaults == nil {
+			c.NotReadyDefaults = make(map[string]any, len(defaults))
+		}
+		maps.Copy(c.NotReadyDefaults, defaults)
+	}
+}
+
+// WithEmptyPayloadDefaults configures what variants without a payload (other than "off") resolve to,
+// by the kind of value requested, for teams which use on/off variants without payloads.
+// The supported kinds and the values they accept are:
+//   - [reflect.String]: a string, for StringEvaluation
+//   - [reflect.Int64]: an int64, for IntEvaluation
+//   - [reflect.Float64]: a float64, for FloatEvaluation
+//   - [reflect.Interface]: any value, for ObjectEvaluation
+//
+// [UseVariantValue] resolves to the variant's value instead (usually its key, e.g. "on"),
+// which IntEvaluation parses as an integer.
+// Kinds which aren't configured keep the default behavior of returning the default value,
+// and boolean evaluation always resolves such variants to true.
+func WithEmptyPayloadDefaults(defaults map[reflect.Kind]any) Option {
+	return func(c *Config) {
+		c.EmptyPayloadDefaults = defaults
+	}
+}
+
+// WithBatchConcurrency sets the number of workers [Provider.EvaluateForUsers] uses to evaluate users,
+// which bounds the number of concurrent requests to Amplitude with remote evaluation.
+// The default is 8.
+func WithBatchConcurrency(n int) Option {
+	return func(c *Config) {
+		c.BatchConcurrency = n
+	}
+}
+
+// WithContextForcedVariantsEnabled lets the evaluation context force flags to specific variant keys
+// for a single evaluation, with [ContextKeyForcedVariants] or [ContextKeyForcedVariantPrefix].
+// This lets internal tooling pin variants per request (e.g. for a gate computed by your own code)
+// without reconfiguring the provider. Forced variants are returned with [of.StaticReason],
+// without calling Amplitude or tracking exposures. Since the flag config isn't consulted, forced
+// variants have no payload: boolean evaluation returns true for any variant other than "off",
+// and other types return the default value.
+// It is disabled by default, since anyone who controls the context can then choose variants.
+func WithContextForcedVariantsEnabled() Option {
+	return func(c *Config) {
+		c.ContextForcedVariants = true
+	}
+}
+
+// WithAllowedStringValues restricts the values of a string flag to a fixed set,
+// such as the variants of an enum. If the flag's payload is any other value,
+// [Provider.StringEvaluation] returns the default value with a type mismatch error,
+// so a typo in the Amplitude console can't leak an invalid value into your code.
+// It can be given multiple times for the same flag; the values are added to the set.
+func WithAllowedStringValues(flag string, values ...string) Option {
+	return func(c *Config) {
+		if c.AllowedStringValues == nil {
+			c.AllowedStringValues = make(map[string][]string)
+		}
+		c.AllowedStringValues[flag] = append(c.AllowedStringValues[flag], values...)
+	}
+}
+
+// WithMetrics records measurements of evaluations and remote evaluation cache lookups
+// with the given [Metrics] implementation.
+// For OpenTelemetry, use the implementation in the otelmetrics module,
+// which keeps this package free of the OpenTelemetry dependency.
+func WithMetrics(metrics Metrics) Option {
+	return func(c *Config) {
+		c.Metrics = metrics
+	}
+}
+
+// WithDecisionAuditSink sets a function which receives a record of every successful flag decision
+// (who, which flag, which variant, why, when, and the flag config version), for keeping your own audit trail.
+// This is distinct from exposure tracking, which sends events to Amplitude.
+// By default the sink is called synchronously during evaluation; use [AuditAsync] to call it
+// from a goroutine with a bounded buffer instead, and [AuditErrors] to also record failed evaluations.
+func WithDecisionAuditSink(sink func(ctx context.Context, record AuditRecord), options ...AuditOption) Option {
+	return func(c *Config) {
+		audit := &AuditConfig{Sink: sink}
+		for _, option := range options {
+			option(audit)
+		}
+		c.DecisionAudit = audit
+	}
+}
+
+// DefaultTrackingConfig returns an [analytics.Config] with the settings we recommend
+// for server-side use, suitable for passing to [WithTrackingEnabled].
+// Events are flushed every few seconds or when the queue fills up,
+// and the number of events held in memory while Amplitude is unreachable is bounded.
+// You can further customize the returned config before using it.
+func DefaultTrackingConfig(apiKey string) analytics.Config {
+	return analytics.Config{
+		APIKey:             apiKey,
+		FlushInterval:      5 * time.Second,
+		FlushQueueSize:     200,
+		FlushMaxRetries:    5,
+		ServerZone:         analytics.ServerZoneUS,
+		ConnectionTimeout:  5 * time.Second,
+		MaxStorageCapacity: 10000,
+	}
+}
+
+// WithProviderName sets the name returned by [Provider.Metadata], instead of "Amplitude".
+// When several Amplitude providers are registered (e.g. one per tenant), distinct names
+// such as "Amplitude[tenant-a]" tell them apart in logs and the OpenFeature registry.
+func WithProviderName(name string) Option {
+	return func(c *Config) {
+		c.ProviderName = name
+	}
+}
+
 // WithKeyMap sets the key map for the Amplitude provider.
 // If unset, [DefaultKeyMap] will be used.
 func WithKeyMap(keyMap map[string]Key) Option {
@@ -112,6 +720,320 @@ func WithKeyMap(keyMap map[string]Key) Option {
 	}
 }
 
+// TargetingKeyIgnored can be given to [WithTargetingKeyAs] so that the OpenFeature targeting key
+// is ignored, for applications where it isn't an Amplitude identifier (e.g. it is a session token).
+const TargetingKeyIgnored Key = "-"
+
+// WithTargetingKeyAs sets the canonical key which the OpenFeature targeting key populates,
+// on both the [experiment.User] used for evaluation and the [analytics.Event] used for tracking.
+// Use [KeyDeviceID] if your application primarily identifies by device (e.g. before login).
+// Use [TargetingKeyIgnored] if the targeting key isn't an Amplitude identifier at all;
+// the user ID or device ID must then be given as context attributes (e.g. "userId").
+// The key must be [KeyUserID], [KeyDeviceID], or [TargetingKeyIgnored].
+// If unset, [KeyUserID] will be used.
+func WithTargetingKeyAs(key Key) Option {
+	return func(c *Config) {
+		c.TargetingKeyField = key
+	}
+}
+
+// WithCaseInsensitiveKeyMatching matches evaluation context keys which aren't in the key map
+// against it ignoring case and separators ("_", "-", "." and spaces), so that unusual spellings
+// such as "Device_Id" or "DEVICEiD" populate the device ID without enumerating them in the key map.
+// Keys in the key map still match exactly first, and take precedence over keys matched ignoring case
+// for the same field; among the latter, the first in sorted order wins, and the others are ignored
+// with a warning.
+func WithCaseInsensitiveKeyMatching() Option {
+	return func(c *Config) {
+		c.CaseInsensitiveKeyMatching = true
+	}
+}
+
+// WithDeviceIDKey routes the given evaluation context key to [KeyDeviceID],
+// e.g. if your application carries the device ID under "deviceIdentifier".
+// This is a focused alternative to providing a full key map with [WithKeyMap].
+func WithDeviceIDKey(contextKey string) Option {
+	return func(c *Config) {
+		c.DeviceIDKey = contextKey
+	}
+}
+
+// WithPayloadSource reads the payloads of variants from the given source, for flags whose
+// configuration is stored somewhere other than the variant payload:
+// [PayloadFromValue] decodes the value of the variant as JSON (a variant whose value isn't valid JSON
+// fails to evaluate with a [of.ParseErrorCode] error), and [PayloadFromMetadata] reads a field of the variant's metadata.
+// The default is [PayloadFromPayload].
+// The source applies to all flags, and to the typed evaluation methods and [Provider.EvaluateFull];
+// other methods, such as [Provider.EvaluateAll], return variants as evaluated by Amplitude.
+func WithPayloadSource(source PayloadSource) Option {
+	return func(c *Config) {
+		c.PayloadSource = source
+	}
+}
+
+// WithBase64JSONPayloads enables decoding of variant payloads which are strings
+// containing base64-encoded JSON objects or arrays.
+// When enabled, [Provider.ObjectEvaluation] returns the decoded structure instead of the string.
+// String payloads which are not valid base64, or which don't decode to a JSON object or array,
+// are returned unchanged.
+// This is opt-in to avoid misinterpreting legitimate string payloads.
+func WithBase64JSONPayloads() Option {
+	return func(c *Config) {
+		c.Base64JSONPayloads = true
+	}
+}
+
+// OffVariants configures the variant keys which mean "off"; see [WithOffVariantKeys].
+type OffVariants struct {
+	// Keys are the variant keys which mean that the user is not in the rollout.
+	Keys []string
+	// IgnoreCase matches the keys regardless of case, so that "Control" matches "control".
+	IgnoreCase bool
+}
+
+// WithOffVariantKeys sets the variant keys which mean that the user is not in the rollout,
+// for deployments whose designated no-rollout variant is, say, "control" or "disabled" rather
+// than Amplitude's "off". Such variants are treated like "off" everywhere: the default value is
+// returned (or false, with [WithOffMeansFalse]), and [Provider.ActiveExperiments] omits them.
+// The keys replace "off"; include it to keep treating "off" as off.
+//
+//	amplitude.WithOffVariantKeys(amplitude.OffVariants{Keys: []string{"off", "control"}, IgnoreCase: true})
+func WithOffVariantKeys(off OffVariants) Option {
+	return func(c *Config) {
+		c.OffVariantKeys = off.Keys
+		c.OffVariantKeysIgnoreCase = off.IgnoreCase
+	}
+}
+
+// WithOffMeansFalse makes [Provider.BooleanEvaluation] return false when the variant is "off",
+// regardless of the default value passed by the caller, with the [of.TargetingMatchReason] reason:
+// false is then the value Amplitude's targeting chose, rather than a fallback.
+// This matches kill-switch semantics, where "off" means the feature is disabled.
+// By default, the "off" variant returns the default value, with [of.DefaultReason]
+// (or [of.TargetingMatchReason] if a targeting rule assigned it).
+// Flags listed with [WithForcedDefaults] still return the default value, with [of.DisabledReason].
+func WithOffMeansFalse() Option {
+	return func(c *Config) {
+		c.OffMeansFalse = true
+	}
+}
+
+// WithMissingBooleanFlagAsFalse makes [Provider.BooleanEvaluation] return false, with [of.DefaultReason]
+// and no error, for a flag which doesn't exist (for example, because it hasn't been created in every
+// environment yet), so that a missing feature flag means "feature off".
+// Evaluation of other types still fails with FLAG_NOT_FOUND, and a fallback provider
+// (see [WithFallbackProvider]) still takes precedence. Metrics and [WithFallbackCallback]
+// still report the flag as not found, so that missing flags remain visible.
+func WithMissingBooleanFlagAsFalse() Option {
+	return func(c *Config) {
+		c.MissingBooleanFlagAsFalse = true
+	}
+}
+
+// WithUnwrapStringifiedObjects enables decoding of variant payloads which are JSON strings
+// containing a JSON object or array (i.e. double-encoded payloads, e.g. "{\"a\": 1}").
+// When enabled, [Provider.ObjectEvaluation] returns the decoded structure instead of the string.
+// Other string payloads are returned unchanged.
+// This is opt-in to avoid misinterpreting legitimate string payloads.
+func WithUnwrapStringifiedObjects() Option {
+	return func(c *Config) {
+		c.UnwrapStringifiedObjects = true
+	}
+}
+
+// WithUseNumberDecoding makes the provider decode numbers as [json.Number] rather than float64
+// wherever it decodes JSON itself (such as base64-encoded payloads, see [WithBase64JSONPayloads],
+// and stringified payloads, see [WithUnwrapStringifiedObjects]),
+// preserving the precision of large integers.
+// [Provider.IntEvaluation] and [Provider.FloatEvaluation] handle [json.Number] values.
+// Note that this can't apply to payloads decoded by the Amplitude SDK,
+// which always represents numbers as float64.
+func WithUseNumberDecoding() Option {
+	return func(c *Config) {
+		c.UseNumberDecoding = true
+	}
+}
+
+// WithReasonMapper sets a function which determines the reason reported
+// when a flag resolves to a variant, allowing you to classify results
+// with domain-meaningful reasons (e.g. experiment vs. kill-switch vs. config).
+// It is not invoked when the default value is returned because the variant is "off"
+// or because of an error.
+// If unset, the provider's default reasons are used.
+func WithReasonMapper(reasonMapper func(flag string, variant *experiment.Variant) of.Reason) Option {
+	return func(c *Config) {
+		c.ReasonMapper = reasonMapper
+	}
+}
+
+// WithFallbackProvider sets a provider which evaluates flags that Amplitude doesn't have,
+// enabling incremental migration of flags from another system without a separate multiplexing layer.
+// Only a flag-not-found result delegates to the fallback provider (with the same arguments);
+// other errors, such as invalid contexts or failures to reach Amplitude, are returned as usual.
+// The fallback provider's lifecycle (e.g. initialization) is not managed by this provider.
+func WithFallbackProvider(provider of.FeatureProvider) Option {
+	return func(c *Config) {
+		c.FallbackProvider = provider
+	}
+}
+
+// WithIdentityOnlyEvaluation designates flags which only target on identity (user ID or device ID).
+// These flags are evaluated with a minimal Amplitude user containing only the identity fields,
+// skipping the cost of mapping the rest of the evaluation context,
+// which is a worthwhile optimization for high-QPS flags with large contexts.
+// This bypasses property-based targeting (e.g. on country, platform or user properties) for these flags,
+// so only use it for flags whose targeting rules don't reference other properties.
+// The user normalizer (see [WithUserNormalizer]) still runs.
+func WithIdentityOnlyEvaluation(flags ...string) Option {
+	return func(c *Config) {
+		c.IdentityOnlyFlags = append(c.IdentityOnlyFlags, flags...)
+	}
+}
+
+// WithConfigValidation checks the configuration for likely mistakes when the provider is created,
+// such as tracking enabled without an API key, a remote evaluation cache with local evaluation,
+// or a key map which maps keys to something other than an Amplitude field.
+// With [ValidationWarn] the mistakes are logged, and with [ValidationError] creating the provider
+// fails with an error listing all of them.
+// Settings which make it impossible to create a provider are always errors.
+// Use [Config.Validate] to check a configuration without creating a provider.
+func WithConfigValidation(mode ValidationMode) Option {
+	return func(c *Config) {
+		c.ConfigValidation = mode
+	}
+}
+
+// WithLogRedaction redacts email addresses, IP address fields and device identifiers
+// (IDFA, IDFV, ADID and Android ID) from messages logged by the provider and the Amplitude SDK
+// through the LoggerProvider configured with [WithLocalConfig] or [WithRemoteConfig],
+// as well as the user encoded in the fetch requests logged by the SDK,
+// so that debug logging doesn't leak personal data into log aggregators.
+// It is on by default when no LoggerProvider is configured and the provider logs to the standard error;
+// a configured LoggerProvider is trusted with unredacted messages unless this option is set.
+// Logs of the analytics client (see [WithTrackingEnabled]) are not redacted.
+func WithLogRedaction() Option {
+	return func(c *Config) {
+		c.LogRedaction = true
+	}
+}
+
+// WithPlatformValidation validates that the platform of the Amplitude user
+// is one of the platforms recognized by Amplitude (see [PlatformIOS] etc.),
+// since free-form values silently fail targeting.
+// With [ValidationWarn] a warning is logged and evaluation continues;
+// with [ValidationError] evaluation fails with an invalid context error.
+// Validation happens after the user normalizer has run, so a normalizer can canonicalize the platform.
+func WithPlatformValidation(mode ValidationMode) Option {
+	return func(c *Config) {
+		c.PlatformValidation = mode
+	}
+}
+
+// WithFlagConfigChangeCallback sets a function which is called with the keys of flags
+// whose config changed, which is useful for precisely invalidating caches.
+// The provider periodically (at [local.Config.FlagConfigPollerInterval]) compares the config
+// of each flag it has evaluated against a snapshot. Flags which have never been evaluated
+// are not reported, and a flag which was deleted is reported as changed.
+// This only applies to local evaluation; the callback never fires for remote evaluation.
+func WithFlagConfigChangeCallback(callback func(changed []string)) Option {
+	return func(c *Config) {
+		c.FlagConfigChangeCallback = callback
+	}
+}
+
+// WithEvaluationChangeCallback sets a function which is called when a flag evaluates to a different
+// variant for a user than the last time it was evaluated for them, e.g. because they entered a cohort,
+// so you can react by invalidating a cache or refreshing a UI.
+// Changes are detected for the typed evaluation methods and [Provider.EvaluateAll] and [Provider.EvaluateFlags],
+// for users with a user ID (or else a device ID), which is passed as userID.
+// The first evaluation of a flag for a user is not a change.
+// The callback is called synchronously, after the evaluation but before it returns.
+//
+// The last variant of each user and flag pair is remembered in memory, bounded to the 10,000
+// most recently evaluated pairs (see [WithEvaluationChangeTrackingSize]); a change for a pair
+// which has been forgotten is not reported.
+func WithEvaluationChangeCallback(callback func(flag, userID, oldVariant, newVariant string)) Option {
+	return func(c *Config) {
+		c.EvaluationChangeCallback = callback
+	}
+}
+
+// WithFallbackCallback sets a function which is called when the evaluation of a flag fails,
+// so that the default value is returned, such as when Amplitude can't be reached during an outage.
+// Unlike logs or metrics, it is a precise signal to alert on: it isn't called when the default value
+// is returned because the variant is "off", or because the flag is forced with [WithForcedDefaults],
+// nor when the flag is delegated to the fallback provider (see [WithFallbackProvider]).
+// It is also called when the typed evaluation methods return the default value because the payload
+// doesn't match the requested type or can't be parsed as it.
+// err is an [of.ResolutionError], whose message starts with the error code (such as "GENERAL" or "TYPE_MISMATCH").
+// The callback is called synchronously, before the evaluation returns, so it should be fast.
+func WithFallbackCallback(callback func(flag string, err error)) Option {
+	return func(c *Config) {
+		c.FallbackCallback = callback
+	}
+}
+
+// WithEvaluationChangeTrackingSize sets the number of user and flag pairs whose last variant
+// is remembered to detect changes for [WithEvaluationChangeCallback].
+// Each pair uses on the order of 100 bytes plus the lengths of its user ID, flag key and variant key.
+func WithEvaluationChangeTrackingSize(size int) Option {
+	return func(c *Config) {
+		c.EvaluationChangeTrackingSize = size
+	}
+}
+
+// WithMaxFlagsPerEvaluation caps the number of flags returned by [Provider.EvaluateAll],
+// protecting callers from accidentally materializing an enormous result set
+// when a deployment has a very large number of flags.
+// By default, results exceeding the cap are truncated to the first n flags (sorted by key)
+// and a warning is logged; use [WithErrorOnMaxFlagsExceeded] to return an error instead.
+// The cap does not apply to [Provider.EvaluateFlags], where the caller chooses the flags explicitly.
+func WithMaxFlagsPerEvaluation(n int) Option {
+	return func(c *Config) {
+		c.MaxFlagsPerEvaluation = n
+	}
+}
+
+// WithErrorOnMaxFlagsExceeded makes [Provider.EvaluateAll] return [ErrMaxFlagsExceeded]
+// rather than truncating the results when the cap set by [WithMaxFlagsPerEvaluation] is exceeded.
+func WithErrorOnMaxFlagsExceeded() Option {
+	return func(c *Config) {
+		c.ErrorOnMaxFlagsExceeded = true
+	}
+}
+
+// WithContextEnricher sets a function which augments the raw evaluation context
+// before key mapping is applied, for both evaluation and tracking.
+// Attributes it adds go through the normal key map, so for example you can derive
+// a "country" attribute from an IP address and have it populate the Amplitude country field.
+// It should return a new context rather than modifying the one it is given.
+// In contrast, the user normalizer (see [WithUserNormalizer]) runs after key mapping.
+// Return an error to abort the evaluation or tracking.
+func WithContextEnricher(enricher func(ctx context.Context, evalCtx of.FlattenedContext) (of.FlattenedContext, error)) Option {
+	return func(c *Config) {
+		c.ContextEnricher = enricher
+	}
+}
+
+// ContextValueExtractor returns an attribute held by the Go context, such as a tenant or locale
+// set by request middleware, and whether the context has it. See [WithContextValueExtractors].
+type ContextValueExtractor func(ctx context.Context) (key Key, value any, ok bool)
+
+// WithContextValueExtractors adds functions which pull attributes from the Go context
+// (the ctx passed to the evaluation methods and Track), so that attributes already on the
+// request context needn't be copied into every evaluation context.
+// The attributes populate the given Amplitude fields after key mapping (a key which isn't an
+// Amplitude field, such as a custom "tenant", becomes a user property), for both evaluation and tracking.
+// The evaluation context takes precedence: an extracted attribute is only used if the field isn't
+// already set by the evaluation context (including through the context enricher, see [WithContextEnricher]).
+// Identity-only flags (see [WithIdentityOnlyEvaluation]) don't use extracted attributes.
+// When several extractors return the same key, the first one wins.
+func WithContextValueExtractors(extractors ...ContextValueExtractor) Option {
+	return func(c *Config) {
+		c.ContextValueExtractors = append(c.ContextValueExtractors, extractors...)
+	}
+}
+
 // WithUserNormalizer sets the user normalizer for the Amplitude provider.
 // If set, it will be used to normalize the evaluation context into an Amplitude User,
 // after key mapping has been applied. 
@@ -151,6 +1073,20 @@ func WithEventNormalizer(eventNormalizer func(ctx context.Context, normContext E
 	}
 }
 
+// WithExposureEventCustomizer sets a function which modifies the "$exposure" events tracked
+// by the provider just before they are tracked, for example to rename the "flag_key", "variant"
+// and "metadata" properties for existing dashboards, to namespace them, to add constant properties,
+// or to set [analytics.EventOptions]. It is the counterpart of [WithEventNormalizer] for the
+// exposure events the provider generates, rather than the events passed to [Provider.Track],
+// and it also applies to the batched exposure events of [WithBatchedExposures].
+// It runs on the evaluation path, so it should be fast; a panic in it is recovered and logged,
+// and the event is then not tracked.
+func WithExposureEventCustomizer(customizer func(event *analytics.Event)) Option {
+	return func(c *Config) {
+		c.ExposureEventCustomizer = customizer
+	}
+}
+
 // EventNormalizationContext is the context for the event normalizer.
 type EventNormalizationContext struct {
 	// EvaluationContext is the evaluation context for the event normalizer.
@@ -168,20 +1104,34 @@ type EventNormalizationContext struct {
 }
 
 // getKeyMap returns the key map for the Amplitude provider.
-// If unset, [DefaultKeyMap] will be used.
+// If unset, the shared default key map will be used; it must not be modified.
 func (c *Config) getKeyMap() map[string]Key {
 	if c.KeyMap == nil {
-		c.KeyMap = DefaultKeyMap()
+		return sharedDefaultKeyMap()
 	}
 	return c.KeyMap
 }
 
+// getTargetingKeyField returns the canonical key which the OpenFeature targeting key populates.
+// If unset, [KeyUserID] will be used.
+func (c *Config) getTargetingKeyField() Key {
+	if c.TargetingKeyField == "" {
+		return KeyUserID
+	}
+	return c.TargetingKeyField
+}
+
 // getLocalConfig returns the local configuration for the Amplitude provider.
 func (c *Config) getLocalConfig() localConfig {
 	if c.LocalConfig == nil {
 		c.LocalConfig = &local.Config{}
 	}
-	return localConfig{Config: *c.LocalConfig}
+	config := localConfig{
+		Config:                   *c.LocalConfig,
+		FlagConfigChangeCallback: c.FlagConfigChangeCallback,
+	}
+	config.LoggerProvider = c.getLoggerProvider(config.LoggerProvider)
+	return config
 }
 
 // getRemoteConfig returns the remote configuration for the Amplitude provider.
@@ -189,8 +1139,28 @@ func (c *Config) getRemoteConfig() remoteConfig {
 	if c.RemoteConfig == nil {
 		c.RemoteConfig = &remote.Config{}
 	}
-	return remoteConfig{
-		Config: *c.RemoteConfig,
-		Cache:  c.RemoteEvaluationCache,
+	config := remoteConfig{
+		Config:  *c.RemoteConfig,
+		Cache:   c.RemoteEvaluationCache,
+		SoftTTL: c.StaleWhileRevalidateSoftTTL,
+		HardTTL: c.StaleWhileRevalidateHardTTL,
+
+		CacheKeyAttributes: c.CacheKeyAttributes,
+		Metrics:            c.Metrics,
+		Codec:              c.RemoteEvaluationCodec,
+	}
+	if c.RemoteFetchTimeout > 0 {
+		config.FetchTimeout = c.RemoteFetchTimeout
+	}
+	if c.RemoteFetchRetries != 0 {
+		// Copy the retry backoff, which may be shared with the SDK's defaults or another config.
+		retryBackoff := *remote.DefaultRetryBackoff
+		if config.RetryBackoff != nil {
+			retryBackoff = *config.RetryBackoff
+		}
+		retryBackoff.FetchRetries = max(c.RemoteFetchRetries, 0)
+		config.RetryBackoff = &retryBackoff
 	}
+	config.LoggerProvider = c.getLoggerProvider(config.LoggerProvider)
+	return config
 }
\ No newline at end of file
@@ -0,0 +1,143 @@
+package amplitude
+
+import (
+	"slices"
+	"time"
+)
+
+// Evaluation modes reported by [ConfigSummary].
+const (
+	EvaluationModeLocal  = "local"
+	EvaluationModeRemote = "remote"
+	// EvaluationModeHybrid evaluates some flags remotely and the rest locally; see [WithRemoteFlags].
+	EvaluationModeHybrid = "hybrid"
+)
+
+// ConfigSummary describes the effective configuration of a provider, for debugging and
+// auditing that the options given actually took effect; see [Provider.EffectiveConfig].
+// It contains no secrets (such as the deployment key or the analytics API key)
+// and no functions, so it can be logged or serialized, e.g. as JSON.
+type ConfigSummary struct {
+	// ProviderName is the name reported in the provider metadata.
+	ProviderName string
+	// EvaluationMode is [EvaluationModeLocal], [EvaluationModeRemote] or [EvaluationModeHybrid].
+	EvaluationMode string
+	// RemoteFlags are the flags evaluated remotely in hybrid mode.
+	RemoteFlags []string
+	// Tracking is true if events are tracked: an analytics client is configured,
+	// and tracking isn't suspended (see [Provider.SetTrackingEnabled]).
+	Tracking bool
+	// TrackingSuspended is true if tracking is suspended with [Provider.SetTrackingEnabled].
+	TrackingSuspended bool
+	// RemoteEvaluationCache is true if remote evaluation results are cached.
+	RemoteEvaluationCache bool
+	// StaleWhileRevalidateSoftTTL and StaleWhileRevalidateHardTTL are the TTLs of cached
+	// remote evaluation results, if set with [WithStaleWhileRevalidate].
+	StaleWhileRevalidateSoftTTL time.Duration
+	StaleWhileRevalidateHardTTL time.Duration
+	// KeyMapSize is the number of entries of the key map in use.
+	KeyMapSize int
+	// CustomKeyMap is true if a key map was configured, rather than [DefaultKeyMap].
+	CustomKeyMap bool
+	// TargetingKeyField is the Amplitude field which the targeting key populates,
+	// or [TargetingKeyIgnored].
+	TargetingKeyField Key
+	// Options are the names of the options which are in effect, such as "WithOffMeansFalse", sorted.
+	// Options which only pass SDK configs (such as [WithLocalConfig]) are not listed.
+	Options []string
+}
+
+// EffectiveConfig returns a summary of the provider's effective configuration,
+// without secrets, so that it can be logged or compared to the expected configuration.
+func (p *Provider) EffectiveConfig() ConfigSummary {
+	c := &p.config
+	summary := ConfigSummary{
+		ProviderName:                p.Metadata().Name,
+		EvaluationMode:              EvaluationModeLocal,
+		RemoteFlags:                 slices.Clone(c.RemoteFlags),
+		Tracking:                    p.trackingEnabled(),
+		TrackingSuspended:           p.trackingDisabled.Load(),
+		RemoteEvaluationCache:       c.RemoteEvaluationCache != nil && c.usesRemoteEvaluation(),
+		StaleWhileRevalidateSoftTTL: c.StaleWhileRevalidateSoftTTL,
+		StaleWhileRevalidateHardTTL: c.StaleWhileRevalidateHardTTL,
+		KeyMapSize:                  len(c.getKeyMap()),
+		CustomKeyMap:                c.KeyMap != nil,
+		TargetingKeyField:           c.getTargetingKeyField(),
+	}
+	switch {
+	case len(c.RemoteFlags) > 0:
+		summary.EvaluationMode = EvaluationModeHybrid
+	case c.RemoteConfig != nil:
+		summary.EvaluationMode = EvaluationModeRemote
+	}
+
+	options := []struct {
+		name   string
+		active bool
+	}{
+		{"WithRemoteEvaluationCache", c.RemoteEvaluationCache != nil},
+		{"WithRemoteEvaluationCodec", c.RemoteEvaluationCodec != nil},
+		{"WithCacheKeyAttributes", len(c.CacheKeyAttributes) > 0},
+		{"WithStaleWhileRevalidate", c.StaleWhileRevalidateSoftTTL != 0 || c.StaleWhileRevalidateHardTTL != 0},
+		{"WithInitRetry", c.InitRetryAttempts > 1},
+		{"WithStartTimeout", c.StartTimeout > 0},
+		{"WithRemoteReadinessProbe", c.RemoteReadinessProbe},
+		{"WithRequiredAttributes", len(c.RequiredAttributes) > 0},
+		{"WithStickyBucketing", len(c.StickyBucketingKeys) > 0},
+		{"WithRemoteFetchTimeout", c.RemoteFetchTimeout != 0},
+		{"WithRemoteFetchRetries", c.RemoteFetchRetries != 0},
+		{"WithTrackingEnabled", c.AnalyticsConfig != nil},
+		{"WithCombinedExposureAssignment", c.CombinedExposureAssignment},
+		{"WithExposureContextKeys", len(c.ExposureContextKeys) > 0},
+		{"WithExposureEventPropertiesFunc", c.ExposureEventPropertiesFunc != nil},
+		{"WithBatchedExposures", c.BatchedExposures},
+		{"WithExposureSink", c.ExposureSink != nil},
+		{"WithExposureSinkBufferSize", c.ExposureSinkBufferSize != 0},
+		{"WithExposureDeduplication", c.ExposureDeduplicationTTL > 0},
+		{"WithExposureDeduplicationSize", c.ExposureDeduplicationSize != 0},
+		{"WithFlagAllowlist", len(c.FlagAllowlist) > 0},
+		{"WithForcedDefaults", len(c.ForcedDefaults) > 0},
+		{"WithNotReadyDefaults", len(c.NotReadyDefaults) > 0},
+		{"WithEmptyPayloadDefaults", len(c.EmptyPayloadDefaults) > 0},
+		{"WithBatchConcurrency", c.BatchConcurrency != 0},
+		{"WithContextForcedVariantsEnabled", c.ContextForcedVariants},
+		{"WithAllowedStringValues", len(c.AllowedStringValues) > 0},
+		{"WithMetrics", c.Metrics != nil},
+		{"WithDecisionAuditSink", c.DecisionAudit != nil},
+		{"WithKeyMap", c.KeyMap != nil},
+		{"WithTargetingKeyAs", c.TargetingKeyField != ""},
+		{"WithDeviceIDKey", c.DeviceIDKey != ""},
+		{"WithCaseInsensitiveKeyMatching", c.CaseInsensitiveKeyMatching},
+		{"WithPayloadSource", c.PayloadSource != PayloadFromPayload},
+		{"WithBase64JSONPayloads", c.Base64JSONPayloads},
+		{"WithOffMeansFalse", c.OffMeansFalse},
+		{"WithOffVariantKeys", len(c.OffVariantKeys) > 0},
+		{"WithMissingBooleanFlagAsFalse", c.MissingBooleanFlagAsFalse},
+		{"WithUnwrapStringifiedObjects", c.UnwrapStringifiedObjects},
+		{"WithUseNumberDecoding", c.UseNumberDecoding},
+		{"WithReasonMapper", c.ReasonMapper != nil},
+		{"WithFallbackProvider", c.FallbackProvider != nil},
+		{"WithIdentityOnlyEvaluation", len(c.IdentityOnlyFlags) > 0},
+		{"WithConfigValidation", c.ConfigValidation != ValidationDisabled},
+		{"WithLogRedaction", c.LogRedaction},
+		{"WithPlatformValidation", c.PlatformValidation != ValidationDisabled},
+		{"WithFlagConfigChangeCallback", c.FlagConfigChangeCallback != nil},
+		{"WithEvaluationChangeCallback", c.EvaluationChangeCallback != nil},
+		{"WithEvaluationChangeTrackingSize", c.EvaluationChangeTrackingSize != 0},
+		{"WithFallbackCallback", c.FallbackCallback != nil},
+		{"WithMaxFlagsPerEvaluation", c.MaxFlagsPerEvaluation > 0},
+		{"WithErrorOnMaxFlagsExceeded", c.ErrorOnMaxFlagsExceeded},
+		{"WithContextEnricher", c.ContextEnricher != nil},
+		{"WithContextValueExtractors", len(c.ContextValueExtractors) > 0},
+		{"WithUserNormalizer", c.UserNormalizer != nil},
+		{"WithEventNormalizer", c.EventNormalizer != nil},
+		{"WithExposureEventCustomizer", c.ExposureEventCustomizer != nil},
+	}
+	for _, option := range options {
+		if option.active {
+			summary.Options = append(summary.Options, option.name)
+		}
+	}
+	slices.Sort(summary.Options)
+	return summary
+}
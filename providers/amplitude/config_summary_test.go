@@ -0,0 +1,102 @@
+package amplitude
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	analytics "github.com/amplitude/analytics-go/amplitude"
+	"github.com/amplitude/experiment-go-server/pkg/experiment/remote"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProvider_EffectiveConfig(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		provider := newTestProvider(t, &mockClientAdapter{})
+
+		summary := provider.EffectiveConfig()
+
+		assert.Equal(t, ConfigSummary{
+			ProviderName:      defaultProviderName,
+			EvaluationMode:    EvaluationModeLocal,
+			KeyMapSize:        len(DefaultKeyMap()),
+			TargetingKeyField: KeyUserID,
+		}, summary)
+	})
+
+	t.Run("reports the effective settings", func(t *testing.T) {
+		provider, err := New(context.Background(), "secret-deployment-key",
+			withMockClient(&mockClientAdapter{}),
+			WithRemoteConfig(remote.Config{}),
+			WithRemoteEvaluationCache(&syncCache{data: map[string]any{}}),
+			WithStaleWhileRevalidate(time.Minute, time.Hour),
+			WithKeyMap(map[string]Key{"uid": KeyUserID}),
+			WithTargetingKeyAs(KeyDeviceID),
+			WithOffMeansFalse(),
+			WithProviderName("checkout"),
+		)
+		require.NoError(t, err)
+
+		summary := provider.EffectiveConfig()
+
+		assert.Equal(t, "checkout", summary.ProviderName)
+		assert.Equal(t, EvaluationModeRemote, summary.EvaluationMode)
+		assert.True(t, summary.RemoteEvaluationCache)
+		assert.Equal(t, time.Minute, summary.StaleWhileRevalidateSoftTTL)
+		assert.Equal(t, time.Hour, summary.StaleWhileRevalidateHardTTL)
+		assert.Equal(t, 1, summary.KeyMapSize)
+		assert.True(t, summary.CustomKeyMap)
+		assert.Equal(t, KeyDeviceID, summary.TargetingKeyField)
+		assert.Equal(t, []string{
+			"WithKeyMap",
+			"WithOffMeansFalse",
+			"WithRemoteEvaluationCache",
+			"WithStaleWhileRevalidate",
+			"WithTargetingKeyAs",
+		}, summary.Options)
+	})
+
+	t.Run("hybrid evaluation", func(t *testing.T) {
+		provider, err := New(context.Background(), "test-key",
+			withMockClient(&mockClientAdapter{}),
+			WithRemoteFlags("remote-flag"),
+		)
+		require.NoError(t, err)
+
+		summary := provider.EffectiveConfig()
+
+		assert.Equal(t, EvaluationModeHybrid, summary.EvaluationMode)
+		assert.Equal(t, []string{"remote-flag"}, summary.RemoteFlags)
+	})
+
+	t.Run("tracking", func(t *testing.T) {
+		provider := newTestProvider(t, &mockClientAdapter{})
+		provider.config.AnalyticsConfig = &analytics.Config{APIKey: "secret-api-key"}
+		provider.analyticsClient = &mockAnalyticsClient{}
+
+		summary := provider.EffectiveConfig()
+		assert.True(t, summary.Tracking)
+		assert.False(t, summary.TrackingSuspended)
+		assert.Contains(t, summary.Options, "WithTrackingEnabled")
+
+		provider.SetTrackingEnabled(false)
+		summary = provider.EffectiveConfig()
+		assert.False(t, summary.Tracking)
+		assert.True(t, summary.TrackingSuspended)
+	})
+
+	t.Run("doesn't include secrets", func(t *testing.T) {
+		provider, err := New(context.Background(), "secret-deployment-key",
+			withMockClient(&mockClientAdapter{}),
+			WithTrackingEnabled(analytics.Config{APIKey: "secret-api-key"}),
+		)
+		require.NoError(t, err)
+
+		data, err := json.Marshal(provider.EffectiveConfig())
+
+		require.NoError(t, err)
+		assert.NotContains(t, string(data), "secret")
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"testing"
 
+	analytics "github.com/amplitude/analytics-go/amplitude"
 	"github.com/amplitude/experiment-go-server/pkg/experiment/local"
 	"github.com/amplitude/experiment-go-server/pkg/experiment/remote"
 	"github.com/stretchr/testify/assert"
@@ -205,3 +206,19 @@ func TestNewFromConfig_UsesLocalByDefault(t *testing.T) {
 }
 
 
+
+func TestDefaultTrackingConfig(t *testing.T) {
+	cfg := DefaultTrackingConfig("api-key")
+
+	assert.Equal(t, "api-key", cfg.APIKey)
+	assert.Positive(t, cfg.FlushInterval)
+	assert.Positive(t, cfg.FlushQueueSize)
+	assert.Positive(t, cfg.MaxStorageCapacity, "the event queue should be bounded")
+	assert.Equal(t, analytics.ServerZoneUS, cfg.ServerZone)
+
+	option := WithTrackingEnabled(cfg)
+	config := &Config{}
+	option(config)
+	require.NotNil(t, config.AnalyticsConfig)
+	assert.Equal(t, cfg.FlushInterval, config.AnalyticsConfig.FlushInterval)
+}
@@ -53,6 +53,9 @@
 //	    }
 //	}
 //
+// When the provider is used without the OpenFeature API, or by cleanup code which expects an
+// [io.Closer], call [Provider.Close] to stop the client and flush tracked events.
+//
 // # Provider Configuration
 //
 // The provider is created using [New] or [NewFromConfig]. The [New] function accepts
@@ -60,11 +63,61 @@
 //
 //   - [WithLocalConfig]: Configure local evaluation settings
 //   - [WithRemoteConfig]: Configure remote evaluation settings
+//   - [WithRemoteFlags]: Evaluate only the given flags remotely, and the rest locally
 //   - [WithRemoteEvaluationCache]: Provide a cache for remote evaluation results
+//   - [WithRemoteEvaluationCodec]: Serialize cached remote evaluation results for out-of-process caches
+//   - [WithStaleWhileRevalidate]: Serve stale cached remote results while refreshing them in the background
+//   - [WithStickyBucketing]: Require the identity fields sticky bucketing depends on
+//   - [WithRequiredAttributes]: Fail the evaluation of a flag if the context lacks attributes it targets on
+//   - [WithRemoteFetchTimeout] and [WithRemoteFetchRetries]: Bound the latency of remote evaluation requests
+//   - [WithInitRetry]: Retry starting the client in Init when Amplitude is briefly unreachable
+//   - [WithStartTimeout]: Bound the total time Init spends starting the client, across retries
+//   - [WithRemoteReadinessProbe]: Fail Init with remote evaluation if Amplitude rejects the deployment key
+//   - [WithProviderName]: Distinguish multiple Amplitude providers in the OpenFeature registry
 //   - [WithKeyMap]: Customize the mapping of evaluation context keys to Amplitude user fields
+//   - [WithTargetingKeyAs]: Choose whether the targeting key populates user_id or device_id
+//   - [WithDeviceIDKey]: Route a custom evaluation context key to device_id
+//   - [WithCaseInsensitiveKeyMatching]: Match context keys such as "Device_Id" regardless of case and separators
 //   - [WithTrackingEnabled]: Enable event and exposure tracking via Amplitude Analytics
 //   - [WithUserNormalizer]: Apply custom transformations to user context before evaluation
 //   - [WithEventNormalizer]: Apply custom transformations to events before tracking
+//   - [WithExposureEventCustomizer]: Modify the generated exposure events before tracking, e.g. to rename properties
+//   - [WithFlagConfigChangeCallback]: Get notified when the config of an evaluated flag changes
+//   - [WithEvaluationChangeCallback]: Get notified when a flag evaluates to a different variant for a user
+//   - [WithFallbackProvider]: Delegate flags which Amplitude doesn't have to another provider
+//   - [WithFallbackCallback]: Get notified when an evaluation error forces the default value
+//   - [WithPlatformValidation]: Warn or fail when the platform isn't one Amplitude recognizes
+//   - [WithBatchedExposures]: Track one exposure event per multi-flag evaluation
+//   - [WithMaxFlagsPerEvaluation]: Cap the number of flags returned by [Provider.EvaluateAll]
+//   - [WithBatchConcurrency]: Bound the number of users evaluated at once by [Provider.EvaluateForUsers]
+//   - [WithContextForcedVariantsEnabled]: Let the evaluation context pin flags to variants per request
+//   - [WithEmptyPayloadDefaults]: Choose what variants without a payload resolve to, by type
+//   - [WithAllowedStringValues]: Restrict a string flag to a fixed set of values
+//   - [WithForcedDefaults]: Always return the default value for the given flags, as a local kill switch
+//   - [WithFlagAllowlist]: Only evaluate the given flags, rejecting any other as not found
+//   - [WithDecisionAuditSink]: Record every flag decision in your own audit log
+//   - [WithMetrics]: Record evaluation and cache metrics, e.g. with OpenTelemetry
+//   - [WithConfigValidation]: Warn or fail at startup on likely configuration mistakes
+//   - [WithLogRedaction]: Redact personal data from messages sent to your own logger provider
+//
+// For complex configurations, [NewBuilder] provides a fluent alternative which validates
+// the combination of settings before creating the provider:
+//
+//	provider, err := amplitude.NewBuilder("deployment-key").
+//	    WithRemoteConfig(remote.Config{}).
+//	    WithRemoteEvaluationCache(myCache).
+//	    WithTrackingEnabled(amplitude.DefaultTrackingConfig("your-amplitude-api-key")).
+//	    Build(ctx)
+//
+// Settings which can't work together, such as both local and remote evaluation, always
+// prevent creating a provider. Likely mistakes, such as a remote evaluation cache with local
+// evaluation (where it has no effect), are reported with [WithConfigValidation], or by
+// [Config.Validate] and the builder, which list every problem at once.
+// A remote evaluation cache with local evaluation is logged as a warning even without
+// [WithConfigValidation]; it is visible when the SDK log level is Warn or lower.
+//
+// To check which settings took effect, [Provider.EffectiveConfig] returns a [ConfigSummary]
+// of the provider's configuration, without secrets, which can be logged or serialized.
 //
 // # Local vs Remote Evaluation
 //
@@ -82,6 +135,27 @@
 //	    }),
 //	)
 //
+// With local evaluation, [WithFlagConfigChangeCallback] reports the keys of flags
+// whose config changed since they were evaluated, so you can invalidate caches precisely:
+//
+//	provider, err := amplitude.New(ctx, "deployment-key",
+//	    amplitude.WithFlagConfigChangeCallback(func(changed []string) {
+//	        myCache.Invalidate(changed...)
+//	    }),
+//	)
+//
+// To help decide when a deployment should switch to remote evaluation, [Provider.FlagConfigStats]
+// reports the number of flags and targeted cohorts, and the approximate size of the flag configs.
+// The figures are approximate, and zero for remote evaluation.
+//
+// When a flag isn't evaluating as expected, [Provider.FlagStatus] reports whether its config
+// reached this deployment and whether it is deployed, from the resident flag configs,
+// and [Provider.LastError] returns the most recent error evaluating it, such as a type mismatch.
+//
+// After editing flags, [Provider.ForceSync] fetches the flag configs immediately rather than
+// waiting for the next poll. It relies on the local SDK fetching flag configs whenever its client
+// is started, so it isn't supported together with cohort sync, and does nothing for remote evaluation.
+//
 // Remote Evaluation: The provider makes a round-trip to Amplitude servers for each
 // evaluation. This is needed for ID resolution, user enrichment, or sticky bucketing
 // (as distinct from consistent bucketing, which works with both modes).
@@ -92,6 +166,15 @@
 //	    amplitude.WithRemoteConfig(remote.Config{}),
 //	)
 //
+// Hybrid Evaluation: [WithRemoteFlags] evaluates only the listed flags remotely, and all other
+// flags locally, so that remote round-trips are reserved for the flags which need them.
+// [WithLocalConfig] and [WithRemoteConfig] may both be used to configure the two modes:
+//
+//	provider, err := amplitude.New(ctx, "deployment-key",
+//	    amplitude.WithRemoteFlags("resolved-identity-flag"),
+//	    amplitude.WithRemoteEvaluationCache(myCache),
+//	)
+//
 // # Caching for Remote Evaluation
 //
 // When using remote evaluation, Amplitude returns all flag results for a user in a
@@ -103,11 +186,59 @@
 //	)
 //
 // The cache must implement the [Cache] interface.
+// [NewRequestCache] returns a cache which caches the variants for the duration of a request,
+// with a function seeding the request's context, e.g. in middleware; contexts which weren't seeded
+// aren't cached. [NewMemoryCache] returns a simple unbounded cache which counts hits and misses;
+// since it never evicts entries, use it only for the duration of a request.
+// For a long-lived process-wide cache, [NewTTLCache] returns a cache bounded to a number of
+// entries (evicting the least recently used), whose entries expire after a TTL.
+//
+// The cache is given the variants as they are, so it must return them as they were set.
+// To back the cache with an out-of-process store such as Redis, use [WithRemoteEvaluationCodec]
+// to serialize them; [JSONCacheCodec] encodes them as JSON:
+//
+//	amplitude.WithRemoteEvaluationCodec(amplitude.JSONCacheCodec)
+//
+// Cache keys are computed from the whole Amplitude user by default. If your evaluation contexts
+// contain request-specific data which doesn't affect targeting, use [WithCacheKeyAttributes]
+// to compute cache keys from only the attributes your flags target on:
+//
+//	amplitude.WithCacheKeyAttributes(amplitude.KeyUserID, amplitude.KeyDeviceID, amplitude.KeyPlatform)
+//
+// [Provider.CacheKeyForContext] returns the cache key for a context, e.g. to debug cache misses
+// or to pre-seed a distributed cache.
+//
+// To keep latency low while staying reasonably fresh, use [WithStaleWhileRevalidate].
+// Cached results older than the soft TTL are served immediately while a refresh
+// is fetched in the background, and results older than the hard TTL are not served:
+//
+//	provider, err := amplitude.New(ctx, "deployment-key",
+//	    amplitude.WithRemoteConfig(remote.Config{}),
+//	    amplitude.WithRemoteEvaluationCache(myCache),
+//	    amplitude.WithStaleWhileRevalidate(30*time.Second, 5*time.Minute),
+//	)
+//
+// # Evaluation Memo
+//
+// Flags are evaluated one at a time by default. When checking many flags for the same user,
+// for example while handling a request, wrap the context with [ContextWithEvaluationMemo]:
+// the first evaluation evaluates all flags for the user, and later evaluations with the same
+// context and user are served from the memo. This works for local and remote evaluation.
+//
+//	ctx = amplitude.ContextWithEvaluationMemo(ctx)
+//	showBanner, _ := client.BooleanValue(ctx, "show-banner", false, evalCtx)
+//	theme, _ := client.StringValue(ctx, "theme", "light", evalCtx) // no further evaluation
 //
 // # Evaluation Context Mapping
 //
 // The provider maps OpenFeature evaluation context keys to Amplitude user fields.
 // The [openfeature.TargetingKey] is automatically mapped to the Amplitude user_id.
+// Use [WithTargetingKeyAs] with [KeyDeviceID] to map it to the device_id instead,
+// for applications which primarily identify by device, or with [TargetingKeyIgnored] if the
+// targeting key isn't an Amplitude identifier (e.g. a session token); the user ID or device ID
+// must then be given as context attributes. If an attribute for the same field has a
+// different value than the targeting key, the targeting key takes precedence, and the conflict
+// is logged as a warning. This includes a user_id in the details of tracking events.
 //
 // Standard Amplitude user fields are recognized with various naming conventions.
 // For example, "device_id", "deviceId", "device-id", and "DeviceID" all map to
@@ -124,6 +255,18 @@
 //	    amplitude.WithKeyMap(customKeyMap),
 //	)
 //
+// [ValidateKeyMap] checks that every entry maps to an Amplitude field, catching typos which
+// would otherwise turn the attribute into a user property.
+//
+// If only the device ID is under a non-standard key, [WithDeviceIDKey] routes that one
+// key to the device_id field without building a whole key map:
+//
+//	amplitude.WithDeviceIDKey("deviceIdentifier")
+//
+// If callers spell the standard keys inconsistently (such as "Device_Id" or "COUNTRY"),
+// [WithCaseInsensitiveKeyMatching] matches keys which aren't in the key map regardless of
+// case and separators. Exact matches take precedence, and unmatched keys remain user properties.
+//
 // # Payload Typing
 //
 // In Amplitude, each variant can have a JSON payload. This provider interprets
@@ -132,22 +275,122 @@
 //   - [Provider.BooleanEvaluation]: Expects a JSON boolean (true/false)
 //   - [Provider.StringEvaluation]: Expects a JSON string ("foo")
 //   - [Provider.IntEvaluation]: Expects a JSON number (42) or string ("42")
-//   - [Provider.FloatEvaluation]: Expects a JSON number (3.14)
+//   - [Provider.FloatEvaluation]: Expects a JSON number (3.14) or string ("3.14")
 //   - [Provider.ObjectEvaluation]: Expects a JSON object ({"key": "value"})
 //
 // If the payload cannot be unmarshalled to the requested type, the provider
 // returns an error and the default value.
 //
+// If your flags keep their configuration in the variant value (as JSON) or in a field of the
+// variant metadata rather than in the payload, use [WithPayloadSource] to read it from there.
+//
+// If your payloads store structured data as base64-encoded JSON strings,
+// use [WithBase64JSONPayloads] to have [Provider.ObjectEvaluation] decode them.
+// Similarly, if your payloads are JSON strings which themselves contain a JSON object or array
+// (double-encoded), use [WithUnwrapStringifiedObjects].
+//
+// The Amplitude SDK decodes payloads with numbers as float64, so integers beyond 2^53 lose precision.
+// Where the provider decodes JSON itself (such as base64-encoded payloads), [WithUseNumberDecoding]
+// decodes numbers as [encoding/json.Number] instead, preserving their precision.
+//
 // # Special Cases
 //
 // The default variant (returned when rollout is 0%) is interpreted as the
 // zero value for the requested type: false for bool, 0 for int/float,
 // empty string for string, and nil for object.
+// The reason is [openfeature.DefaultReason] when the user fell through all targeting rules,
+// or [openfeature.TargetingMatchReason] when a targeting rule explicitly assigned the default variant
+// (as indicated by the variant's metadata).
+// For kill-switch flags where "off" means disabled, use [WithOffMeansFalse]
+// to have boolean evaluation return false instead of the default value, with
+// [openfeature.TargetingMatchReason], since false is then what the targeting chose.
+//
+// If your deployment designates another variant, such as "control", as the no-rollout variant,
+// configure it with [WithOffVariantKeys] (optionally ignoring case) to have it treated like "off".
+//
+// If boolean flags may not exist yet in every environment, [WithMissingBooleanFlagAsFalse]
+// makes boolean evaluation of a missing flag return false with [openfeature.DefaultReason],
+// instead of a FLAG_NOT_FOUND error. Other types still fail.
+//
+// While the provider isn't ready (e.g. before Init completes), evaluations fail with
+// PROVIDER_NOT_READY; [WithNotReadyDefaults] resolves the flags it lists to safe values instead,
+// with [NotReadyDefaultReason].
+//
+// To kill a flag from your own config, independently of the Amplitude console,
+// list it with [WithForcedDefaults]: it then always evaluates to the default value with
+// [openfeature.DisabledReason], without calling Amplitude or tracking an exposure.
+//
+// To restrict a service to the flags it is meant to consume, list them with [WithFlagAllowlist]:
+// any other flag fails with a flag-not-found error, without calling Amplitude.
 //
 // If a variant has no payload and is not the default variant:
 //   - For boolean evaluation, it returns true
 //   - For other types, it returns an error
 //
+// # Fallback Provider
+//
+// When migrating flags from another system, use [WithFallbackProvider] to delegate
+// evaluation of flags which Amplitude doesn't have to another [openfeature.FeatureProvider]:
+//
+//	provider, err := amplitude.New(ctx, "deployment-key",
+//	    amplitude.WithFallbackProvider(legacyProvider),
+//	)
+//
+// Only a flag-not-found result triggers the fallback. Other errors, such as an invalid
+// evaluation context or a failure to reach Amplitude, are returned as usual.
+//
+// # Evaluating Multiple Flags
+//
+// [Provider.EvaluateAll] returns the variants of all flags for a context, and
+// [Provider.EvaluateFlags] returns the variants of an explicit set of flags.
+// Neither tracks exposure events, unless [WithBatchedExposures] is set, in which case each call
+// tracks a single exposure event listing all the evaluated flags. Batched exposures aren't
+// attributed to individual experiments by Amplitude's analysis, so use the typed evaluation
+// methods for flags whose experiment results matter. [Provider.ActiveExperiments] builds on [Provider.EvaluateAll]
+// to return the variant key of each flag for which the user is not in the "off" or default variant,
+// which is useful for showing users the experiments they are part of. For deployments with a very large number of flags,
+// use [WithMaxFlagsPerEvaluation] to cap the number of flags returned by [Provider.EvaluateAll],
+// either truncating the result (the default) or returning [ErrMaxFlagsExceeded]
+// (with [WithErrorOnMaxFlagsExceeded]). The cap does not apply to [Provider.EvaluateFlags].
+// [Provider.BatchEvaluation] resolves several flags for one context with a single evaluation,
+// each exactly as [Provider.ObjectEvaluation] would with a nil default value, tracking their exposures.
+// To make code using these methods testable, depend on the [FlagEvaluator] interface,
+// which [Provider] implements, rather than on *Provider.
+//
+// For batch jobs, [Provider.EvaluateForUsers] evaluates flags for many contexts with a pool of
+// workers (see [WithBatchConcurrency]), returning the results in the same order as the contexts,
+// and a [*BatchError] with the failure of each context which couldn't be evaluated.
+//
+// To backfill exposures for historical events, [Provider.EvaluateAsOf] evaluates a flag and
+// backdates its exposure event to the given time. Amplitude doesn't serve past flag config
+// versions, so the current config is used; its version is in the variant's "flagVersion" metadata.
+//
+// # Flag Metadata
+//
+// Successful resolutions carry the variant "key" and "value" in their flag metadata,
+// along with keys describing how the evaluation was performed:
+//
+//   - [MetadataKeyNormalizerApplied]: true when a user normalizer ran
+//   - [MetadataKeyEvalMs]: how long the evaluation took, in milliseconds
+//   - [MetadataKeyFlagVersion]: the version of the flag config which produced the variant
+//   - [MetadataKeyDeployed]: whether the flag config which produced the variant was deployed
+//   - [MetadataKeySegmentName]: the name of the targeting segment which matched, e.g. "beta-users"
+//   - [MetadataKeyOverrideApplied]: true when an override, such as a forced variant, chose the variant
+//   - [MetadataKeyDefaultContextMerged]: true when attributes from the Go context were merged into the user
+//
+// The flag version and deployment status are only present when Amplitude includes them
+// in the variant's metadata, which is typical for local evaluation but they may be absent
+// for remote evaluation. Likewise, the segment name is only present when a named
+// targeting segment matched:
+//
+//	details, _ := client.BooleanValueDetails(ctx, "my-flag", false, evalCtx)
+//	if segment, err := details.FlagMetadata.GetString(amplitude.MetadataKeySegmentName); err == nil {
+//	    // e.g. "beta-users"
+//	}
+//
+// To get the variant key, value and raw payload of a flag together with its metadata and reason,
+// without evaluating it once per accessor, use [Provider.EvaluateFull].
+//
 // # Amplitude User Fields
 //
 // The following Amplitude user fields can be set via the evaluation context:
@@ -159,7 +402,7 @@
 //   - [KeyCity]: User's city
 //   - [KeyDma]: Designated Market Area
 //   - [KeyLanguage]: User's language preference
-//   - [KeyPlatform]: Platform (iOS, Android, Web, etc.)
+//   - [KeyPlatform]: Platform (iOS, Android, Web, etc.; see [PlatformIOS] and friends)
 //   - [KeyVersion]: Application version
 //   - [KeyOs]: Operating system
 //   - [KeyDeviceManufacturer]: Device manufacturer
@@ -173,6 +416,8 @@
 //   - [KeyCohortIDs]: Cohort IDs for targeting (map[string]struct{})
 //   - [KeyGroupCohortIDSet]: Group cohort IDs (map[string]map[string]map[string]struct{})
 //
+// Use [Groups] and [GroupCohorts] to build the values of [KeyGroups] and [KeyGroupCohortIDSet].
+//
 // # Event Tracking
 //
 // The provider implements the [openfeature.Tracker] interface, allowing you to send
@@ -187,10 +432,43 @@
 //	openfeature.SetProviderAndWait(provider)
 //	client := openfeature.NewDefaultClient()
 //
+// [DefaultTrackingConfig] returns an analytics config with our recommended settings
+// for server-side use, which you can customize before passing it to [WithTrackingEnabled]:
+//
+//	trackingConfig := amplitude.DefaultTrackingConfig("your-amplitude-api-key")
+//	trackingConfig.ServerZone = analytics.ServerZoneEU
+//	provider, err := amplitude.New(ctx, "deployment-key",
+//	    amplitude.WithTrackingEnabled(trackingConfig),
+//	)
+//
 // When tracking is enabled:
 //   - Exposure events are automatically sent when flags are evaluated
 //   - You can send custom tracking events via the client's Track method
 //   - Assignment events are tracked for local evaluation
+//   - Tracking never interrupts evaluation; a panic in the analytics client is recovered and logged
+//
+// To tie exposures to where a flag was evaluated, set the reserved [ContextKeySurface] key
+// in the evaluation context; it is recorded on the exposure event rather than used for targeting.
+// Other evaluation context keys are only copied to exposure events if they are listed
+// with [WithExposureContextKeys]. To attach computed properties, such as a session bucket,
+// use [WithExposureEventPropertiesFunc]; it runs on the evaluation path, so it should be fast.
+//
+// For logged-out users whose exposures shouldn't be attributed until they are identified,
+// set the reserved [ContextKeyAnonymous] key to true: flags are evaluated as usual, but no
+// exposure events are tracked. Assignment events sent by the local evaluation SDK itself
+// are not affected.
+// To evaluate without exposures at specific call sites, such as health checks or previews,
+// wrap the context passed to the evaluation with [ContextWithoutExposures], or, where only the
+// evaluation context can be set, set the reserved [ContextKeyNoExposure] key to true.
+//
+// With local evaluation, [WithCombinedExposureAssignment] tracks assignments on the exposure events
+// instead of as separate assignment events, at the cost of not recording assignments without exposures.
+//
+// To track at most one exposure per user, flag and variant within a TTL, use [WithExposureDeduplication].
+// To mirror exposures elsewhere, such as to a data warehouse, set a sink with [WithExposureSink];
+// it is called from a bounded buffer, so a slow sink drops records rather than stalling evaluations.
+//
+// To suspend all tracking at runtime (e.g. during a load test), use [Provider.SetTrackingEnabled].
 //
 // See https://amplitude.com/docs/feature-experiment/under-the-hood/event-tracking for details.
 //
@@ -213,6 +491,64 @@
 //
 // Additional attributes added via Add() are mapped using the same key mapping logic
 // as the evaluation context. Unmapped keys are placed in the event's EventProperties.
+// The [KeyPrice], [KeyQuantity] and [KeyRevenue] attributes populate the event's Price, Quantity and
+// Revenue fields, which Amplitude's revenue analysis uses, rather than its EventProperties.
+// Numeric strings are parsed; a quantity which isn't a whole number fails the event.
+//
+// # Metrics
+//
+// [WithMetrics] records the count, reason and duration of evaluations, and whether
+// remote evaluation cache lookups hit, with any implementation of the [Metrics] interface.
+// An OpenTelemetry implementation is provided by the
+// github.com/open-feature/go-sdk-contrib/providers/amplitude/otelmetrics package,
+// so that this package doesn't import OpenTelemetry:
+//
+//	metrics, err := otelmetrics.NewForMeterProvider(meterProvider)
+//	provider, err := amplitude.New(ctx, "deployment-key", amplitude.WithMetrics(metrics))
+//
+// To alert when evaluation errors force default values, use [WithFallbackCallback],
+// which isn't called for "off" variants or forced defaults.
+//
+// # Decision Audit
+//
+// Exposure events go to Amplitude; for a compliance trail of flag decisions in your own systems,
+// use [WithDecisionAuditSink]. The sink receives an [AuditRecord] (user, flag, variant, reason,
+// timestamp and flag config version) for every successful evaluation:
+//
+//	amplitude.WithDecisionAuditSink(func(ctx context.Context, record amplitude.AuditRecord) {
+//	    auditLog.Write(record)
+//	}, amplitude.AuditAsync(), amplitude.AuditErrors())
+//
+// By default the sink is called synchronously, adding its latency to the evaluation.
+// [AuditAsync] calls it from a goroutine with a bounded buffer instead, dropping records when the buffer
+// is full (see [Provider.DroppedAuditRecords]), and [AuditErrors] also records failed evaluations.
+//
+// # Identity-Only Flags
+//
+// For high-QPS flags which only target on user ID or device ID, [WithIdentityOnlyEvaluation]
+// evaluates them with a minimal user containing only the identity fields, skipping the cost
+// of mapping the rest of the evaluation context. This bypasses property-based targeting
+// (e.g. on country or user properties) for those flags.
+//
+// # Context Enricher
+//
+// To add raw attributes to the evaluation context before key mapping (so they are mapped
+// like any other attribute), use [WithContextEnricher]. It runs before key mapping for both
+// evaluation and tracking:
+//
+//	provider, err := amplitude.New(ctx, "deployment-key",
+//	    amplitude.WithContextEnricher(func(ctx context.Context, evalCtx openfeature.FlattenedContext) (openfeature.FlattenedContext, error) {
+//	        enriched := maps.Clone(evalCtx)
+//	        enriched["country"] = countryFromIP(evalCtx["ip"])
+//	        return enriched, nil
+//	    }),
+//	)
+//
+// # Context Value Extractors
+//
+// To pull attributes which live on the Go context (such as a tenant or locale set by request
+// middleware) into evaluation and tracking, use [WithContextValueExtractors]. The evaluation
+// context takes precedence over extracted attributes.
 //
 // # User Normalizer
 //
@@ -234,6 +570,9 @@
 // The [UserNormalizationContext] provides access to both the original evaluation context
 // and the partially-built Amplitude User. Return an error to abort the evaluation.
 //
+// To unit-test a key map or user normalizer, [Provider.BuildUser] returns the Amplitude User
+// for an evaluation context. It works before the provider is initialized.
+//
 // # Event Normalizer
 //
 // For advanced event transformation, use [WithEventNormalizer]. The normalizer function
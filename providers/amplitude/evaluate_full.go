@@ -0,0 +1,52 @@
+package amplitude
+
+import (
+	"context"
+
+	of "github.com/open-feature/go-sdk/openfeature"
+)
+
+// FullResult is everything known about the evaluation of a flag; see [Provider.EvaluateFull].
+type FullResult struct {
+	// VariantKey is the key of the evaluated variant, e.g. "off" if the user is not in the rollout.
+	// It is empty if the flag was forced to its default value (see [WithForcedDefaults]).
+	VariantKey string
+	// Value is the value of the variant, as configured in Amplitude.
+	Value string
+	// Payload is the payload of the variant as decoded from JSON by the Amplitude SDK,
+	// or nil if the variant has none. It is not converted or decoded any further.
+	Payload any
+	// Metadata is the flag metadata the typed evaluation methods report for the variant,
+	// or nil if the default value would be used (e.g. for the "off" variant).
+	Metadata of.FlagMetadata
+	// Reason is the reason the typed evaluation methods report for the evaluation.
+	Reason of.Reason
+}
+
+// EvaluateFull evaluates a flag and returns its variant key, value and raw payload together,
+// with the metadata and reason reported by the typed evaluation methods, for consumers who need
+// more than one of them without evaluating the flag twice.
+// Like the typed evaluation methods, it tracks an exposure (if tracking is enabled),
+// records metrics and audits the decision, but the fallback provider (see [WithFallbackProvider])
+// is not used, and no default values apply.
+// It returns an [of.ResolutionError] if the flag can't be evaluated.
+func (p *Provider) EvaluateFull(ctx context.Context, flag string, evalCtx of.FlattenedContext) (FullResult, error) {
+	eval, resErr := p.evaluateFlag(ctx, flag, evalCtx)
+	if resErr != nil {
+		return FullResult{}, resErr.ResolutionError
+	}
+	if eval.variant == nil {
+		return FullResult{
+			VariantKey: eval.evaluated.Key,
+			Value:      eval.evaluated.Value,
+			Reason:     eval.offReason,
+		}, nil
+	}
+	return FullResult{
+		VariantKey: eval.variant.Key,
+		Value:      eval.variant.Value,
+		Payload:    eval.variant.Payload,
+		Metadata:   eval.flagMetadata(),
+		Reason:     eval.reason,
+	}, nil
+}
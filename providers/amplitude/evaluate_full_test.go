@@ -0,0 +1,73 @@
+package amplitude
+
+import (
+	"context"
+	"testing"
+
+	experiment "github.com/amplitude/experiment-go-server/pkg/experiment"
+	of "github.com/open-feature/go-sdk/openfeature"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProvider_EvaluateFull(t *testing.T) {
+	payload := map[string]any{"color": "blue", "size": 3.0}
+	variants := map[string]experiment.Variant{
+		"test-flag": {Key: "treatment", Value: "on", Payload: payload, Metadata: map[string]any{"flagVersion": 4}},
+		"off-flag":  {Key: "off", Metadata: map[string]any{"default": true}},
+	}
+	newProvider := func(t *testing.T, options ...Option) *Provider {
+		t.Helper()
+		mock := &mockClientAdapter{
+			EvaluateFunc: func(_ context.Context, _ *experiment.User, _ []string) (map[string]experiment.Variant, error) {
+				return variants, nil
+			},
+		}
+		return newTestProvider(t, mock, options...)
+	}
+	evalCtx := of.FlattenedContext{of.TargetingKey: "user-1"}
+
+	t.Run("returns the variant key, value and payload together", func(t *testing.T) {
+		provider := newProvider(t)
+
+		result, err := provider.EvaluateFull(context.Background(), "test-flag", evalCtx)
+
+		require.NoError(t, err)
+		assert.Equal(t, "treatment", result.VariantKey)
+		assert.Equal(t, "on", result.Value)
+		assert.Equal(t, payload, result.Payload)
+		assert.Equal(t, int64(4), result.Metadata[MetadataKeyFlagVersion])
+		objectResult := provider.ObjectEvaluation(context.Background(), "test-flag", nil, evalCtx)
+		assert.Equal(t, objectResult.Reason, result.Reason)
+	})
+
+	t.Run("reports the off variant", func(t *testing.T) {
+		provider := newProvider(t)
+
+		result, err := provider.EvaluateFull(context.Background(), "off-flag", evalCtx)
+
+		require.NoError(t, err)
+		assert.Equal(t, "off", result.VariantKey)
+		assert.Nil(t, result.Payload)
+		assert.Nil(t, result.Metadata)
+		assert.Equal(t, of.DefaultReason, result.Reason)
+	})
+
+	t.Run("forced defaults have no variant", func(t *testing.T) {
+		provider := newProvider(t, WithForcedDefaults("test-flag"))
+
+		result, err := provider.EvaluateFull(context.Background(), "test-flag", evalCtx)
+
+		require.NoError(t, err)
+		assert.Empty(t, result.VariantKey)
+		assert.Equal(t, of.DisabledReason, result.Reason)
+	})
+
+	t.Run("unknown flag", func(t *testing.T) {
+		provider := newProvider(t)
+
+		_, err := provider.EvaluateFull(context.Background(), "missing-flag", evalCtx)
+
+		assert.ErrorContains(t, err, string(of.FlagNotFoundCode))
+	})
+}
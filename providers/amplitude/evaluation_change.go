@@ -0,0 +1,102 @@
+package amplitude
+
+import (
+	"container/list"
+	"sync"
+
+	experiment "github.com/amplitude/experiment-go-server/pkg/experiment"
+)
+
+// defaultEvaluationChangeTrackingSize is the number of user and flag pairs whose last variant
+// is remembered to detect changes, unless configured with [WithEvaluationChangeTrackingSize].
+const defaultEvaluationChangeTrackingSize = 10_000
+
+// evaluationChangeTracker remembers the last variant observed for each user and flag,
+// and invokes a callback when a later evaluation yields a different variant.
+// It is bounded: the least recently observed pairs are forgotten first.
+type evaluationChangeTracker struct {
+	callback func(flag, userID, oldVariant, newVariant string)
+	size     int
+
+	mu sync.Mutex
+	// entries maps user and flag pairs to their elements in recent.
+	entries map[evaluationChangeKey]*list.Element
+	// recent lists the observed pairs, most recently observed first.
+	recent *list.List
+}
+
+// evaluationChangeKey identifies a user and flag pair.
+type evaluationChangeKey struct {
+	userID string
+	flag   string
+}
+
+// evaluationChangeEntry is the value of the elements of evaluationChangeTracker.recent.
+type evaluationChangeEntry struct {
+	key     evaluationChangeKey
+	variant string
+}
+
+// newEvaluationChangeTracker creates a tracker which remembers up to size user and flag pairs.
+func newEvaluationChangeTracker(callback func(flag, userID, oldVariant, newVariant string), size int) *evaluationChangeTracker {
+	if size <= 0 {
+		size = defaultEvaluationChangeTrackingSize
+	}
+	return &evaluationChangeTracker{
+		callback: callback,
+		size:     size,
+		entries:  make(map[evaluationChangeKey]*list.Element),
+		recent:   list.New(),
+	}
+}
+
+// observe records the variants evaluated for the user, invoking the callback for each flag
+// whose variant differs from the one last observed for the user.
+// Users without a user ID or device ID can't be told apart, so they are ignored.
+func (t *evaluationChangeTracker) observe(user *experiment.User, variants map[string]experiment.Variant) {
+	userID := user.UserId
+	if userID == "" {
+		userID = user.DeviceId
+	}
+	if userID == "" {
+		return
+	}
+
+	type change struct {
+		flag, oldVariant, newVariant string
+	}
+	var changes []change
+	t.mu.Lock()
+	for flag, variant := range variants {
+		key := evaluationChangeKey{userID: userID, flag: flag}
+		if element, ok := t.entries[key]; ok {
+			entry := element.Value.(*evaluationChangeEntry)
+			if entry.variant != variant.Key {
+				changes = append(changes, change{flag: flag, oldVariant: entry.variant, newVariant: variant.Key})
+				entry.variant = variant.Key
+			}
+			t.recent.MoveToFront(element)
+			continue
+		}
+		t.entries[key] = t.recent.PushFront(&evaluationChangeEntry{key: key, variant: variant.Key})
+		if t.recent.Len() > t.size {
+			oldest := t.recent.Remove(t.recent.Back()).(*evaluationChangeEntry)
+			delete(t.entries, oldest.key)
+		}
+	}
+	t.mu.Unlock()
+
+	// The callback runs without holding the lock, so it may evaluate flags itself.
+	for _, c := range changes {
+		t.callback(c.flag, userID, c.oldVariant, c.newVariant)
+	}
+}
+
+// observeEvaluationChanges reports changes in the variants evaluated for the user,
+// if an evaluation change callback is configured.
+func (p *Provider) observeEvaluationChanges(user *experiment.User, variants map[string]experiment.Variant) {
+	if p.changeTracker == nil || user == nil {
+		return
+	}
+	p.changeTracker.observe(user, variants)
+}
@@ -0,0 +1,98 @@
+package amplitude
+
+import (
+	"context"
+	"testing"
+
+	experiment "github.com/amplitude/experiment-go-server/pkg/experiment"
+	of "github.com/open-feature/go-sdk/openfeature"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// evaluationChange records a call to an evaluation change callback.
+type evaluationChange struct {
+	flag, userID, oldVariant, newVariant string
+}
+
+func TestProvider_EvaluationChangeCallback(t *testing.T) {
+	newProvider := func(t *testing.T, options ...Option) (*Provider, *string, *[]evaluationChange) {
+		t.Helper()
+		variantKey := "control"
+		var changes []evaluationChange
+		mock := &mockClientAdapter{
+			EvaluateFunc: func(_ context.Context, _ *experiment.User, _ []string) (map[string]experiment.Variant, error) {
+				return map[string]experiment.Variant{"test-flag": makeVariant(variantKey, variantKey, nil)}, nil
+			},
+		}
+		options = append(options, WithEvaluationChangeCallback(func(flag, userID, oldVariant, newVariant string) {
+			changes = append(changes, evaluationChange{flag, userID, oldVariant, newVariant})
+		}))
+		return newTestProvider(t, mock, options...), &variantKey, &changes
+	}
+	evalCtx := func(userID string) of.FlattenedContext {
+		return of.FlattenedContext{of.TargetingKey: userID}
+	}
+
+	t.Run("reports a changed variant", func(t *testing.T) {
+		provider, variantKey, changes := newProvider(t)
+
+		provider.StringEvaluation(context.Background(), "test-flag", "default", evalCtx("user-1"))
+		provider.StringEvaluation(context.Background(), "test-flag", "default", evalCtx("user-1"))
+		assert.Empty(t, *changes, "the first evaluation and an unchanged variant are not changes")
+
+		*variantKey = "treatment"
+		provider.StringEvaluation(context.Background(), "test-flag", "default", evalCtx("user-1"))
+
+		assert.Equal(t, []evaluationChange{{"test-flag", "user-1", "control", "treatment"}}, *changes)
+	})
+
+	t.Run("tracks users separately", func(t *testing.T) {
+		provider, variantKey, changes := newProvider(t)
+
+		provider.StringEvaluation(context.Background(), "test-flag", "default", evalCtx("user-1"))
+		*variantKey = "treatment"
+		provider.StringEvaluation(context.Background(), "test-flag", "default", evalCtx("user-2"))
+
+		assert.Empty(t, *changes)
+	})
+
+	t.Run("multi-flag evaluations are observed", func(t *testing.T) {
+		provider, variantKey, changes := newProvider(t)
+
+		_, err := provider.EvaluateAll(context.Background(), evalCtx("user-1"))
+		require.NoError(t, err)
+		*variantKey = "treatment"
+		_, err = provider.EvaluateFlags(context.Background(), evalCtx("user-1"), []string{"test-flag"})
+		require.NoError(t, err)
+
+		assert.Equal(t, []evaluationChange{{"test-flag", "user-1", "control", "treatment"}}, *changes)
+	})
+
+	t.Run("the least recently observed users are forgotten", func(t *testing.T) {
+		provider, variantKey, changes := newProvider(t, WithEvaluationChangeTrackingSize(1))
+
+		provider.StringEvaluation(context.Background(), "test-flag", "default", evalCtx("user-1"))
+		provider.StringEvaluation(context.Background(), "test-flag", "default", evalCtx("user-2"))
+		*variantKey = "treatment"
+		provider.StringEvaluation(context.Background(), "test-flag", "default", evalCtx("user-1"))
+		provider.StringEvaluation(context.Background(), "test-flag", "default", evalCtx("user-1"))
+
+		assert.Empty(t, *changes)
+	})
+}
+
+func TestEvaluationChangeTracker_Size(t *testing.T) {
+	tracker := newEvaluationChangeTracker(func(string, string, string, string) {}, 2)
+	user := &experiment.User{UserId: "user-1"}
+
+	tracker.observe(user, map[string]experiment.Variant{
+		"flag-a": makeVariant("on", "on", nil),
+		"flag-b": makeVariant("on", "on", nil),
+		"flag-c": makeVariant("on", "on", nil),
+	})
+
+	assert.Equal(t, 2, tracker.recent.Len())
+	assert.Len(t, tracker.entries, 2)
+	assert.Equal(t, defaultEvaluationChangeTrackingSize, newEvaluationChangeTracker(nil, 0).size)
+}
@@ -0,0 +1,84 @@
+package amplitude
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	experiment "github.com/amplitude/experiment-go-server/pkg/experiment"
+)
+
+// defaultExposureDeduplicationSize is the number of user and flag pairs whose last exposure
+// is remembered to deduplicate exposures, unless configured with [WithExposureDeduplicationSize].
+const defaultExposureDeduplicationSize = 10_000
+
+// exposureDeduplicator remembers when each user and flag pair was last exposed, and to which variant,
+// to suppress repeated exposures within a TTL. Like [evaluationChangeTracker], it is bounded:
+// the least recently exposed pairs are forgotten first, and then exposed again.
+type exposureDeduplicator struct {
+	ttl  time.Duration
+	size int
+	// now returns the current time; it is replaced in tests.
+	now func() time.Time
+
+	mu sync.Mutex
+	// entries maps user and flag pairs to their elements in recent.
+	entries map[evaluationChangeKey]*list.Element
+	// recent lists the exposed pairs, most recently exposed first.
+	recent *list.List
+}
+
+// exposureDeduplicationEntry is the value of the elements of exposureDeduplicator.recent.
+type exposureDeduplicationEntry struct {
+	key       evaluationChangeKey
+	variant   string
+	exposedAt time.Time
+}
+
+// newExposureDeduplicator creates a deduplicator which remembers up to size user and flag pairs for ttl.
+func newExposureDeduplicator(ttl time.Duration, size int) *exposureDeduplicator {
+	if size <= 0 {
+		size = defaultExposureDeduplicationSize
+	}
+	return &exposureDeduplicator{
+		ttl:     ttl,
+		size:    size,
+		now:     time.Now,
+		entries: make(map[evaluationChangeKey]*list.Element),
+		recent:  list.New(),
+	}
+}
+
+// shouldExpose returns false if the user was exposed to the same variant of the flag within the TTL,
+// and otherwise records the exposure and returns true.
+// Users without a user ID or device ID can't be told apart, so they are always exposed.
+func (d *exposureDeduplicator) shouldExpose(user *experiment.User, flag string, variant experiment.Variant) bool {
+	userID := user.UserId
+	if userID == "" {
+		userID = user.DeviceId
+	}
+	if userID == "" {
+		return true
+	}
+
+	key := evaluationChangeKey{userID: userID, flag: flag}
+	now := d.now()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if element, ok := d.entries[key]; ok {
+		entry := element.Value.(*exposureDeduplicationEntry)
+		if entry.variant == variant.Key && now.Sub(entry.exposedAt) < d.ttl {
+			return false
+		}
+		entry.variant = variant.Key
+		entry.exposedAt = now
+		d.recent.MoveToFront(element)
+		return true
+	}
+	d.entries[key] = d.recent.PushFront(&exposureDeduplicationEntry{key: key, variant: variant.Key, exposedAt: now})
+	if d.recent.Len() > d.size {
+		oldest := d.recent.Remove(d.recent.Back()).(*exposureDeduplicationEntry)
+		delete(d.entries, oldest.key)
+	}
+	return true
+}
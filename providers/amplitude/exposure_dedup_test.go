@@ -0,0 +1,87 @@
+package amplitude
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	experiment "github.com/amplitude/experiment-go-server/pkg/experiment"
+	of "github.com/open-feature/go-sdk/openfeature"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProvider_ExposureDeduplication(t *testing.T) {
+	newProvider := func(t *testing.T, options ...Option) (*Provider, *string, *mockAnalyticsClient, *time.Time) {
+		t.Helper()
+		variantKey := "control"
+		mock := &mockClientAdapter{
+			EvaluateFunc: func(context.Context, *experiment.User, []string) (map[string]experiment.Variant, error) {
+				return map[string]experiment.Variant{"test-flag": makeVariant(variantKey, variantKey, nil)}, nil
+			},
+		}
+		provider, analyticsClient := newTrackingTestProvider(t, mock, options...)
+		now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		if provider.exposureDeduplicator != nil {
+			provider.exposureDeduplicator.now = func() time.Time { return now }
+		}
+		return provider, &variantKey, analyticsClient, &now
+	}
+	evaluate := func(provider *Provider, userID string) {
+		provider.StringEvaluation(context.Background(), "test-flag", "default", of.FlattenedContext{of.TargetingKey: userID})
+	}
+
+	t.Run("repeated exposures are suppressed within the TTL", func(t *testing.T) {
+		provider, _, analyticsClient, now := newProvider(t, WithExposureDeduplication(time.Hour))
+
+		evaluate(provider, "user-1")
+		evaluate(provider, "user-1")
+		*now = now.Add(59 * time.Minute)
+		evaluate(provider, "user-1")
+		assert.Len(t, analyticsClient.events, 1)
+
+		*now = now.Add(time.Minute)
+		evaluate(provider, "user-1")
+		assert.Len(t, analyticsClient.events, 2, "exposures are tracked again after the TTL")
+	})
+
+	t.Run("a changed variant is exposed again", func(t *testing.T) {
+		provider, variantKey, analyticsClient, _ := newProvider(t, WithExposureDeduplication(time.Hour))
+
+		evaluate(provider, "user-1")
+		*variantKey = "treatment"
+		evaluate(provider, "user-1")
+		evaluate(provider, "user-1")
+
+		require.Len(t, analyticsClient.events, 2)
+		assert.Equal(t, "treatment", analyticsClient.events[1].EventProperties["variant"])
+	})
+
+	t.Run("users are deduplicated separately", func(t *testing.T) {
+		provider, _, analyticsClient, _ := newProvider(t, WithExposureDeduplication(time.Hour))
+
+		evaluate(provider, "user-1")
+		evaluate(provider, "user-2")
+
+		assert.Len(t, analyticsClient.events, 2)
+	})
+
+	t.Run("the least recently exposed users are forgotten", func(t *testing.T) {
+		provider, _, analyticsClient, _ := newProvider(t, WithExposureDeduplication(time.Hour), WithExposureDeduplicationSize(1))
+
+		evaluate(provider, "user-1")
+		evaluate(provider, "user-2")
+		evaluate(provider, "user-1")
+
+		assert.Len(t, analyticsClient.events, 3)
+	})
+
+	t.Run("disabled by default", func(t *testing.T) {
+		provider, _, analyticsClient, _ := newProvider(t)
+
+		evaluate(provider, "user-1")
+		evaluate(provider, "user-1")
+
+		assert.Len(t, analyticsClient.events, 2)
+	})
+}
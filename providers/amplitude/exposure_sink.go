@@ -0,0 +1,50 @@
+package amplitude
+
+import (
+	"context"
+	"time"
+
+	experiment "github.com/amplitude/experiment-go-server/pkg/experiment"
+)
+
+// ExposureRecord describes an exposure of a user to a variant of a flag,
+// as passed to the sink set with [WithExposureSink].
+type ExposureRecord struct {
+	// Flag is the key of the flag.
+	Flag string
+	// Variant is the key of the variant the user was exposed to.
+	Variant string
+	// UserID and DeviceID identify the user; either may be empty.
+	UserID   string
+	DeviceID string
+	// Timestamp is when the exposure occurred; for [Provider.EvaluateAsOf], the time it is backdated to.
+	Timestamp time.Time
+}
+
+// sinkExposure buffers the exposure of the user to the variant of the flag for the exposure sink,
+// if any, unless the variant is "off" or tracking is suspended. If at is zero, the current time is used.
+func (p *Provider) sinkExposure(ctx context.Context, user *experiment.User, flag string, variant experiment.Variant, at time.Time) {
+	if p.exposureSink == nil || p.trackingDisabled.Load() || p.isOffVariant(&variant) {
+		return
+	}
+	if at.IsZero() {
+		at = time.Now()
+	}
+	p.exposureSink.send(ctx, ExposureRecord{
+		Flag:      flag,
+		Variant:   variant.Key,
+		UserID:    user.UserId,
+		DeviceID:  user.DeviceId,
+		Timestamp: at,
+	})
+}
+
+// DroppedExposureRecords returns the number of exposure records which were dropped rather than
+// passed to the sink set with [WithExposureSink], because its buffer was full
+// (see [WithExposureSinkBufferSize]).
+func (p *Provider) DroppedExposureRecords() uint64 {
+	if p.exposureSink == nil {
+		return 0
+	}
+	return p.exposureSink.dropped.Load()
+}
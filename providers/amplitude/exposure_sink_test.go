@@ -0,0 +1,120 @@
+package amplitude
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	experiment "github.com/amplitude/experiment-go-server/pkg/experiment"
+	of "github.com/open-feature/go-sdk/openfeature"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProvider_ExposureSink(t *testing.T) {
+	newProvider := func(t *testing.T) (*Provider, *[]ExposureRecord) {
+		t.Helper()
+		mock := &mockClientAdapter{
+			EvaluateFunc: func(context.Context, *experiment.User, []string) (map[string]experiment.Variant, error) {
+				return map[string]experiment.Variant{
+					"on-flag":  makeVariant("treatment", "treatment", "treatment"),
+					"off-flag": makeVariant("off", "", nil),
+				}, nil
+			},
+		}
+		var records []ExposureRecord
+		provider := newTestProvider(t, mock,
+			WithExposureSink(func(_ context.Context, record ExposureRecord) {
+				records = append(records, record)
+			}),
+		)
+		return provider, &records
+	}
+	evalCtx := of.FlattenedContext{of.TargetingKey: "user-1", "device_id": "device-1"}
+
+	t.Run("exposures are passed to the sink", func(t *testing.T) {
+		provider, records := newProvider(t)
+		analyticsClient := &mockAnalyticsClient{}
+		provider.analyticsClient = analyticsClient
+		before := time.Now()
+
+		provider.StringEvaluation(context.Background(), "on-flag", "default", evalCtx)
+		require.NoError(t, provider.Close())
+
+		require.Len(t, *records, 1)
+		record := (*records)[0]
+		assert.Equal(t, "on-flag", record.Flag)
+		assert.Equal(t, "treatment", record.Variant)
+		assert.Equal(t, "user-1", record.UserID)
+		assert.Equal(t, "device-1", record.DeviceID)
+		assert.False(t, record.Timestamp.Before(before))
+		assert.Len(t, analyticsClient.events, 1, "the exposure is still tracked in Amplitude")
+	})
+
+	t.Run("off variants aren't passed to the sink", func(t *testing.T) {
+		provider, records := newProvider(t)
+
+		provider.StringEvaluation(context.Background(), "off-flag", "default", evalCtx)
+		require.NoError(t, provider.Close())
+
+		assert.Empty(t, *records)
+	})
+
+	t.Run("the sink works without tracking", func(t *testing.T) {
+		provider, records := newProvider(t)
+
+		provider.BooleanEvaluation(context.Background(), "on-flag", false, evalCtx)
+		require.NoError(t, provider.Close())
+
+		assert.Len(t, *records, 1)
+	})
+
+	t.Run("suppressed exposures aren't passed to the sink", func(t *testing.T) {
+		provider, records := newProvider(t)
+
+		provider.StringEvaluation(ContextWithoutExposures(context.Background()), "on-flag", "default", evalCtx)
+		provider.SetTrackingEnabled(false)
+		provider.StringEvaluation(context.Background(), "on-flag", "default", evalCtx)
+		require.NoError(t, provider.Close())
+
+		assert.Empty(t, *records)
+	})
+}
+
+func TestProvider_ExposureSink_Blocking(t *testing.T) {
+	mock := &mockClientAdapter{
+		EvaluateFunc: func(context.Context, *experiment.User, []string) (map[string]experiment.Variant, error) {
+			return map[string]experiment.Variant{"on-flag": makeVariant("treatment", "treatment", "treatment")}, nil
+		},
+	}
+	release := make(chan struct{})
+	var passed atomic.Int32
+	provider := newTestProvider(t, mock,
+		WithExposureSink(func(context.Context, ExposureRecord) {
+			<-release
+			passed.Add(1)
+		}),
+		WithExposureSinkBufferSize(2),
+	)
+	evalCtx := of.FlattenedContext{of.TargetingKey: "user-1"}
+
+	evaluated := make(chan struct{})
+	go func() {
+		defer close(evaluated)
+		for range 10 {
+			provider.StringEvaluation(context.Background(), "on-flag", "default", evalCtx)
+		}
+	}()
+	select {
+	case <-evaluated:
+	case <-time.After(time.Second):
+		t.Fatal("a blocking exposure sink stalled evaluation")
+	}
+	close(release)
+	require.NoError(t, provider.Close())
+
+	// The worker holds one record and the buffer two more, so at least 7 of the 10 are dropped.
+	assert.GreaterOrEqual(t, provider.DroppedExposureRecords(), uint64(7))
+	assert.EqualValues(t, 10, uint64(passed.Load())+provider.DroppedExposureRecords())
+}
@@ -0,0 +1,130 @@
+package amplitude
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"unsafe"
+
+	"github.com/amplitude/experiment-go-server/pkg/experiment/local"
+)
+
+// FlagConfigStats describes the flag configs used for local evaluation.
+// See [Provider.FlagConfigStats].
+type FlagConfigStats struct {
+	// Flags is the number of flags in the deployment.
+	Flags int
+	// Cohorts is the number of distinct cohorts targeted by the flags.
+	// Cohort memberships must be held in memory for local evaluation.
+	Cohorts int
+	// ApproximateConfigBytes is the approximate size of the flag configs, in bytes.
+	// It is the size of the serialized configs, which approximates (but does not equal)
+	// the memory they occupy, and does not include cohort memberships.
+	ApproximateConfigBytes int
+}
+
+// flagConfigStatsProvider is implemented by client adapters which can describe their flag configs.
+type flagConfigStatsProvider interface {
+	flagConfigStats() (FlagConfigStats, error)
+}
+
+// flagConfigStats returns statistics about the flag configs held by the local evaluation client.
+func (c *clientAdapterLocal) flagConfigStats() (FlagConfigStats, error) {
+	flagsJSON, err := residentFlagConfigs(c.client)
+	if err != nil {
+		return FlagConfigStats{}, fmt.Errorf("failed to get flag configs: %w", err)
+	}
+	return newFlagConfigStats(flagsJSON)
+}
+
+// flagConfigLister is implemented by local evaluators which can serialize the flag configs they hold,
+// such as test doubles; the Amplitude SDK's client is read by [sdkFlagConfigs] instead.
+type flagConfigLister interface {
+	flagConfigsJSON() ([]byte, error)
+}
+
+// residentFlagConfigs returns the flag configs held by the local evaluator, serialized as
+// a JSON object of flag keys to flag configs, without contacting Amplitude.
+func residentFlagConfigs(client localEvaluator) ([]byte, error) {
+	switch client := client.(type) {
+	case flagConfigLister:
+		return client.flagConfigsJSON()
+	case *local.Client:
+		return sdkFlagConfigs(client)
+	default:
+		return nil, fmt.Errorf("unsupported local evaluation client %T", client)
+	}
+}
+
+// sdkFlagConfigs serializes the flag configs held by the SDK's local evaluation client.
+// The client doesn't expose them, but its flag config storage has an exported GetFlagConfigs method,
+// which copies them under the storage's lock; it is found by reflection, and an error is returned
+// if the SDK changes shape, rather than guessing.
+func sdkFlagConfigs(client *local.Client) ([]byte, error) {
+	field := reflect.ValueOf(client).Elem().FieldByName("flagConfigStorage")
+	if !field.IsValid() || field.Kind() != reflect.Interface {
+		return nil, errors.New("the Amplitude SDK has no flag config storage")
+	}
+	// Values read from unexported fields can't be used to call methods, so read it through its address.
+	storage := reflect.NewAt(field.Type(), unsafe.Pointer(field.UnsafeAddr())).Elem()
+	if storage.IsNil() {
+		return nil, errors.New("the Amplitude SDK has no flag config storage")
+	}
+	getFlagConfigs := storage.Elem().MethodByName("GetFlagConfigs")
+	if !getFlagConfigs.IsValid() || getFlagConfigs.Type().NumIn() != 0 || getFlagConfigs.Type().NumOut() != 1 ||
+		getFlagConfigs.Type().Out(0).Kind() != reflect.Map {
+		return nil, errors.New("the flag config storage of the Amplitude SDK has no GetFlagConfigs method")
+	}
+	return json.Marshal(getFlagConfigs.Call(nil)[0].Interface())
+}
+
+// newFlagConfigStats computes statistics from serialized flag configs,
+// which are a JSON object of flag keys to flag configs.
+func newFlagConfigStats(flagsJSON []byte) (FlagConfigStats, error) {
+	var flags map[string]any
+	if err := json.Unmarshal(flagsJSON, &flags); err != nil {
+		return FlagConfigStats{}, fmt.Errorf("failed to decode flag configs: %w", err)
+	}
+
+	cohortIDs := make(map[string]struct{})
+	for _, flag := range flags {
+		collectCohortIDs(flag, cohortIDs)
+	}
+
+	return FlagConfigStats{
+		Flags:                  len(flags),
+		Cohorts:                len(cohortIDs),
+		ApproximateConfigBytes: len(flagsJSON),
+	}, nil
+}
+
+// collectCohortIDs walks a decoded flag config, collecting the values of
+// targeting conditions whose selector refers to cohort IDs.
+func collectCohortIDs(value any, cohortIDs map[string]struct{}) {
+	switch v := value.(type) {
+	case map[string]any:
+		if selector, ok := v["selector"].([]any); ok && slices.ContainsFunc(selector, isCohortIDsSelector) {
+			if values, ok := v["values"].([]any); ok {
+				for _, cohortID := range values {
+					if id, ok := cohortID.(string); ok {
+						cohortIDs[id] = struct{}{}
+					}
+				}
+			}
+		}
+		for _, child := range v {
+			collectCohortIDs(child, cohortIDs)
+		}
+	case []any:
+		for _, child := range v {
+			collectCohortIDs(child, cohortIDs)
+		}
+	}
+}
+
+// isCohortIDsSelector returns true if the selector element refers to cohort IDs.
+func isCohortIDsSelector(element any) bool {
+	return element == string(KeyCohortIDs)
+}
@@ -0,0 +1,123 @@
+package amplitude
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/amplitude/experiment-go-server/pkg/experiment/local"
+	"github.com/amplitude/experiment-go-server/pkg/logger"
+	of "github.com/open-feature/go-sdk/openfeature"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testFlagConfigs are serialized flag configs in the format returned by the local evaluation client.
+const testFlagConfigs = `{
+	"flag-a": {
+		"key": "flag-a",
+		"segments": [
+			{
+				"conditions": [[
+					{"selector": ["context", "user", "cohort_ids"], "op": "set contains any", "values": ["cohort-1", "cohort-2"]}
+				]],
+				"variant": "on"
+			},
+			{"variant": "off"}
+		]
+	},
+	"flag-b": {
+		"key": "flag-b",
+		"segments": [
+			{
+				"conditions": [[
+					{"selector": ["context", "user", "cohort_ids"], "op": "set contains any", "values": ["cohort-2"]},
+					{"selector": ["context", "user", "user_id"], "op": "is", "values": ["user-1"]}
+				]],
+				"variant": "on"
+			}
+		]
+	},
+	"flag-c": {"key": "flag-c", "segments": [{"variant": "on"}]}
+}`
+
+func TestNewFlagConfigStats(t *testing.T) {
+	stats, err := newFlagConfigStats([]byte(testFlagConfigs))
+
+	require.NoError(t, err)
+	assert.Equal(t, FlagConfigStats{
+		Flags:                  3,
+		Cohorts:                2,
+		ApproximateConfigBytes: len(testFlagConfigs),
+	}, stats)
+
+	_, err = newFlagConfigStats([]byte("not json"))
+	assert.Error(t, err)
+}
+
+// TestSDKFlagConfigs pins the shape of the SDK's local evaluation client which sdkFlagConfigs relies on,
+// so that an SDK upgrade which changes it fails here rather than disabling FlagConfigStats.
+func TestSDKFlagConfigs(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/sdk/v2/flags", r.URL.Path)
+		_, _ = w.Write([]byte(`[{"key": "flag-a", "segments": [{"variant": "on"}]}]`))
+	}))
+	defer server.Close()
+	client := local.Initialize(uniqueDeploymentKey(t), &local.Config{ServerUrl: server.URL})
+
+	t.Run("before the flag configs are fetched", func(t *testing.T) {
+		flagsJSON, err := sdkFlagConfigs(client)
+
+		require.NoError(t, err)
+		assert.JSONEq(t, "{}", string(flagsJSON))
+	})
+
+	t.Run("after the flag configs are fetched", func(t *testing.T) {
+		require.NoError(t, client.Start())
+
+		flagsJSON, err := sdkFlagConfigs(client)
+
+		require.NoError(t, err)
+		stats, err := newFlagConfigStats(flagsJSON)
+		require.NoError(t, err)
+		assert.Equal(t, 1, stats.Flags)
+	})
+}
+
+func TestProvider_FlagConfigStats(t *testing.T) {
+	t.Run("local evaluation", func(t *testing.T) {
+		provider := &Provider{client: &clientAdapterLocal{client: &mockLocalEvaluator{flagsJSON: testFlagConfigs}}}
+
+		stats := provider.FlagConfigStats()
+
+		assert.Equal(t, 3, stats.Flags)
+		assert.Equal(t, 2, stats.Cohorts)
+		assert.Positive(t, stats.ApproximateConfigBytes)
+	})
+
+	t.Run("errors are logged", func(t *testing.T) {
+		loggerProvider := &recordingLoggerProvider{}
+		provider := &Provider{
+			client: &clientAdapterLocal{client: &mockLocalEvaluator{flagsErr: errors.New("storage unavailable")}},
+			logger: logger.New(logger.Error, loggerProvider),
+		}
+
+		assert.Equal(t, FlagConfigStats{}, provider.FlagConfigStats())
+		require.Len(t, loggerProvider.errors, 1)
+		assert.Contains(t, loggerProvider.errors[0], "storage unavailable")
+	})
+
+	t.Run("remote evaluation returns zero values", func(t *testing.T) {
+		provider := &Provider{client: &clientAdapterRemote{}}
+
+		assert.Equal(t, FlagConfigStats{}, provider.FlagConfigStats())
+	})
+
+	t.Run("other client adapters return zero values", func(t *testing.T) {
+		provider := newTestProvider(t, &mockClientAdapter{})
+		require.Equal(t, of.ReadyState, provider.Status())
+
+		assert.Equal(t, FlagConfigStats{}, provider.FlagConfigStats())
+	})
+}
@@ -0,0 +1,105 @@
+package amplitude
+
+import (
+	"reflect"
+	"slices"
+	"sync"
+	"time"
+
+	experiment "github.com/amplitude/experiment-go-server/pkg/experiment"
+)
+
+// flagConfigWatcher periodically compares the metadata of flag configs
+// (which includes the flag version) against a snapshot, and reports the keys
+// of flags whose config changed.
+// Only flags which have been evaluated are watched, because the local evaluation
+// client does not expose a way to list its flag configs.
+type flagConfigWatcher struct {
+	flagMetadata func(flagKey string) map[string]any
+	callback     func(changed []string)
+	interval     time.Duration
+
+	mu       sync.Mutex
+	snapshot map[string]map[string]any
+
+	// runMu guards done, which is closed to stop the goroutine started by start,
+	// and is nil while the watcher isn't running.
+	runMu sync.Mutex
+	done  chan struct{}
+}
+
+// newFlagConfigWatcher creates a new flagConfigWatcher.
+// It does nothing until start is called.
+func newFlagConfigWatcher(flagMetadata func(flagKey string) map[string]any, callback func(changed []string), interval time.Duration) *flagConfigWatcher {
+	return &flagConfigWatcher{
+		flagMetadata: flagMetadata,
+		callback:     callback,
+		interval:     interval,
+		snapshot:     make(map[string]map[string]any),
+	}
+}
+
+// observe adds any flags in the evaluation results which are not yet watched to the snapshot.
+func (w *flagConfigWatcher) observe(variants map[string]experiment.Variant) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for flagKey := range variants {
+		if _, ok := w.snapshot[flagKey]; !ok {
+			w.snapshot[flagKey] = w.flagMetadata(flagKey)
+		}
+	}
+}
+
+// check compares the current flag configs against the snapshot,
+// and invokes the callback with the keys of any flags which changed.
+func (w *flagConfigWatcher) check() {
+	w.mu.Lock()
+	var changed []string
+	for flagKey, previous := range w.snapshot {
+		current := w.flagMetadata(flagKey)
+		if !reflect.DeepEqual(previous, current) {
+			changed = append(changed, flagKey)
+			w.snapshot[flagKey] = current
+		}
+	}
+	w.mu.Unlock()
+
+	if len(changed) > 0 {
+		slices.Sort(changed)
+		w.callback(changed)
+	}
+}
+
+// start starts checking for changes in the background.
+// Starting a running watcher does nothing, and a stopped watcher can be started again.
+func (w *flagConfigWatcher) start() {
+	w.runMu.Lock()
+	defer w.runMu.Unlock()
+	if w.done != nil {
+		return
+	}
+	done := make(chan struct{})
+	w.done = done
+	go func() {
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				w.check()
+			}
+		}
+	}()
+}
+
+// stop stops checking for changes. Stopping a watcher which isn't running does nothing.
+func (w *flagConfigWatcher) stop() {
+	w.runMu.Lock()
+	defer w.runMu.Unlock()
+	if w.done != nil {
+		close(w.done)
+		w.done = nil
+	}
+}
@@ -0,0 +1,176 @@
+package amplitude
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	experiment "github.com/amplitude/experiment-go-server/pkg/experiment"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockLocalEvaluator is a mock implementation of localEvaluator for testing.
+type mockLocalEvaluator struct {
+	mu       sync.Mutex
+	variants map[string]experiment.Variant
+	metadata map[string]map[string]any
+	// flagsJSON and flagsErr are returned by flagConfigsJSON.
+	flagsJSON string
+	flagsErr  error
+	// starts counts calls to Start, which returns startErr.
+	starts   int
+	startErr error
+}
+
+func (m *mockLocalEvaluator) Start() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.starts++
+	return m.startErr
+}
+
+func (m *mockLocalEvaluator) EvaluateV2(_ *experiment.User, _ []string) (map[string]experiment.Variant, error) {
+	return m.variants, nil
+}
+
+func (m *mockLocalEvaluator) FlagMetadata(flagKey string) map[string]any {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.metadata[flagKey]
+}
+
+func (m *mockLocalEvaluator) flagConfigsJSON() ([]byte, error) {
+	return []byte(m.flagsJSON), m.flagsErr
+}
+
+func (m *mockLocalEvaluator) setMetadata(flagKey string, metadata map[string]any) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if metadata == nil {
+		delete(m.metadata, flagKey)
+		return
+	}
+	m.metadata[flagKey] = metadata
+}
+
+func TestFlagConfigWatcher_Check(t *testing.T) {
+	evaluator := &mockLocalEvaluator{
+		variants: map[string]experiment.Variant{
+			"flag-a": {Key: "on"},
+			"flag-b": {Key: "on"},
+		},
+		metadata: map[string]map[string]any{
+			"flag-a": {"flagVersion": 1},
+			"flag-b": {"flagVersion": 1},
+			"flag-c": {"flagVersion": 1},
+		},
+	}
+	var calls [][]string
+	watcher := newFlagConfigWatcher(evaluator.FlagMetadata, func(changed []string) {
+		calls = append(calls, changed)
+	}, time.Hour)
+	adapter := &clientAdapterLocal{client: evaluator, watcher: watcher}
+
+	_, err := adapter.Evaluate(context.Background(), &experiment.User{}, nil)
+	require.NoError(t, err)
+
+	t.Run("no changes", func(t *testing.T) {
+		watcher.check()
+		assert.Empty(t, calls)
+	})
+
+	t.Run("changed and deleted flags are reported", func(t *testing.T) {
+		calls = nil
+		evaluator.setMetadata("flag-b", map[string]any{"flagVersion": 2})
+		evaluator.setMetadata("flag-a", nil)
+		watcher.check()
+		assert.Equal(t, [][]string{{"flag-a", "flag-b"}}, calls)
+	})
+
+	t.Run("changes are reported once", func(t *testing.T) {
+		calls = nil
+		watcher.check()
+		assert.Empty(t, calls)
+	})
+
+	t.Run("flags which were not evaluated are not reported", func(t *testing.T) {
+		calls = nil
+		evaluator.setMetadata("flag-c", map[string]any{"flagVersion": 2})
+		watcher.check()
+		assert.Empty(t, calls)
+	})
+}
+
+func TestClientAdapterLocal_FlagConfigWatcher(t *testing.T) {
+	evaluator := &mockLocalEvaluator{
+		variants: map[string]experiment.Variant{"flag-a": {Key: "on"}},
+		metadata: map[string]map[string]any{"flag-a": {"flagVersion": 1}},
+	}
+	changes := make(chan []string, 1)
+	adapter := &clientAdapterLocal{
+		client: evaluator,
+		watcher: newFlagConfigWatcher(evaluator.FlagMetadata, func(changed []string) {
+			changes <- changed
+		}, 10*time.Millisecond),
+	}
+
+	require.NoError(t, adapter.Start())
+	_, err := adapter.Evaluate(context.Background(), &experiment.User{}, []string{"flag-a"})
+	require.NoError(t, err)
+	evaluator.setMetadata("flag-a", map[string]any{"flagVersion": 2})
+
+	select {
+	case changed := <-changes:
+		assert.Equal(t, []string{"flag-a"}, changed)
+	case <-time.After(time.Second):
+		t.Fatal("expected flag config change callback")
+	}
+
+	require.NoError(t, adapter.Stop())
+	// Stopping again is safe.
+	require.NoError(t, adapter.Stop())
+}
+
+func TestClientAdapterLocal_FlagConfigWatcher_Restart(t *testing.T) {
+	evaluator := &mockLocalEvaluator{
+		variants: map[string]experiment.Variant{"flag-a": {Key: "on"}},
+		metadata: map[string]map[string]any{"flag-a": {"flagVersion": 1}},
+	}
+	changes := make(chan []string, 1)
+	adapter := &clientAdapterLocal{
+		client: evaluator,
+		watcher: newFlagConfigWatcher(evaluator.FlagMetadata, func(changed []string) {
+			changes <- changed
+		}, 10*time.Millisecond),
+	}
+	_, err := adapter.Evaluate(context.Background(), &experiment.User{}, []string{"flag-a"})
+	require.NoError(t, err)
+
+	require.NoError(t, adapter.Start())
+	require.NoError(t, adapter.Stop())
+	require.NoError(t, adapter.Start())
+	// Starting a running watcher doesn't start a second goroutine.
+	require.NoError(t, adapter.Start())
+	defer adapter.Stop()
+
+	evaluator.setMetadata("flag-a", map[string]any{"flagVersion": 2})
+	select {
+	case changed := <-changes:
+		assert.Equal(t, []string{"flag-a"}, changed)
+	case <-time.After(time.Second):
+		t.Fatal("expected the restarted watcher to report the change")
+	}
+}
+
+func TestConfig_getLocalConfig_FlagConfigChangeCallback(t *testing.T) {
+	called := false
+	cfg := &Config{}
+	WithFlagConfigChangeCallback(func([]string) { called = true })(cfg)
+
+	localCfg := cfg.getLocalConfig()
+	require.NotNil(t, localCfg.FlagConfigChangeCallback)
+	localCfg.FlagConfigChangeCallback(nil)
+	assert.True(t, called)
+}
@@ -0,0 +1,65 @@
+package amplitude
+
+import (
+	"context"
+	"errors"
+	"maps"
+
+	of "github.com/open-feature/go-sdk/openfeature"
+)
+
+// ErrFlagStatusUnavailable is returned by [Provider.FlagStatus] with remote evaluation,
+// where the provider has no flag configs to inspect.
+var ErrFlagStatusUnavailable = errors.New("flag status is only available with local evaluation")
+
+// FlagStatus describes whether a flag reaches this deployment. See [Provider.FlagStatus].
+type FlagStatus struct {
+	// Known is true if the flag's config is in the flag configs downloaded for this deployment.
+	// Amplitude doesn't serve configs for flags which aren't active in the deployment,
+	// so a flag which isn't known is typically missing, archived, or assigned to another deployment.
+	Known bool
+	// Deployed is true if the flag config reports that the flag is deployed.
+	// It is false if the flag isn't known, or its config doesn't say.
+	Deployed bool
+	// Version is the version of the flag config, or zero if it isn't reported.
+	Version int64
+	// Metadata is a copy of the metadata of the flag config, for details beyond the fields above.
+	Metadata map[string]any
+}
+
+// flagStatusProvider is implemented by client adapters which can describe the status of a flag.
+type flagStatusProvider interface {
+	flagStatus(flag string) FlagStatus
+}
+
+// flagStatus returns the status of the flag from the flag configs of the local evaluation client.
+// This reads the resident flag configs, so it doesn't make a request.
+func (c *clientAdapterLocal) flagStatus(flag string) FlagStatus {
+	metadata := c.client.FlagMetadata(flag)
+	if metadata == nil {
+		return FlagStatus{}
+	}
+	status := FlagStatus{
+		Known:    true,
+		Metadata: maps.Clone(metadata),
+	}
+	status.Deployed, _ = metadata["deployed"].(bool)
+	status.Version, _ = flagVersion(metadata["flagVersion"])
+	return status
+}
+
+// FlagStatus reports whether a flag is known to this deployment and deployed,
+// to answer "is my flag even reaching this environment?" without the Amplitude console.
+// It reads the flag configs held for local evaluation, so it doesn't contact Amplitude.
+// It returns [ErrFlagStatusUnavailable] for remote evaluation,
+// and an error if the provider hasn't been initialized.
+func (p *Provider) FlagStatus(_ context.Context, flag string) (FlagStatus, error) {
+	if p.state != of.ReadyState {
+		return FlagStatus{}, errors.New(providerNotReady)
+	}
+	statusProvider, ok := p.client.(flagStatusProvider)
+	if !ok {
+		return FlagStatus{}, ErrFlagStatusUnavailable
+	}
+	return statusProvider.flagStatus(flag), nil
+}
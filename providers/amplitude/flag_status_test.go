@@ -0,0 +1,54 @@
+package amplitude
+
+import (
+	"context"
+	"testing"
+
+	of "github.com/open-feature/go-sdk/openfeature"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProvider_FlagStatus(t *testing.T) {
+	t.Run("local evaluation", func(t *testing.T) {
+		evaluator := &mockLocalEvaluator{metadata: map[string]map[string]any{
+			"deployed-flag":   {"deployed": true, "flagVersion": float64(12), "flagType": "release"},
+			"undeployed-flag": {"deployed": false},
+		}}
+		provider := &Provider{client: &clientAdapterLocal{client: evaluator}, state: of.ReadyState}
+
+		deployed, err := provider.FlagStatus(context.Background(), "deployed-flag")
+		require.NoError(t, err)
+		assert.Equal(t, FlagStatus{
+			Known:    true,
+			Deployed: true,
+			Version:  12,
+			Metadata: map[string]any{"deployed": true, "flagVersion": float64(12), "flagType": "release"},
+		}, deployed)
+
+		undeployed, err := provider.FlagStatus(context.Background(), "undeployed-flag")
+		require.NoError(t, err)
+		assert.True(t, undeployed.Known)
+		assert.False(t, undeployed.Deployed)
+
+		unknown, err := provider.FlagStatus(context.Background(), "missing-flag")
+		require.NoError(t, err)
+		assert.Equal(t, FlagStatus{}, unknown)
+	})
+
+	t.Run("remote evaluation", func(t *testing.T) {
+		provider := &Provider{client: &mockClientAdapter{}, state: of.ReadyState}
+
+		_, err := provider.FlagStatus(context.Background(), "any-flag")
+
+		assert.ErrorIs(t, err, ErrFlagStatusUnavailable)
+	})
+
+	t.Run("not ready", func(t *testing.T) {
+		provider := &Provider{client: &clientAdapterLocal{client: &mockLocalEvaluator{}}, state: of.NotReadyState}
+
+		_, err := provider.FlagStatus(context.Background(), "any-flag")
+
+		assert.ErrorContains(t, err, providerNotReady)
+	})
+}
@@ -5,6 +5,17 @@ go 1.24.0
 require (
 	github.com/open-feature/go-sdk v1.17.0
 	github.com/stretchr/testify v1.11.1
+	go.opentelemetry.io/otel v1.38.0
+	go.opentelemetry.io/otel/metric v1.38.0
+	go.opentelemetry.io/otel/sdk/metric v1.38.0
+)
+
+require (
+	github.com/go-logr/stdr v1.2.2 // indirect
+	go.opentelemetry.io/auto/sdk v1.1.0 // indirect
+	go.opentelemetry.io/otel/sdk v1.38.0 // indirect
+	go.opentelemetry.io/otel/trace v1.38.0 // indirect
+	golang.org/x/sys v0.35.0 // indirect
 )
 
 require (
@@ -13,11 +24,11 @@ require (
 )
 
 require (
-	github.com/amplitude/analytics-go v1.2.0 // indirect
+	github.com/amplitude/analytics-go v1.2.0
 	github.com/amplitude/experiment-go-server v1.9.0
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/go-logr/logr v1.4.3 // indirect
-	github.com/google/uuid v1.3.0 // indirect
+	github.com/google/uuid v1.6.0 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	github.com/r3labs/sse/v2 v2.10.0 // indirect
 	github.com/spaolacci/murmur3 v1.1.0 // indirect
@@ -0,0 +1,67 @@
+package amplitude
+
+import "slices"
+
+// Group identifies a group the user belongs to, such as the "acme" group of type "org".
+type Group struct {
+	// Type is the group type, e.g. "org".
+	Type string
+	// Name is the group name, e.g. "acme".
+	Name string
+}
+
+// Groups returns the groups the user belongs to, in the form expected under [KeyGroups]
+// in the evaluation context: the group names keyed by group type, without duplicates.
+//
+//	evalCtx := openfeature.NewEvaluationContext("user-123", map[string]any{
+//	    string(amplitude.KeyGroups): amplitude.Groups(
+//	        amplitude.Group{Type: "org", Name: "acme"},
+//	        amplitude.Group{Type: "team", Name: "platform"},
+//	    ),
+//	})
+func Groups(groups ...Group) map[string][]string {
+	result := make(map[string][]string)
+	for _, group := range groups {
+		if !slices.Contains(result[group.Type], group.Name) {
+			result[group.Type] = append(result[group.Type], group.Name)
+		}
+	}
+	return result
+}
+
+// GroupCohort lists cohorts which a group belongs to.
+type GroupCohort struct {
+	Group
+	// CohortIDs are the IDs of the cohorts the group belongs to.
+	CohortIDs []string
+}
+
+// GroupCohorts returns the cohorts which groups belong to, in the form expected under
+// [KeyGroupCohortIDSet] in the evaluation context: sets of cohort IDs keyed by group type and name.
+// The cohorts of a group given more than once are merged.
+//
+//	evalCtx := openfeature.NewEvaluationContext("user-123", map[string]any{
+//	    string(amplitude.KeyGroupCohortIDSet): amplitude.GroupCohorts(amplitude.GroupCohort{
+//	        Group:     amplitude.Group{Type: "org", Name: "acme"},
+//	        CohortIDs: []string{"cohort-1", "cohort-2"},
+//	    }),
+//	})
+func GroupCohorts(cohorts ...GroupCohort) map[string]map[string]map[string]struct{} {
+	result := make(map[string]map[string]map[string]struct{})
+	for _, cohort := range cohorts {
+		names := result[cohort.Type]
+		if names == nil {
+			names = make(map[string]map[string]struct{})
+			result[cohort.Type] = names
+		}
+		ids := names[cohort.Name]
+		if ids == nil {
+			ids = make(map[string]struct{}, len(cohort.CohortIDs))
+			names[cohort.Name] = ids
+		}
+		for _, id := range cohort.CohortIDs {
+			ids[id] = struct{}{}
+		}
+	}
+	return result
+}
@@ -0,0 +1,55 @@
+package amplitude
+
+import (
+	"context"
+	"testing"
+
+	of "github.com/open-feature/go-sdk/openfeature"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGroups(t *testing.T) {
+	groups := Groups(
+		Group{Type: "org", Name: "acme"},
+		Group{Type: "team", Name: "platform"},
+		Group{Type: "org", Name: "globex"},
+		Group{Type: "org", Name: "acme"},
+	)
+
+	assert.Equal(t, map[string][]string{
+		"org":  {"acme", "globex"},
+		"team": {"platform"},
+	}, groups)
+}
+
+func TestGroupCohorts(t *testing.T) {
+	acme := Group{Type: "org", Name: "acme"}
+	cohorts := GroupCohorts(
+		GroupCohort{Group: acme, CohortIDs: []string{"cohort-1"}},
+		GroupCohort{Group: Group{Type: "team", Name: "platform"}, CohortIDs: []string{"cohort-3"}},
+		GroupCohort{Group: acme, CohortIDs: []string{"cohort-2", "cohort-1"}},
+	)
+
+	assert.Equal(t, map[string]map[string]map[string]struct{}{
+		"org":  {"acme": {"cohort-1": {}, "cohort-2": {}}},
+		"team": {"platform": {"cohort-3": {}}},
+	}, cohorts)
+}
+
+func TestGroups_AmplitudeUser(t *testing.T) {
+	provider := newTestProvider(t, &mockClientAdapter{})
+	groups := Groups(Group{Type: "org", Name: "acme"}, Group{Type: "org", Name: "globex"})
+	cohorts := GroupCohorts(GroupCohort{Group: Group{Type: "org", Name: "acme"}, CohortIDs: []string{"cohort-1"}})
+
+	user, err := provider.BuildUser(context.Background(), of.FlattenedContext{
+		of.TargetingKey:             "user-123",
+		string(KeyGroups):           groups,
+		string(KeyGroupCohortIDSet): cohorts,
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, groups, user.Groups)
+	assert.Equal(t, cohorts, user.GroupCohortIds)
+	assert.Empty(t, user.UserProperties)
+}
@@ -0,0 +1,73 @@
+package amplitude
+
+import (
+	"sync"
+	"time"
+)
+
+// maxLastErrors bounds the number of flags whose last evaluation error is remembered,
+// since failing evaluations of flags which don't exist could otherwise grow it without bound.
+const maxLastErrors = 1_000
+
+// lastErrors remembers the last evaluation error of each flag, and when it occurred.
+// Its zero value is ready to use.
+type lastErrors struct {
+	mu      sync.Mutex
+	entries map[string]lastError
+}
+
+// lastError is an evaluation error and when it occurred.
+type lastError struct {
+	err error
+	at  time.Time
+}
+
+// record records err as the last error of the flag, if it isn't nil.
+// When the errors of maxLastErrors flags are remembered, the oldest is forgotten.
+func (l *lastErrors) record(flag string, err error, at time.Time) {
+	if err == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.entries == nil {
+		l.entries = make(map[string]lastError)
+	}
+	if _, ok := l.entries[flag]; !ok && len(l.entries) >= maxLastErrors {
+		// The empty flag key is recorded too (see [emptyFlagKey]), so it can't mean that none was picked.
+		var oldestFlag string
+		var oldest time.Time
+		picked := false
+		for f, e := range l.entries {
+			if !picked || e.at.Before(oldest) {
+				oldestFlag, oldest, picked = f, e.at, true
+			}
+		}
+		delete(l.entries, oldestFlag)
+	}
+	l.entries[flag] = lastError{err: err, at: at}
+}
+
+// get returns the last error of the flag and when it occurred, or nil and the zero time.
+func (l *lastErrors) get(flag string) (error, time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	entry := l.entries[flag]
+	return entry.err, entry.at
+}
+
+// recordLastError records the error of an evaluation of the flag, if any; see [Provider.LastError].
+func (p *Provider) recordLastError(flag string, err error) {
+	p.lastErrors.record(flag, err, time.Now())
+}
+
+// LastError returns the most recent error of the typed evaluation methods (such as
+// [Provider.BooleanEvaluation]) for the flag, and when it occurred, for diagnostics such as
+// showing which flags are currently misbehaving, e.g. because of a payload of the wrong type.
+// It returns nil and the zero time if no evaluation of the flag has failed.
+// A later successful evaluation doesn't clear the error; compare its time to decide
+// whether it is still relevant.
+// The errors of up to 1,000 flags are remembered; beyond that, the oldest are forgotten.
+func (p *Provider) LastError(flag string) (error, time.Time) {
+	return p.lastErrors.get(flag)
+}
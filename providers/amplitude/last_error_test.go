@@ -0,0 +1,86 @@
+package amplitude
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	experiment "github.com/amplitude/experiment-go-server/pkg/experiment"
+	of "github.com/open-feature/go-sdk/openfeature"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProvider_LastError(t *testing.T) {
+	mock := &mockClientAdapter{
+		EvaluateFunc: func(context.Context, *experiment.User, []string) (map[string]experiment.Variant, error) {
+			return map[string]experiment.Variant{"string-flag": makeVariant("on", "on", "enabled")}, nil
+		},
+	}
+	evalCtx := of.FlattenedContext{of.TargetingKey: "user-1"}
+
+	t.Run("type mismatches are recorded", func(t *testing.T) {
+		provider := newTestProvider(t, mock)
+		before := time.Now()
+
+		result := provider.IntEvaluation(context.Background(), "string-flag", 1, evalCtx)
+
+		require.Error(t, result.Error())
+		err, at := provider.LastError("string-flag")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), string(of.TypeMismatchCode))
+		assert.False(t, at.Before(before))
+	})
+
+	t.Run("evaluation errors are recorded", func(t *testing.T) {
+		provider := newTestProvider(t, mock)
+
+		provider.BooleanEvaluation(context.Background(), "missing-flag", false, evalCtx)
+		provider.ObjectEvaluation(context.Background(), "other-missing-flag", nil, evalCtx)
+
+		err, _ := provider.LastError("missing-flag")
+		assert.Contains(t, err.Error(), string(of.FlagNotFoundCode))
+		err, _ = provider.LastError("other-missing-flag")
+		assert.Contains(t, err.Error(), string(of.FlagNotFoundCode))
+	})
+
+	t.Run("successful evaluations don't record errors", func(t *testing.T) {
+		provider := newTestProvider(t, mock)
+
+		provider.StringEvaluation(context.Background(), "string-flag", "default", evalCtx)
+
+		err, at := provider.LastError("string-flag")
+		assert.NoError(t, err)
+		assert.True(t, at.IsZero())
+	})
+}
+
+func TestLastErrors_Bounded(t *testing.T) {
+	var errs lastErrors
+	start := time.Now()
+	for i := range maxLastErrors + 1 {
+		errs.record(fmt.Sprintf("flag-%d", i), assert.AnError, start.Add(time.Duration(i)*time.Second))
+	}
+
+	assert.Len(t, errs.entries, maxLastErrors)
+	err, _ := errs.get("flag-0")
+	assert.NoError(t, err, "the oldest error is forgotten")
+	err, _ = errs.get(fmt.Sprintf("flag-%d", maxLastErrors))
+	assert.ErrorIs(t, err, assert.AnError)
+}
+
+func TestLastErrors_BoundedEmptyFlagKey(t *testing.T) {
+	var errs lastErrors
+	start := time.Now()
+	errs.record("", assert.AnError, start)
+	for i := range maxLastErrors {
+		errs.record(fmt.Sprintf("flag-%d", i), assert.AnError, start.Add(time.Duration(i+1)*time.Second))
+	}
+
+	assert.Len(t, errs.entries, maxLastErrors)
+	err, _ := errs.get("")
+	assert.NoError(t, err, "the oldest error is forgotten, even for the empty flag key")
+	err, _ = errs.get("flag-0")
+	assert.ErrorIs(t, err, assert.AnError)
+}
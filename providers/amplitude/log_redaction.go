@@ -0,0 +1,124 @@
+package amplitude
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/amplitude/experiment-go-server/pkg/logger"
+)
+
+// redactedValue replaces sensitive values in log messages.
+const redactedValue = "[REDACTED]"
+
+// redactedKeys are the Amplitude fields whose values are redacted from log messages.
+var redactedKeys = []Key{KeyIP, KeyIDFA, KeyIDFV, KeyADID, KeyAndroidID}
+
+// userHeader is the header in which the remote evaluation client sends the base64-encoded user,
+// which the SDK logs with each fetch request.
+const userHeader = "X-Amp-Exp-User"
+
+var (
+	// redactedFieldPattern matches a redacted field and its value, as JSON ("ip":"1.2.3.4"),
+	// as printed by fmt for a struct (IP:1.2.3.4) or a map (ip:1.2.3.4), or as key=value.
+	// IP addresses are only redacted in such a field, as they can't be told apart from
+	// version numbers such as 1.2.3.4 elsewhere.
+	redactedFieldPattern = regexp.MustCompile(`(?i)(\b"?(?:` + redactedKeysPattern() + `)"?\s*[:=]\s*)("[^"]*"|[^\s,}\]]+)`)
+	// emailPattern matches email addresses anywhere in a log message, such as in user properties.
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+)
+
+// redactedKeysPattern returns a regular expression alternation of the redacted keys,
+// also matching Go field names such as AndroidID for android_id.
+func redactedKeysPattern() string {
+	alternatives := make([]string, len(redactedKeys))
+	for i, key := range redactedKeys {
+		alternatives[i] = strings.ReplaceAll(regexp.QuoteMeta(string(key)), "_", "_?")
+	}
+	return strings.Join(alternatives, "|")
+}
+
+// redact removes email addresses and the values of IP address and device identifier fields from a log message.
+func redact(message string) string {
+	message = redactedFieldPattern.ReplaceAllString(message, "${1}"+redactedValue)
+	return emailPattern.ReplaceAllString(message, redactedValue)
+}
+
+// redactArg returns a log message argument without the personal data that [redact] can't find
+// once it's formatted: the user encoded in the header of a fetch request logged by the SDK.
+func redactArg(arg any) any {
+	request, ok := arg.(*http.Request)
+	if !ok || request.Header.Get(userHeader) == "" {
+		return arg
+	}
+	request = request.Clone(request.Context())
+	request.Header.Set(userHeader, redactedValue)
+	return request
+}
+
+// redactingLoggerProvider is a [logger.LoggerProvider] which redacts personal data
+// from log messages (see [redact]) before passing them to another logger provider.
+type redactingLoggerProvider struct {
+	next logger.LoggerProvider
+}
+
+var _ logger.LoggerProvider = (*redactingLoggerProvider)(nil)
+
+// newRedactingLoggerProvider wraps the logger provider to redact personal data from log messages.
+func newRedactingLoggerProvider(next logger.LoggerProvider) *redactingLoggerProvider {
+	if redacting, ok := next.(*redactingLoggerProvider); ok {
+		return redacting
+	}
+	return &redactingLoggerProvider{next: next}
+}
+
+func (r *redactingLoggerProvider) Verbose(message string, args ...any) {
+	r.next.Verbose("%s", r.format(message, args))
+}
+
+func (r *redactingLoggerProvider) Debug(message string, args ...any) {
+	r.next.Debug("%s", r.format(message, args))
+}
+
+func (r *redactingLoggerProvider) Info(message string, args ...any) {
+	r.next.Info("%s", r.format(message, args))
+}
+
+func (r *redactingLoggerProvider) Warn(message string, args ...any) {
+	r.next.Warn("%s", r.format(message, args))
+}
+
+func (r *redactingLoggerProvider) Error(message string, args ...any) {
+	r.next.Error("%s", r.format(message, args))
+}
+
+// format formats and redacts a log message.
+// The SDK's [logger.Logger] passes its arguments to the provider as a single slice,
+// so a single []any argument is expanded.
+func (r *redactingLoggerProvider) format(message string, args []any) string {
+	if len(args) == 1 {
+		if wrapped, ok := args[0].([]any); ok {
+			args = wrapped
+		}
+	}
+	redacted := make([]any, len(args))
+	for i, arg := range args {
+		redacted[i] = redactArg(arg)
+	}
+	return redact(fmt.Sprintf(message, redacted...))
+}
+
+// getLoggerProvider returns the logger provider to use in place of the configured one.
+// If none is configured, the provider owns the logger, so it logs to the standard error
+// with redaction; a configured logger provider is only wrapped if [Config.LogRedaction] is set.
+func (c *Config) getLoggerProvider(provider logger.LoggerProvider) logger.LoggerProvider {
+	switch {
+	case provider == nil:
+		return newRedactingLoggerProvider(logger.NewDefault())
+	case c.LogRedaction:
+		return newRedactingLoggerProvider(provider)
+	default:
+		return provider
+	}
+}
@@ -0,0 +1,135 @@
+package amplitude
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/amplitude/experiment-go-server/pkg/experiment"
+	"github.com/amplitude/experiment-go-server/pkg/experiment/local"
+	"github.com/amplitude/experiment-go-server/pkg/experiment/remote"
+	"github.com/amplitude/experiment-go-server/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedact(t *testing.T) {
+	tests := []struct {
+		name     string
+		message  string
+		expected string
+	}{
+		{
+			name:     "IP address field in text",
+			message:  "request from ip=192.168.0.17 failed",
+			expected: "request from ip=[REDACTED] failed",
+		},
+		{
+			name:     "JSON fields",
+			message:  `{"user_id":"user-1","ip":"2001:db8::1","idfa":"ABCD-1234","platform":"iOS"}`,
+			expected: `{"user_id":"user-1","ip":[REDACTED],"idfa":[REDACTED],"platform":"iOS"}`,
+		},
+		{
+			name:     "struct fields",
+			message:  "event: {UserID:user-1 IP:10.0.0.1 IDFV:EFGH-5678 AndroidID:a1b2c3 ADID:gaid-1}",
+			expected: "event: {UserID:user-1 IP:[REDACTED] IDFV:[REDACTED] AndroidID:[REDACTED] ADID:[REDACTED]}",
+		},
+		{
+			name:     "email-like user properties",
+			message:  "user_properties: map[email:jane.doe@example.com plan:pro]",
+			expected: "user_properties: map[email:[REDACTED] plan:pro]",
+		},
+		{
+			name:     "nothing sensitive",
+			message:  "evaluated flag checkout-flow (version 1.2.3) in 5ms",
+			expected: "evaluated flag checkout-flow (version 1.2.3) in 5ms",
+		},
+		{
+			name:     "version number outside an IP field",
+			message:  "amplitude: using experiment-go-server 1.2.3.4 with IPv6 support for 2001:db8::1",
+			expected: "amplitude: using experiment-go-server 1.2.3.4 with IPv6 support for 2001:db8::1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, redact(tt.message))
+		})
+	}
+}
+
+func TestRedactingLoggerProvider(t *testing.T) {
+	recorder := &recordingLoggerProvider{}
+	log := logger.New(logger.Warn, newRedactingLoggerProvider(recorder))
+
+	log.Warn("amplitude: failed to evaluate flags for %v", map[string]any{"user_id": "user-1", "ip": "203.0.113.9"})
+
+	require.Len(t, recorder.warnings, 1)
+	assert.Equal(t, "amplitude: failed to evaluate flags for map[ip:[REDACTED] user_id:user-1]", recorder.warnings[0])
+}
+
+func TestRedactingLoggerProvider_SDKFetchRequest(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("{}"))
+	}))
+	defer server.Close()
+
+	recorder := &recordingLoggerProvider{}
+	client := remote.Initialize(uniqueDeploymentKey(t), &remote.Config{
+		Debug:          true,
+		LoggerProvider: newRedactingLoggerProvider(recorder),
+		ServerUrl:      server.URL,
+	})
+	user := &experiment.User{
+		UserId:         "user-1",
+		UserProperties: map[string]any{"email": "jane.doe@example.com", "ip": "203.0.113.9"},
+	}
+	userJSON, err := json.Marshal(user)
+	require.NoError(t, err)
+
+	_, err = client.FetchV2(user)
+	require.NoError(t, err)
+
+	var logged bool
+	for _, message := range recorder.debugs {
+		if strings.HasPrefix(message, "fetch request: ") {
+			logged = true
+			assert.Contains(t, message, userHeader+":["+redactedValue+"]")
+		}
+		assert.NotContains(t, message, base64.StdEncoding.EncodeToString(userJSON))
+		assert.NotContains(t, message, "jane.doe@example.com")
+		assert.NotContains(t, message, "203.0.113.9")
+	}
+	assert.True(t, logged, "the SDK no longer logs its fetch requests")
+}
+
+func TestConfig_getLoggerProvider(t *testing.T) {
+	t.Run("redacts by default without a logger provider", func(t *testing.T) {
+		config := Config{}
+
+		assert.IsType(t, &redactingLoggerProvider{}, config.getLocalConfig().LoggerProvider)
+		assert.IsType(t, &redactingLoggerProvider{}, config.getRemoteConfig().LoggerProvider)
+	})
+
+	t.Run("trusts a configured logger provider", func(t *testing.T) {
+		recorder := &recordingLoggerProvider{}
+		config := Config{LocalConfig: &local.Config{LoggerProvider: recorder}}
+
+		assert.Same(t, recorder, config.getLocalConfig().LoggerProvider)
+	})
+
+	t.Run("redacts a configured logger provider with WithLogRedaction", func(t *testing.T) {
+		recorder := &recordingLoggerProvider{}
+		config := Config{RemoteConfig: &remote.Config{LoggerProvider: recorder}}
+		WithLogRedaction()(&config)
+
+		loggerProvider := config.getRemoteConfig().LoggerProvider
+		loggerProvider.Error("failed to fetch variants for ip=%s", "198.51.100.4")
+
+		require.Len(t, recorder.errors, 1)
+		assert.Equal(t, "failed to fetch variants for ip=[REDACTED]", recorder.errors[0])
+	})
+}
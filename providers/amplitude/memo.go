@@ -0,0 +1,88 @@
+package amplitude
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/amplitude/experiment-go-server/pkg/experiment"
+)
+
+// memoContextKey is the context key for the evaluation memo.
+type memoContextKey struct{}
+
+// evaluationMemo holds the variants of all flags, evaluated once per Amplitude user.
+type evaluationMemo struct {
+	mu       sync.Mutex
+	variants map[string]map[string]experiment.Variant
+}
+
+// ContextWithEvaluationMemo returns a context which memoizes flag evaluations.
+// When a flag is evaluated with the returned context, all flags are evaluated for the user
+// (as with [Provider.EvaluateAll]) and remembered, so that evaluating further flags for the same
+// user with the same context doesn't evaluate again. This suits a request handler which checks
+// many flags for the same evaluation context: create the memo at the start of the request,
+// and the flag rules are only run once.
+//
+// Exposure events are still tracked for each flag which is evaluated.
+// Flag config changes are not seen by a memo once it has been populated,
+// so it should be scoped to a short-lived unit of work such as a request.
+// Concurrent first evaluations for the same user may each evaluate all flags.
+func ContextWithEvaluationMemo(ctx context.Context) context.Context {
+	return context.WithValue(ctx, memoContextKey{}, &evaluationMemo{})
+}
+
+// evaluateWithMemo evaluates the flag for the user, using the evaluation memo
+// in the context if there is one.
+func (p *Provider) evaluateWithMemo(ctx context.Context, user *experiment.User, flag string) (map[string]experiment.Variant, error) {
+	memo, ok := ctx.Value(memoContextKey{}).(*evaluationMemo)
+	if !ok {
+		return p.client.Evaluate(ctx, user, []string{flag})
+	}
+
+	userKey, err := memoUserKey(user)
+	if err != nil {
+		return nil, err
+	}
+
+	memo.mu.Lock()
+	variants, ok := memo.variants[userKey]
+	memo.mu.Unlock()
+	if ok {
+		return variants, nil
+	}
+
+	variants, err = p.client.Evaluate(ctx, user, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	memo.mu.Lock()
+	if memo.variants == nil {
+		memo.variants = make(map[string]map[string]experiment.Variant)
+	}
+	memo.variants[userKey] = variants
+	memo.mu.Unlock()
+	return variants, nil
+}
+
+// contextWithEvaluatedVariants returns a context with an evaluation memo which already holds
+// the given variants for the user, so that resolving those flags with it doesn't evaluate them again.
+func contextWithEvaluatedVariants(ctx context.Context, user *experiment.User, variants map[string]experiment.Variant) (context.Context, error) {
+	userKey, err := memoUserKey(user)
+	if err != nil {
+		return nil, err
+	}
+	memo := &evaluationMemo{variants: map[string]map[string]experiment.Variant{userKey: variants}}
+	return context.WithValue(ctx, memoContextKey{}, memo), nil
+}
+
+// memoUserKey returns the key of the user's variants in an evaluation memo.
+func memoUserKey(user *experiment.User) (string, error) {
+	userJSON, err := json.Marshal(user)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode user for evaluation memo: %w", err)
+	}
+	return string(userJSON), nil
+}
@@ -0,0 +1,94 @@
+package amplitude
+
+import (
+	"context"
+	"testing"
+
+	"github.com/amplitude/experiment-go-server/pkg/experiment"
+	of "github.com/open-feature/go-sdk/openfeature"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProvider_EvaluationMemo(t *testing.T) {
+	newMock := func() *mockClientAdapter {
+		return &mockClientAdapter{
+			EvaluateFunc: func(_ context.Context, _ *experiment.User, _ []string) (map[string]experiment.Variant, error) {
+				return map[string]experiment.Variant{
+					"flag-a": makeVariant("on", "on", true),
+					"flag-b": makeVariant("blue", "blue", "blue"),
+				}, nil
+			},
+		}
+	}
+	evalCtx := of.FlattenedContext{of.TargetingKey: "user-1", "country": "US"}
+
+	t.Run("without a memo each flag is evaluated separately", func(t *testing.T) {
+		mock := newMock()
+		provider := newTestProvider(t, mock)
+
+		provider.BooleanEvaluation(context.Background(), "flag-a", false, evalCtx)
+		provider.StringEvaluation(context.Background(), "flag-b", "", evalCtx)
+
+		require.Len(t, mock.evaluateCalls, 2)
+		assert.Equal(t, []string{"flag-a"}, mock.evaluateCalls[0].FlagKeys)
+		assert.Equal(t, []string{"flag-b"}, mock.evaluateCalls[1].FlagKeys)
+	})
+
+	t.Run("with a memo all flags are evaluated once", func(t *testing.T) {
+		mock := newMock()
+		provider := newTestProvider(t, mock)
+		ctx := ContextWithEvaluationMemo(context.Background())
+
+		boolResult := provider.BooleanEvaluation(ctx, "flag-a", false, evalCtx)
+		stringResult := provider.StringEvaluation(ctx, "flag-b", "", evalCtx)
+		missingResult := provider.BooleanEvaluation(ctx, "missing-flag", false, evalCtx)
+
+		require.Len(t, mock.evaluateCalls, 1)
+		assert.Nil(t, mock.evaluateCalls[0].FlagKeys)
+		assert.True(t, boolResult.Value)
+		assert.Equal(t, "blue", stringResult.Value)
+		assert.ErrorContains(t, missingResult.ResolutionError, string(of.FlagNotFoundCode))
+	})
+
+	t.Run("different users are evaluated separately", func(t *testing.T) {
+		mock := newMock()
+		provider := newTestProvider(t, mock)
+		ctx := ContextWithEvaluationMemo(context.Background())
+
+		provider.BooleanEvaluation(ctx, "flag-a", false, evalCtx)
+		provider.BooleanEvaluation(ctx, "flag-a", false, of.FlattenedContext{of.TargetingKey: "user-1", "country": "CA"})
+		provider.BooleanEvaluation(ctx, "flag-b", false, evalCtx)
+
+		require.Len(t, mock.evaluateCalls, 2)
+		assert.Equal(t, "US", mock.evaluateCalls[0].User.Country)
+		assert.Equal(t, "CA", mock.evaluateCalls[1].User.Country)
+	})
+
+	t.Run("errors are not memoized", func(t *testing.T) {
+		mock := &mockClientAdapter{
+			EvaluateFunc: func(context.Context, *experiment.User, []string) (map[string]experiment.Variant, error) {
+				return nil, errMockEvaluate
+			},
+		}
+		provider := newTestProvider(t, mock)
+		ctx := ContextWithEvaluationMemo(context.Background())
+
+		provider.BooleanEvaluation(ctx, "flag-a", false, evalCtx)
+		provider.BooleanEvaluation(ctx, "flag-a", false, evalCtx)
+
+		assert.Len(t, mock.evaluateCalls, 2)
+	})
+
+	t.Run("exposures are tracked for each flag", func(t *testing.T) {
+		provider, analyticsClient := newTrackingTestProvider(t, newMock())
+		ctx := ContextWithEvaluationMemo(context.Background())
+
+		provider.BooleanEvaluation(ctx, "flag-a", false, evalCtx)
+		provider.StringEvaluation(ctx, "flag-b", "", evalCtx)
+
+		require.Len(t, analyticsClient.events, 2)
+		assert.Equal(t, "flag-a", analyticsClient.events[0].EventProperties["flag_key"])
+		assert.Equal(t, "flag-b", analyticsClient.events[1].EventProperties["flag_key"])
+	})
+}
@@ -0,0 +1,33 @@
+package amplitude
+
+import (
+	"context"
+	"time"
+
+	of "github.com/open-feature/go-sdk/openfeature"
+)
+
+// Metrics receives measurements of the provider's activity, for export to a metrics system.
+// See [WithMetrics]; the otelmetrics module provides an OpenTelemetry implementation.
+// Implementations must be safe for concurrent use, and should return quickly
+// because they are called on the evaluation path.
+type Metrics interface {
+	// RecordEvaluation is called after each flag evaluation with the flag key,
+	// the reason for the decision and how long the evaluation took.
+	RecordEvaluation(ctx context.Context, flag string, reason of.Reason, duration time.Duration)
+	// RecordCacheLookup is called after each lookup in the remote evaluation cache
+	// (see [WithRemoteEvaluationCache]), with whether a usable result was found.
+	RecordCacheLookup(ctx context.Context, hit bool)
+}
+
+// decisionReason returns the reason for the decision made by an evaluation.
+func decisionReason(eval *flagEvaluation, resErr *resolutionError) of.Reason {
+	switch {
+	case resErr != nil:
+		return of.ErrorReason
+	case eval.variant == nil:
+		return eval.offReason
+	default:
+		return eval.reason
+	}
+}
@@ -0,0 +1,172 @@
+package amplitude
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/amplitude/experiment-go-server/pkg/experiment"
+	of "github.com/open-feature/go-sdk/openfeature"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordedEvaluation is an evaluation recorded by recordingMetrics.
+type recordedEvaluation struct {
+	flag     string
+	reason   of.Reason
+	duration time.Duration
+}
+
+// recordingMetrics is a Metrics which records measurements for testing.
+type recordingMetrics struct {
+	mu           sync.Mutex
+	evaluations  []recordedEvaluation
+	cacheLookups []bool
+}
+
+func (m *recordingMetrics) RecordEvaluation(_ context.Context, flag string, reason of.Reason, duration time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.evaluations = append(m.evaluations, recordedEvaluation{flag: flag, reason: reason, duration: duration})
+}
+
+func (m *recordingMetrics) RecordCacheLookup(_ context.Context, hit bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.cacheLookups = append(m.cacheLookups, hit)
+}
+
+func TestProvider_Metrics(t *testing.T) {
+	metrics := &recordingMetrics{}
+	mock := &mockClientAdapter{
+		EvaluateFunc: func(_ context.Context, _ *experiment.User, _ []string) (map[string]experiment.Variant, error) {
+			return map[string]experiment.Variant{
+				"on-flag":  makeVariant("on", "on", true),
+				"off-flag": {Key: "off"},
+			}, nil
+		},
+	}
+	provider := newTestProvider(t, mock,
+		WithMetrics(metrics),
+		WithReasonMapper(func(string, *experiment.Variant) of.Reason { return of.TargetingMatchReason }),
+	)
+	evalCtx := of.FlattenedContext{of.TargetingKey: "user-1"}
+
+	provider.BooleanEvaluation(context.Background(), "on-flag", false, evalCtx)
+	provider.BooleanEvaluation(context.Background(), "off-flag", false, evalCtx)
+	provider.BooleanEvaluation(context.Background(), "missing-flag", false, evalCtx)
+
+	require.Len(t, metrics.evaluations, 3)
+	assert.Equal(t, "on-flag", metrics.evaluations[0].flag)
+	assert.Equal(t, of.TargetingMatchReason, metrics.evaluations[0].reason)
+	assert.Equal(t, "off-flag", metrics.evaluations[1].flag)
+	assert.Equal(t, of.DefaultReason, metrics.evaluations[1].reason)
+	assert.Equal(t, "missing-flag", metrics.evaluations[2].flag)
+	assert.Equal(t, of.ErrorReason, metrics.evaluations[2].reason)
+	for _, evaluation := range metrics.evaluations {
+		assert.Positive(t, evaluation.duration)
+	}
+}
+
+func TestProvider_FallbackCallback(t *testing.T) {
+	type fallback struct {
+		flag string
+		err  error
+	}
+	newProvider := func(t *testing.T, evaluateErr error, options ...Option) (*Provider, *[]fallback) {
+		t.Helper()
+		var fallbacks []fallback
+		mock := &mockClientAdapter{
+			EvaluateFunc: func(context.Context, *experiment.User, []string) (map[string]experiment.Variant, error) {
+				if evaluateErr != nil {
+					return nil, evaluateErr
+				}
+				return map[string]experiment.Variant{
+					"off-flag":    {Key: "off"},
+					"string-flag": makeVariant("on", "on", "not-a-number"),
+				}, nil
+			},
+		}
+		options = append(options, WithFallbackCallback(func(flag string, err error) {
+			fallbacks = append(fallbacks, fallback{flag: flag, err: err})
+		}))
+		return newTestProvider(t, mock, options...), &fallbacks
+	}
+	evalCtx := of.FlattenedContext{of.TargetingKey: "user-1"}
+
+	t.Run("called on an evaluation error", func(t *testing.T) {
+		provider, fallbacks := newProvider(t, errMockEvaluate)
+
+		result := provider.BooleanEvaluation(context.Background(), "test-flag", true, evalCtx)
+
+		assert.True(t, result.Value)
+		require.Len(t, *fallbacks, 1)
+		assert.Equal(t, "test-flag", (*fallbacks)[0].flag)
+		assert.ErrorContains(t, (*fallbacks)[0].err, errMockEvaluate.Error())
+	})
+
+	t.Run("called on a payload of the wrong type", func(t *testing.T) {
+		provider, fallbacks := newProvider(t, nil)
+
+		intResult := provider.IntEvaluation(context.Background(), "string-flag", 7, evalCtx)
+		floatResult := provider.FloatEvaluation(context.Background(), "string-flag", 2.5, evalCtx)
+
+		assert.EqualValues(t, 7, intResult.Value)
+		assert.InDelta(t, 2.5, floatResult.Value, 0)
+		require.Len(t, *fallbacks, 2)
+		for _, fallback := range *fallbacks {
+			assert.Equal(t, "string-flag", fallback.flag)
+			assert.ErrorContains(t, fallback.err, string(of.TypeMismatchCode))
+		}
+	})
+
+	t.Run("not called for an off variant or a forced default", func(t *testing.T) {
+		provider, fallbacks := newProvider(t, nil, WithForcedDefaults("killed-flag"))
+
+		provider.BooleanEvaluation(context.Background(), "off-flag", true, evalCtx)
+		provider.BooleanEvaluation(context.Background(), "killed-flag", true, evalCtx)
+
+		assert.Empty(t, *fallbacks)
+	})
+
+	t.Run("not called when the fallback provider evaluates the flag", func(t *testing.T) {
+		provider, fallbacks := newProvider(t, nil, WithFallbackProvider(&of.NoopProvider{}))
+
+		provider.BooleanEvaluation(context.Background(), "missing-flag", true, evalCtx)
+
+		assert.Empty(t, *fallbacks)
+	})
+}
+
+func TestClientAdapterRemote_Metrics(t *testing.T) {
+	metrics := &recordingMetrics{}
+	evaluator := &mockRemoteEvaluator{
+		fetchFunc: func(*experiment.User) (map[string]experiment.Variant, error) {
+			return map[string]experiment.Variant{"flag-1": {Key: "on"}}, nil
+		},
+	}
+	client := &clientAdapterRemote{
+		evaluator: evaluator,
+		cache:     &syncCache{},
+		config:    remoteConfig{Metrics: metrics},
+	}
+
+	_, err := client.Evaluate(context.Background(), &experiment.User{UserId: "user-1"}, nil)
+	require.NoError(t, err)
+	_, err = client.Evaluate(context.Background(), &experiment.User{UserId: "user-1"}, nil)
+	require.NoError(t, err)
+	_, err = client.Evaluate(context.Background(), &experiment.User{UserId: "user-2"}, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, []bool{false, true, false}, metrics.cacheLookups)
+}
+
+func TestConfig_getRemoteConfig_Metrics(t *testing.T) {
+	metrics := &recordingMetrics{}
+	config := &Config{}
+	WithMetrics(metrics)(config)
+
+	assert.Same(t, metrics, config.getRemoteConfig().Metrics)
+}
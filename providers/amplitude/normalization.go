@@ -1,8 +1,12 @@
 package amplitude
 
 import (
+	"maps"
 	"regexp"
+	"slices"
 	"strings"
+	"sync"
+	"unicode"
 
 	of "github.com/open-feature/go-sdk/openfeature"
 )
@@ -255,7 +259,18 @@ var allKeys = append(append(userKeys, eventKeys...), sharedKeys...)
 // to the canonical keys used by Amplitude.
 // Any keys that are not mapped will be added to the [User.UserProperties] map.
 // For more advanced normalization, use a hook to pre-process the evaluation context.
+//
+// Each call returns a new copy, which the caller may modify.
 func DefaultKeyMap() map[string]Key {
+	return maps.Clone(sharedDefaultKeyMap())
+}
+
+// sharedDefaultKeyMap returns the default key map, which is built once and shared
+// by all providers without a custom key map. It must not be modified.
+var sharedDefaultKeyMap = sync.OnceValue(buildDefaultKeyMap)
+
+// buildDefaultKeyMap builds the default key map; see [DefaultKeyMap].
+func buildDefaultKeyMap() map[string]Key {
 	var keyMap = map[string]Key{}
 
 	// All canonical keys - permutations will be generated automatically
@@ -309,3 +324,38 @@ func makePermutations(value string) []string {
 }
 
 var reWordBreak = regexp.MustCompile(`[_^].`)
+
+// foldKey folds a context key for case-insensitive matching (see [WithCaseInsensitiveKeyMatching]):
+// it is lower-cased, and separators are removed, so that "Device_Id", "DEVICEiD" and "device-id" match.
+func foldKey(key string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '_', '-', '.', ' ':
+			return -1
+		}
+		return unicode.ToLower(r)
+	}, key)
+}
+
+// foldKeyMap returns the key map keyed by folded context keys, also matching the fields
+// which the key map maps to. Folded keys which would match different fields are left out,
+// since they are ambiguous.
+func foldKeyMap(keyMap map[string]Key) map[string]Key {
+	folded := make(map[string]Key, len(keyMap))
+	ambiguous := make(map[string]bool)
+	add := func(key string, field Key) {
+		foldedKey := foldKey(key)
+		if existing, ok := folded[foldedKey]; ok && existing != field {
+			ambiguous[foldedKey] = true
+		}
+		folded[foldedKey] = field
+	}
+	for _, key := range slices.Sorted(maps.Keys(keyMap)) {
+		add(key, keyMap[key])
+		add(string(keyMap[key]), keyMap[key])
+	}
+	for foldedKey := range ambiguous {
+		delete(folded, foldedKey)
+	}
+	return folded
+}
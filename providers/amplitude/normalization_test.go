@@ -9,6 +9,7 @@ import (
 
 	analytics "github.com/amplitude/analytics-go/amplitude"
 	"github.com/amplitude/experiment-go-server/pkg/experiment"
+	"github.com/amplitude/experiment-go-server/pkg/logger"
 	of "github.com/open-feature/go-sdk/openfeature"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -601,3 +602,468 @@ func TestKeyArraysMatchStructFields(t *testing.T) {
 		}
 	})
 }
+
+func TestToAmplitudeUser_TargetingKeyAs(t *testing.T) {
+	tests := []struct {
+		name             string
+		targetingKeyAs   Key
+		expectedUserID   string
+		expectedDeviceID string
+	}{
+		{
+			name:           "default maps targeting key to user ID",
+			expectedUserID: "key-123",
+		},
+		{
+			name:           "explicit user ID",
+			targetingKeyAs: KeyUserID,
+			expectedUserID: "key-123",
+		},
+		{
+			name:             "device ID",
+			targetingKeyAs:   KeyDeviceID,
+			expectedDeviceID: "key-123",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &Provider{config: Config{TargetingKeyField: tt.targetingKeyAs}}
+
+			user, err := provider.toAmplitudeUser(context.Background(), of.FlattenedContext{
+				of.TargetingKey: "key-123",
+			})
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.expectedUserID, user.UserId)
+			assert.Equal(t, tt.expectedDeviceID, user.DeviceId)
+		})
+	}
+}
+
+func TestToAmplitudeUser_TargetingKeyIgnored(t *testing.T) {
+	provider := &Provider{config: Config{TargetingKeyField: TargetingKeyIgnored}}
+
+	t.Run("identity comes from context attributes", func(t *testing.T) {
+		user, err := provider.toAmplitudeUser(context.Background(), of.FlattenedContext{
+			of.TargetingKey: "session-token",
+			"userId":        "user-1",
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, "user-1", user.UserId)
+		assert.Empty(t, user.DeviceId)
+		assert.NotContains(t, user.UserProperties, of.TargetingKey)
+	})
+
+	t.Run("the targeting key alone is not an identity", func(t *testing.T) {
+		_, err := provider.toAmplitudeUser(context.Background(), of.FlattenedContext{
+			of.TargetingKey: "session-token",
+		})
+
+		assert.Error(t, err)
+	})
+
+	t.Run("identity-only users", func(t *testing.T) {
+		user, err := provider.toIdentityUser(context.Background(), of.FlattenedContext{
+			of.TargetingKey: "session-token",
+			"deviceId":      "device-1",
+		})
+		require.NoError(t, err)
+
+		assert.Empty(t, user.UserId)
+		assert.Equal(t, "device-1", user.DeviceId)
+	})
+}
+
+func TestDefaultKeyMap_ReturnsCopy(t *testing.T) {
+	keyMap := DefaultKeyMap()
+	keyMap["user_id"] = KeyDeviceID
+	keyMap["custom_key"] = KeyCountry
+
+	fresh := DefaultKeyMap()
+	assert.Equal(t, KeyUserID, fresh["user_id"])
+	assert.NotContains(t, fresh, "custom_key")
+
+	// Providers without a custom key map use the shared default, which must be unaffected.
+	provider := &Provider{}
+	user, err := provider.toAmplitudeUser(context.Background(), of.FlattenedContext{"user_id": "user-1"})
+	require.NoError(t, err)
+	assert.Equal(t, "user-1", user.UserId)
+	assert.Equal(t, buildDefaultKeyMap(), sharedDefaultKeyMap())
+}
+
+func BenchmarkDefaultKeyMap(b *testing.B) {
+	b.Run("build", func(b *testing.B) {
+		for b.Loop() {
+			_ = buildDefaultKeyMap()
+		}
+	})
+
+	b.Run("copy", func(b *testing.B) {
+		for b.Loop() {
+			_ = DefaultKeyMap()
+		}
+	})
+
+	b.Run("provider default", func(b *testing.B) {
+		for b.Loop() {
+			_ = (&Config{}).getKeyMap()
+		}
+	})
+}
+
+func TestProvider_BuildUser(t *testing.T) {
+	keyMap := DefaultKeyMap()
+	keyMap["account"] = KeyUserID
+	provider, err := New(context.Background(), "test-key",
+		WithKeyMap(keyMap),
+		WithUserNormalizer(func(_ context.Context, normalization UserNormalizationContext) error {
+			normalization.User.Region = strings.ToUpper(normalization.User.Region)
+			return nil
+		}),
+	)
+	require.NoError(t, err)
+
+	// The provider hasn't been initialized, so this doesn't contact Amplitude.
+	user, err := provider.BuildUser(context.Background(), of.FlattenedContext{
+		"account": "account-1",
+		"region":  "emea",
+		"tier":    "gold",
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, "account-1", user.UserId)
+	assert.Equal(t, "EMEA", user.Region)
+	assert.Equal(t, map[string]any{"tier": "gold"}, user.UserProperties)
+}
+
+func TestProvider_StickyBucketing(t *testing.T) {
+	newProvider := func(t *testing.T) (*Provider, *mockClientAdapter) {
+		t.Helper()
+		mock := &mockClientAdapter{
+			EvaluateFunc: func(_ context.Context, _ *experiment.User, _ []string) (map[string]experiment.Variant, error) {
+				return map[string]experiment.Variant{"test-flag": makeVariant("treatment", "treatment", "treatment")}, nil
+			},
+		}
+		provider := newTestProvider(t, mock, WithStickyBucketing(KeyUserID, KeyDeviceID))
+		return provider, mock
+	}
+
+	t.Run("errors when a sticky key is absent", func(t *testing.T) {
+		provider, mock := newProvider(t)
+
+		result := provider.StringEvaluation(context.Background(), "test-flag", "default", of.FlattenedContext{
+			of.TargetingKey: "user-1",
+		})
+
+		assert.Equal(t, "default", result.Value)
+		assert.Equal(t, of.ErrorReason, result.Reason)
+		assert.ErrorContains(t, result.ResolutionError, string(of.InvalidContextCode))
+		assert.ErrorContains(t, result.ResolutionError, "sticky bucketing requires device_id")
+		assert.Empty(t, mock.evaluateCalls, "Amplitude should not be called without the sticky keys")
+
+		_, err := provider.EvaluateAll(context.Background(), of.FlattenedContext{"deviceId": "device-1"})
+		assert.ErrorContains(t, err, "sticky bucketing requires user_id")
+	})
+
+	t.Run("evaluates when the sticky keys are present", func(t *testing.T) {
+		provider, mock := newProvider(t)
+
+		result := provider.StringEvaluation(context.Background(), "test-flag", "default", of.FlattenedContext{
+			of.TargetingKey: "user-1",
+			"deviceId":      "device-1",
+		})
+
+		require.NoError(t, result.Error())
+		assert.Equal(t, "treatment", result.Value)
+		require.Len(t, mock.evaluateCalls, 1)
+		assert.Equal(t, "device-1", mock.evaluateCalls[0].User.DeviceId)
+	})
+}
+
+func TestToAmplitudeUser_TargetingKeyPrecedence(t *testing.T) {
+	newProvider := func(t *testing.T, options ...Option) (*Provider, *recordingLoggerProvider) {
+		t.Helper()
+		provider := newTestProvider(t, &mockClientAdapter{}, options...)
+		loggerProvider := &recordingLoggerProvider{}
+		provider.logger = logger.New(logger.Warn, loggerProvider)
+		return provider, loggerProvider
+	}
+
+	t.Run("the targeting key wins over a conflicting user_id", func(t *testing.T) {
+		provider, loggerProvider := newProvider(t)
+
+		// Map iteration order is random, so check the precedence holds repeatedly.
+		for range 20 {
+			user, err := provider.BuildUser(context.Background(), of.FlattenedContext{
+				of.TargetingKey: "user-a",
+				"user_id":       "user-b",
+				"userId":        "user-b",
+			})
+			require.NoError(t, err)
+			assert.Equal(t, "user-a", user.UserId)
+		}
+		require.NotEmpty(t, loggerProvider.warnings)
+		assert.Contains(t, loggerProvider.warnings[0], "user_id")
+		assert.Contains(t, loggerProvider.warnings[0], "differs from the targeting key")
+	})
+
+	t.Run("matching values are not a conflict", func(t *testing.T) {
+		provider, loggerProvider := newProvider(t)
+
+		user, err := provider.BuildUser(context.Background(), of.FlattenedContext{
+			of.TargetingKey: "user-a",
+			"user_id":       "user-a",
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, "user-a", user.UserId)
+		assert.Empty(t, loggerProvider.warnings)
+	})
+
+	t.Run("applies to the configured targeting key field", func(t *testing.T) {
+		provider, loggerProvider := newProvider(t, WithTargetingKeyAs(KeyDeviceID))
+
+		user, err := provider.BuildUser(context.Background(), of.FlattenedContext{
+			of.TargetingKey: "device-a",
+			"deviceId":      "device-b",
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, "device-a", user.DeviceId)
+		require.Len(t, loggerProvider.warnings, 1)
+		assert.Contains(t, loggerProvider.warnings[0], "device_id")
+	})
+}
+
+func TestToAmplitudeEvent_UserIDOnlyOnEventUserID(t *testing.T) {
+	newProvider := func(t *testing.T) (*Provider, *recordingLoggerProvider) {
+		t.Helper()
+		provider := newTestProvider(t, &mockClientAdapter{})
+		loggerProvider := &recordingLoggerProvider{}
+		provider.logger = logger.New(logger.Warn, loggerProvider)
+		return provider, loggerProvider
+	}
+	assertOnlyUserID := func(t *testing.T, event analytics.Event, expected string) {
+		t.Helper()
+		assert.Equal(t, expected, event.UserID)
+		assert.Empty(t, event.EventOptions.UserID)
+		assert.NotContains(t, event.EventProperties, "user_id")
+		assert.NotContains(t, event.EventProperties, "userId")
+		assert.NotContains(t, event.UserProperties["$set"], "user_id")
+	}
+
+	t.Run("targeting key and user_id attribute", func(t *testing.T) {
+		provider, _ := newProvider(t)
+
+		event, err := provider.toAmplitudeEvent(context.Background(), "purchase",
+			of.NewEvaluationContext("user-a", map[string]any{"user_id": "user-b", "userId": "user-b"}),
+			of.NewTrackingEventDetails(0))
+
+		require.NoError(t, err)
+		assertOnlyUserID(t, event, "user-a")
+	})
+
+	t.Run("user_id in the details doesn't override the targeting key", func(t *testing.T) {
+		provider, loggerProvider := newProvider(t)
+
+		event, err := provider.toAmplitudeEvent(context.Background(), "purchase",
+			of.NewEvaluationContext("user-a", nil),
+			of.NewTrackingEventDetails(0).Add("user_id", "user-b"))
+
+		require.NoError(t, err)
+		assertOnlyUserID(t, event, "user-a")
+		require.Len(t, loggerProvider.warnings, 1)
+		assert.Contains(t, loggerProvider.warnings[0], "differs from the targeting key")
+	})
+
+	t.Run("user_id attribute without a targeting key", func(t *testing.T) {
+		provider, loggerProvider := newProvider(t)
+
+		event, err := provider.toAmplitudeEvent(context.Background(), "purchase",
+			of.NewEvaluationContext("", map[string]any{"user_id": "user-b"}),
+			of.NewTrackingEventDetails(0))
+
+		require.NoError(t, err)
+		assertOnlyUserID(t, event, "user-b")
+		assert.Empty(t, loggerProvider.warnings)
+	})
+}
+
+type contextValueKey string
+
+func TestProvider_ContextValueExtractors(t *testing.T) {
+	extractor := func(key Key, ctxKey contextValueKey) ContextValueExtractor {
+		return func(ctx context.Context) (Key, any, bool) {
+			value, ok := ctx.Value(ctxKey).(string)
+			return key, value, ok
+		}
+	}
+	newProvider := func(t *testing.T) *Provider {
+		t.Helper()
+		return newTestProvider(t, &mockClientAdapter{},
+			WithContextValueExtractors(
+				extractor(KeyCountry, "country"),
+				extractor(KeyLanguage, "locale"),
+				extractor("tenant", "tenant"),
+			),
+		)
+	}
+	ctx := context.WithValue(context.Background(), contextValueKey("country"), "DE")
+	ctx = context.WithValue(ctx, contextValueKey("locale"), "de-DE")
+	ctx = context.WithValue(ctx, contextValueKey("tenant"), "acme")
+
+	t.Run("populates the user from the Go context", func(t *testing.T) {
+		provider := newProvider(t)
+
+		user, err := provider.BuildUser(ctx, of.FlattenedContext{of.TargetingKey: "user-123"})
+
+		require.NoError(t, err)
+		assert.Equal(t, "user-123", user.UserId)
+		assert.Equal(t, "DE", user.Country)
+		assert.Equal(t, "de-DE", user.Language)
+		assert.Equal(t, map[string]any{"tenant": "acme"}, user.UserProperties)
+	})
+
+	t.Run("the evaluation context takes precedence", func(t *testing.T) {
+		provider := newProvider(t)
+
+		user, err := provider.BuildUser(ctx, of.FlattenedContext{
+			of.TargetingKey: "user-123",
+			"country":       "FR",
+			"tenant":        "globex",
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, "FR", user.Country)
+		assert.Equal(t, "de-DE", user.Language)
+		assert.Equal(t, map[string]any{"tenant": "globex"}, user.UserProperties)
+	})
+
+	t.Run("ignores values missing from the Go context", func(t *testing.T) {
+		provider := newProvider(t)
+
+		user, err := provider.BuildUser(context.Background(), of.FlattenedContext{of.TargetingKey: "user-123"})
+
+		require.NoError(t, err)
+		assert.Empty(t, user.Country)
+		assert.Empty(t, user.UserProperties)
+	})
+
+	t.Run("records the merge in the flag metadata", func(t *testing.T) {
+		mock := &mockClientAdapter{
+			EvaluateFunc: func(_ context.Context, _ *experiment.User, flagKeys []string) (map[string]experiment.Variant, error) {
+				return map[string]experiment.Variant{flagKeys[0]: makeVariant("on", "on", nil)}, nil
+			},
+		}
+		provider := newTestProvider(t, mock, WithContextValueExtractors(extractor(KeyCountry, "country")))
+
+		merged := provider.BooleanEvaluation(ctx, "flag", false, of.FlattenedContext{of.TargetingKey: "user-123"})
+		overridden := provider.BooleanEvaluation(ctx, "flag", false, of.FlattenedContext{of.TargetingKey: "user-123", "country": "FR"})
+		absent := provider.BooleanEvaluation(context.Background(), "flag", false, of.FlattenedContext{of.TargetingKey: "user-123"})
+
+		assert.Equal(t, true, merged.FlagMetadata[MetadataKeyDefaultContextMerged])
+		assert.NotContains(t, overridden.FlagMetadata, MetadataKeyDefaultContextMerged)
+		assert.NotContains(t, absent.FlagMetadata, MetadataKeyDefaultContextMerged)
+		assert.NotContains(t, absent.FlagMetadata, MetadataKeyOverrideApplied)
+	})
+
+	t.Run("populates tracking events from the Go context", func(t *testing.T) {
+		provider := newProvider(t)
+
+		event, err := provider.toAmplitudeEvent(ctx, "purchase", of.NewEvaluationContext("user-123", map[string]any{"language": "en"}), of.NewTrackingEventDetails(0))
+
+		require.NoError(t, err)
+		assert.Equal(t, "user-123", event.UserID)
+		assert.Equal(t, "DE", event.Country)
+		assert.Equal(t, "en", event.Language)
+	})
+
+	t.Run("populates tracking events without a targeting key", func(t *testing.T) {
+		provider := newProvider(t)
+
+		event, err := provider.toAmplitudeEvent(ctx, "purchase", of.NewEvaluationContext("", map[string]any{"user_id": "user-456"}), of.NewTrackingEventDetails(0))
+
+		require.NoError(t, err)
+		assert.Equal(t, "user-456", event.UserID)
+		assert.Empty(t, event.EventOptions.UserID)
+		assert.Equal(t, "DE", event.Country)
+	})
+}
+
+func TestProvider_RequiredAttributes(t *testing.T) {
+	newProvider := func(t *testing.T) *Provider {
+		t.Helper()
+		mock := &mockClientAdapter{
+			EvaluateFunc: func(_ context.Context, _ *experiment.User, flagKeys []string) (map[string]experiment.Variant, error) {
+				return map[string]experiment.Variant{flagKeys[0]: makeVariant("treatment", "treatment", "treatment")}, nil
+			},
+		}
+		return newTestProvider(t, mock,
+			WithRequiredAttributes("country-flag", KeyCountry),
+			WithRequiredAttributes("country-flag", "tier"),
+		)
+	}
+
+	t.Run("evaluates when the attributes are present", func(t *testing.T) {
+		provider := newProvider(t)
+
+		result := provider.StringEvaluation(context.Background(), "country-flag", "default", of.FlattenedContext{
+			of.TargetingKey: "user-123",
+			"country":       "DE",
+			"tier":          "gold",
+		})
+
+		require.NoError(t, result.Error())
+		assert.Equal(t, "treatment", result.Value)
+	})
+
+	t.Run("fails when an attribute is missing", func(t *testing.T) {
+		provider := newProvider(t)
+
+		result := provider.StringEvaluation(context.Background(), "country-flag", "default", of.FlattenedContext{
+			of.TargetingKey: "user-123",
+			"tier":          "gold",
+		})
+
+		assert.Equal(t, "default", result.Value)
+		assert.Equal(t, of.ErrorReason, result.Reason)
+		assert.ErrorContains(t, result.Error(), string(of.InvalidContextCode))
+		assert.ErrorContains(t, result.Error(), "flag country-flag requires country, which the context is missing")
+	})
+
+	t.Run("names every missing attribute", func(t *testing.T) {
+		provider := newProvider(t)
+
+		result := provider.StringEvaluation(context.Background(), "country-flag", "default", of.FlattenedContext{
+			of.TargetingKey: "user-123",
+		})
+
+		assert.ErrorContains(t, result.Error(), "requires country, tier")
+	})
+
+	t.Run("other flags don't require the attributes", func(t *testing.T) {
+		provider := newProvider(t)
+
+		result := provider.StringEvaluation(context.Background(), "other-flag", "default", of.FlattenedContext{
+			of.TargetingKey: "user-123",
+		})
+
+		require.NoError(t, result.Error())
+		assert.Equal(t, "treatment", result.Value)
+	})
+}
+
+func TestFoldKeyMap(t *testing.T) {
+	folded := foldKeyMap(map[string]Key{
+		"deviceId": KeyDeviceID,
+		"user.id":  KeyUserID,
+		"userid":   KeyDeviceID,
+	})
+
+	assert.Equal(t, KeyDeviceID, folded[foldKey("Device_Id")])
+	assert.Equal(t, KeyDeviceID, folded[foldKey("DEVICE ID")])
+	// "user.id" and "userid" fold to the same key but map to different fields.
+	assert.NotContains(t, folded, "userid")
+}
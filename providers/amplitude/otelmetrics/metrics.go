@@ -0,0 +1,101 @@
+// Package otelmetrics records the activity of the Amplitude OpenFeature provider
+// as OpenTelemetry metrics.
+//
+// It is a separate package so that the amplitude package itself doesn't import OpenTelemetry.
+// Pass the metrics to the provider with [amplitude.WithMetrics]:
+//
+//	metrics, err := otelmetrics.NewForMeterProvider(meterProvider)
+//	if err != nil {
+//	    return err
+//	}
+//	provider, err := amplitude.New(ctx, "deployment-key", amplitude.WithMetrics(metrics))
+//
+// The following instruments are recorded:
+//
+//   - feature_flag.amplitude.evaluation_count: a counter of flag evaluations,
+//     with the feature_flag.key and feature_flag.result.reason attributes
+//   - feature_flag.amplitude.evaluation_duration: a histogram of evaluation durations in seconds,
+//     with the same attributes
+//   - feature_flag.amplitude.cache_lookup_count: a counter of remote evaluation cache lookups,
+//     with a boolean "hit" attribute from which the hit ratio can be derived
+package otelmetrics
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/open-feature/go-sdk-contrib/providers/amplitude"
+	of "github.com/open-feature/go-sdk/openfeature"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+)
+
+// ScopeName is the instrumentation scope name of the meter.
+const ScopeName = "github.com/open-feature/go-sdk-contrib/providers/amplitude/otelmetrics"
+
+const (
+	evaluationCount    = "feature_flag.amplitude.evaluation_count"
+	evaluationDuration = "feature_flag.amplitude.evaluation_duration"
+	cacheLookupCount   = "feature_flag.amplitude.cache_lookup_count"
+)
+
+// Metrics is an [amplitude.Metrics] which records OpenTelemetry instruments.
+type Metrics struct {
+	evaluationCounter  metric.Int64Counter
+	durationHistogram  metric.Float64Histogram
+	cacheLookupCounter metric.Int64Counter
+}
+
+var _ amplitude.Metrics = &Metrics{}
+
+// New builds metrics backed by the globally set [metric.MeterProvider].
+// Use [otel.SetMeterProvider] to set the global provider or use [NewForMeterProvider].
+func New() (*Metrics, error) {
+	return NewForMeterProvider(otel.GetMeterProvider())
+}
+
+// NewForMeterProvider builds metrics backed by the given [metric.MeterProvider].
+func NewForMeterProvider(provider metric.MeterProvider) (*Metrics, error) {
+	meter := provider.Meter(ScopeName)
+
+	evaluationCounter, err := meter.Int64Counter(evaluationCount, metric.WithDescription("Amplitude flag evaluation counter"))
+	if err != nil {
+		return nil, err
+	}
+
+	durationHistogram, err := meter.Float64Histogram(evaluationDuration,
+		metric.WithDescription("Amplitude flag evaluation duration"),
+		metric.WithUnit("s"))
+	if err != nil {
+		return nil, err
+	}
+
+	cacheLookupCounter, err := meter.Int64Counter(cacheLookupCount, metric.WithDescription("Amplitude remote evaluation cache lookup counter"))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Metrics{
+		evaluationCounter:  evaluationCounter,
+		durationHistogram:  durationHistogram,
+		cacheLookupCounter: cacheLookupCounter,
+	}, nil
+}
+
+// RecordEvaluation implements [amplitude.Metrics].
+func (m *Metrics) RecordEvaluation(ctx context.Context, flag string, reason of.Reason, duration time.Duration) {
+	attributes := metric.WithAttributes(
+		semconv.FeatureFlagKey(flag),
+		semconv.FeatureFlagResultReasonKey.String(strings.ToLower(string(reason))),
+	)
+	m.evaluationCounter.Add(ctx, 1, attributes)
+	m.durationHistogram.Record(ctx, duration.Seconds(), attributes)
+}
+
+// RecordCacheLookup implements [amplitude.Metrics].
+func (m *Metrics) RecordCacheLookup(ctx context.Context, hit bool) {
+	m.cacheLookupCounter.Add(ctx, 1, metric.WithAttributes(attribute.Bool("hit", hit)))
+}
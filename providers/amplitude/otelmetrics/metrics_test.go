@@ -0,0 +1,89 @@
+package otelmetrics
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	of "github.com/open-feature/go-sdk/openfeature"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func newTestMetrics(t *testing.T) (*Metrics, *sdkmetric.ManualReader) {
+	t.Helper()
+	reader := sdkmetric.NewManualReader()
+	metrics, err := NewForMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)))
+	require.NoError(t, err)
+	return metrics, reader
+}
+
+func collect(t *testing.T, reader *sdkmetric.ManualReader) map[string]metricdata.Metrics {
+	t.Helper()
+	var data metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.Background(), &data))
+	byName := make(map[string]metricdata.Metrics)
+	for _, scope := range data.ScopeMetrics {
+		assert.Equal(t, ScopeName, scope.Scope.Name)
+		for _, m := range scope.Metrics {
+			byName[m.Name] = m
+		}
+	}
+	return byName
+}
+
+func TestMetrics_RecordEvaluation(t *testing.T) {
+	metrics, reader := newTestMetrics(t)
+
+	metrics.RecordEvaluation(context.Background(), "my-flag", of.TargetingMatchReason, 5*time.Millisecond)
+	metrics.RecordEvaluation(context.Background(), "my-flag", of.TargetingMatchReason, 15*time.Millisecond)
+	metrics.RecordEvaluation(context.Background(), "my-flag", of.ErrorReason, time.Millisecond)
+
+	collected := collect(t, reader)
+	expectedAttributes := attribute.NewSet(
+		attribute.String("feature_flag.key", "my-flag"),
+		attribute.String("feature_flag.result.reason", "targeting_match"),
+	)
+
+	counts, ok := collected[evaluationCount].Data.(metricdata.Sum[int64])
+	require.True(t, ok)
+	require.Len(t, counts.DataPoints, 2)
+	for _, point := range counts.DataPoints {
+		if point.Attributes.Equals(&expectedAttributes) {
+			assert.Equal(t, int64(2), point.Value)
+		} else {
+			assert.Equal(t, int64(1), point.Value)
+		}
+	}
+
+	durations, ok := collected[evaluationDuration].Data.(metricdata.Histogram[float64])
+	require.True(t, ok)
+	require.Len(t, durations.DataPoints, 2)
+	for _, point := range durations.DataPoints {
+		if point.Attributes.Equals(&expectedAttributes) {
+			assert.Equal(t, uint64(2), point.Count)
+			assert.InDelta(t, 0.02, point.Sum, 1e-9)
+		}
+	}
+	assert.Equal(t, "s", collected[evaluationDuration].Unit)
+}
+
+func TestMetrics_RecordCacheLookup(t *testing.T) {
+	metrics, reader := newTestMetrics(t)
+
+	metrics.RecordCacheLookup(context.Background(), true)
+	metrics.RecordCacheLookup(context.Background(), true)
+	metrics.RecordCacheLookup(context.Background(), false)
+
+	lookups, ok := collect(t, reader)[cacheLookupCount].Data.(metricdata.Sum[int64])
+	require.True(t, ok)
+	values := make(map[bool]int64)
+	for _, point := range lookups.DataPoints {
+		hit, _ := point.Attributes.Value("hit")
+		values[hit.AsBool()] = point.Value
+	}
+	assert.Equal(t, map[bool]int64{true: 2, false: 1}, values)
+}
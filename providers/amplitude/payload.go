@@ -0,0 +1,163 @@
+package amplitude
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+
+	"github.com/amplitude/experiment-go-server/pkg/experiment"
+)
+
+// decodeBase64JSONPayload decodes a string payload containing base64-encoded JSON.
+// Only JSON objects and arrays are accepted, so that plain string payloads
+// which happen to be valid base64 are not misinterpreted.
+// It returns false if the payload could not be decoded.
+func decodeBase64JSONPayload(payload string, useNumber bool) (any, bool) {
+	decoded, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		decoded, err = base64.RawStdEncoding.DecodeString(payload)
+		if err != nil {
+			return nil, false
+		}
+	}
+
+	return decodeJSONStructure(decoded, useNumber)
+}
+
+// decodeStringifiedJSONPayload decodes a string payload which itself contains JSON
+// (i.e. the payload was double-encoded).
+// Like [decodeBase64JSONPayload], only JSON objects and arrays are accepted.
+// It returns false if the payload could not be decoded.
+func decodeStringifiedJSONPayload(payload string, useNumber bool) (any, bool) {
+	return decodeJSONStructure([]byte(payload), useNumber)
+}
+
+// decodeJSONStructure decodes JSON data, returning false unless it is a JSON object or array.
+func decodeJSONStructure(data []byte, useNumber bool) (any, bool) {
+	value, err := decodeJSON(data, useNumber)
+	if err != nil {
+		return nil, false
+	}
+	switch value.(type) {
+	case map[string]any, []any:
+		return value, true
+	}
+	return nil, false
+}
+
+// decodeJSON decodes a single JSON value.
+// If useNumber is true, numbers are decoded as [json.Number] rather than float64,
+// preserving the precision of large integers.
+func decodeJSON(data []byte, useNumber bool) (any, error) {
+	if !useNumber {
+		var value any
+		err := json.Unmarshal(data, &value)
+		return value, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	// Match json.Unmarshal, which rejects trailing data.
+	if _, err := decoder.Token(); !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("invalid data after top-level JSON value at offset %d", decoder.InputOffset())
+	}
+	return value, nil
+}
+
+// PayloadSource determines where the provider reads the payload of a variant from;
+// see [WithPayloadSource].
+type PayloadSource struct {
+	kind payloadSourceKind
+	// metadataField is the metadata field holding the payload, for payloadSourceMetadata.
+	metadataField string
+}
+
+// payloadSourceKind is the kind of a [PayloadSource].
+type payloadSourceKind int
+
+const (
+	payloadSourcePayload payloadSourceKind = iota
+	payloadSourceValue
+	payloadSourceMetadata
+)
+
+var (
+	// PayloadFromPayload reads the payload from the payload of the variant, as decoded by the Amplitude SDK.
+	// This is the default.
+	PayloadFromPayload = PayloadSource{kind: payloadSourcePayload}
+	// PayloadFromValue reads the payload from the value of the variant, decoding it as JSON.
+	PayloadFromValue = PayloadSource{kind: payloadSourceValue}
+)
+
+// PayloadFromMetadata reads the payload from the given field of the variant's metadata,
+// as decoded by the Amplitude SDK.
+func PayloadFromMetadata(field string) PayloadSource {
+	return PayloadSource{kind: payloadSourceMetadata, metadataField: field}
+}
+
+// resolvePayload returns the payload of the variant from the configured [PayloadSource].
+// It returns nil if the source is empty, so that the variant is treated as having no payload,
+// and an error if the value of the variant isn't valid JSON.
+func (p *Provider) resolvePayload(variant *experiment.Variant) (any, error) {
+	source := p.config.PayloadSource
+	switch source.kind {
+	case payloadSourceValue:
+		if variant.Value == "" {
+			return nil, nil
+		}
+		payload, err := decodeJSON([]byte(variant.Value), p.config.UseNumberDecoding)
+		if err != nil {
+			return nil, fmt.Errorf("the value of variant %s is not valid JSON: %w", variant.Key, err)
+		}
+		return payload, nil
+	case payloadSourceMetadata:
+		return variant.Metadata[source.metadataField], nil
+	}
+	return variant.Payload, nil
+}
+
+// useVariantValue is the type of [UseVariantValue].
+type useVariantValue struct{}
+
+// UseVariantValue can be given as a value to [WithEmptyPayloadDefaults] to resolve variants
+// without a payload to the variant's value (which is usually its key), rather than a fixed value.
+var UseVariantValue = useVariantValue{}
+
+// emptyPayloadKinds are the kinds supported by [WithEmptyPayloadDefaults],
+// with the type of value each kind's evaluation method accepts.
+var emptyPayloadKinds = map[reflect.Kind]reflect.Type{
+	reflect.String:    reflect.TypeFor[string](),
+	reflect.Int64:     reflect.TypeFor[int64](),
+	reflect.Float64:   reflect.TypeFor[float64](),
+	reflect.Interface: nil,
+}
+
+// payloadOrDefault returns the variant's payload, or if it has none,
+// the value configured with [WithEmptyPayloadDefaults] for the kind, if any.
+func (p *Provider) payloadOrDefault(kind reflect.Kind, variant *experiment.Variant) any {
+	if variant.Payload != nil {
+		return variant.Payload
+	}
+	value, ok := p.config.EmptyPayloadDefaults[kind]
+	if !ok {
+		return nil
+	}
+	if _, ok := value.(useVariantValue); ok {
+		return variant.Value
+	}
+	// Integer payloads are never int64, so IntEvaluation rejects them; pass it the string form,
+	// which it parses without the loss of precision of a float64.
+	if intValue, ok := value.(int64); ok && kind == reflect.Int64 {
+		return strconv.FormatInt(intValue, 10)
+	}
+	return value
+}
@@ -0,0 +1,124 @@
+package amplitude
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+
+	"github.com/amplitude/experiment-go-server/pkg/experiment"
+	of "github.com/open-feature/go-sdk/openfeature"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeJSON(t *testing.T) {
+	t.Run("decodes numbers as float64 by default", func(t *testing.T) {
+		value, err := decodeJSON([]byte(`{"n": 9007199254740993}`), false)
+
+		require.NoError(t, err)
+		assert.IsType(t, float64(0), value.(map[string]any)["n"])
+	})
+
+	t.Run("decodes numbers as json.Number with useNumber", func(t *testing.T) {
+		value, err := decodeJSON([]byte(`{"n": 9007199254740993}`), true)
+
+		require.NoError(t, err)
+		assert.Equal(t, json.Number("9007199254740993"), value.(map[string]any)["n"])
+	})
+
+	t.Run("rejects trailing data with useNumber", func(t *testing.T) {
+		_, err := decodeJSON([]byte(`{} {}`), true)
+		assert.Error(t, err)
+
+		_, err = decodeJSON([]byte(`{} x`), true)
+		assert.Error(t, err)
+	})
+
+	t.Run("rejects invalid JSON", func(t *testing.T) {
+		_, err := decodeJSON([]byte(`{`), true)
+		assert.Error(t, err)
+
+		_, err = decodeJSON([]byte(`{`), false)
+		assert.Error(t, err)
+	})
+}
+
+func TestDecodeBase64JSONPayload_UseNumber(t *testing.T) {
+	encoded := base64.StdEncoding.EncodeToString([]byte(`[9007199254740993]`))
+
+	value, ok := decodeBase64JSONPayload(encoded, true)
+
+	require.True(t, ok)
+	assert.Equal(t, []any{json.Number("9007199254740993")}, value)
+}
+
+func TestProvider_PayloadSource(t *testing.T) {
+	newProvider := func(t *testing.T, variant experiment.Variant, options ...Option) *Provider {
+		t.Helper()
+		mock := &mockClientAdapter{
+			EvaluateFunc: func(context.Context, *experiment.User, []string) (map[string]experiment.Variant, error) {
+				return map[string]experiment.Variant{"test-flag": variant}, nil
+			},
+		}
+		return newTestProvider(t, mock, options...)
+	}
+	evalCtx := of.FlattenedContext{of.TargetingKey: "user-123"}
+
+	t.Run("reads the payload by default", func(t *testing.T) {
+		provider := newProvider(t, makeVariant("on", `{"size": 2}`, map[string]any{"size": 1.0}))
+
+		result := provider.ObjectEvaluation(context.Background(), "test-flag", nil, evalCtx)
+
+		require.NoError(t, result.Error())
+		assert.Equal(t, map[string]any{"size": 1.0}, result.Value)
+	})
+
+	t.Run("decodes the value as JSON", func(t *testing.T) {
+		provider := newProvider(t, makeVariant("on", `{"size": 2}`, nil), WithPayloadSource(PayloadFromValue))
+
+		result := provider.ObjectEvaluation(context.Background(), "test-flag", nil, evalCtx)
+
+		require.NoError(t, result.Error())
+		assert.Equal(t, map[string]any{"size": 2.0}, result.Value)
+	})
+
+	t.Run("decodes scalar values for typed evaluation", func(t *testing.T) {
+		provider := newProvider(t, makeVariant("on", `"blue"`, nil), WithPayloadSource(PayloadFromValue))
+
+		result := provider.StringEvaluation(context.Background(), "test-flag", "default", evalCtx)
+
+		require.NoError(t, result.Error())
+		assert.Equal(t, "blue", result.Value)
+	})
+
+	t.Run("fails if the value is not JSON", func(t *testing.T) {
+		provider := newProvider(t, makeVariant("on", "on", nil), WithPayloadSource(PayloadFromValue))
+
+		result := provider.StringEvaluation(context.Background(), "test-flag", "default", evalCtx)
+
+		assert.Equal(t, "default", result.Value)
+		assert.Equal(t, of.ErrorReason, result.Reason)
+		assert.ErrorContains(t, result.Error(), string(of.ParseErrorCode))
+	})
+
+	t.Run("reads a metadata field", func(t *testing.T) {
+		variant := makeVariant("on", "on", nil)
+		variant.Metadata = map[string]any{"payload": map[string]any{"size": 3.0}}
+		provider := newProvider(t, variant, WithPayloadSource(PayloadFromMetadata("payload")))
+
+		result := provider.ObjectEvaluation(context.Background(), "test-flag", nil, evalCtx)
+
+		require.NoError(t, result.Error())
+		assert.Equal(t, map[string]any{"size": 3.0}, result.Value)
+	})
+
+	t.Run("treats a missing metadata field as no payload", func(t *testing.T) {
+		provider := newProvider(t, makeVariant("on", "on", "ignored"), WithPayloadSource(PayloadFromMetadata("payload")))
+
+		result := provider.StringEvaluation(context.Background(), "test-flag", "default", evalCtx)
+
+		assert.Equal(t, "default", result.Value)
+		assert.Equal(t, of.DefaultReason, result.Reason)
+	})
+}
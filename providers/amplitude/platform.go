@@ -0,0 +1,48 @@
+package amplitude
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Platform values recognized by Amplitude for the [KeyPlatform] field.
+// Amplitude compares platforms as exact strings, so free-form values
+// (e.g. "ios" or "iPhone") silently fail to match targeting rules.
+const (
+	PlatformIOS     = "iOS"
+	PlatformAndroid = "Android"
+	PlatformWeb     = "Web"
+	PlatformMacOS   = "macOS"
+	PlatformTvOS    = "tvOS"
+	PlatformWatchOS = "watchOS"
+)
+
+// knownPlatforms are the platform values recognized by Amplitude.
+var knownPlatforms = []string{
+	PlatformIOS,
+	PlatformAndroid,
+	PlatformWeb,
+	PlatformMacOS,
+	PlatformTvOS,
+	PlatformWatchOS,
+}
+
+// ValidationMode determines how the provider reacts to an invalid value.
+type ValidationMode int
+
+const (
+	// ValidationDisabled skips validation.
+	ValidationDisabled ValidationMode = iota
+	// ValidationWarn logs a warning and continues.
+	ValidationWarn
+	// ValidationError fails the operation with an error.
+	ValidationError
+)
+
+// validatePlatform checks that the platform is empty or one of the platforms recognized by Amplitude.
+func validatePlatform(platform string) error {
+	if platform == "" || slices.Contains(knownPlatforms, platform) {
+		return nil
+	}
+	return fmt.Errorf("unrecognized %s %q, expected one of %v", KeyPlatform, platform, knownPlatforms)
+}
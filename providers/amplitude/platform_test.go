@@ -0,0 +1,96 @@
+package amplitude
+
+import (
+	"context"
+	"testing"
+
+	"github.com/amplitude/experiment-go-server/pkg/logger"
+	of "github.com/open-feature/go-sdk/openfeature"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidatePlatform(t *testing.T) {
+	for _, platform := range []string{"", PlatformIOS, PlatformAndroid, PlatformWeb, PlatformMacOS, PlatformTvOS, PlatformWatchOS} {
+		assert.NoError(t, validatePlatform(platform), platform)
+	}
+	for _, platform := range []string{"ios", "iPhone", "web "} {
+		assert.ErrorContains(t, validatePlatform(platform), "unrecognized platform", platform)
+	}
+}
+
+func TestToAmplitudeUser_PlatformValidation(t *testing.T) {
+	tests := []struct {
+		name             string
+		mode             ValidationMode
+		platform         string
+		expectedErr      bool
+		expectedWarnings int
+	}{
+		{
+			name:     "disabled ignores unknown platforms",
+			mode:     ValidationDisabled,
+			platform: "iphone",
+		},
+		{
+			name:     "warn accepts known platforms silently",
+			mode:     ValidationWarn,
+			platform: PlatformIOS,
+		},
+		{
+			name:             "warn logs unknown platforms",
+			mode:             ValidationWarn,
+			platform:         "iphone",
+			expectedWarnings: 1,
+		},
+		{
+			name:     "error accepts known platforms",
+			mode:     ValidationError,
+			platform: PlatformAndroid,
+		},
+		{
+			name:        "error rejects unknown platforms",
+			mode:        ValidationError,
+			platform:    "iphone",
+			expectedErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loggerProvider := &recordingLoggerProvider{}
+			provider := &Provider{
+				config: Config{PlatformValidation: tt.mode},
+				logger: logger.New(logger.Warn, loggerProvider),
+			}
+
+			user, err := provider.toAmplitudeUser(context.Background(), of.FlattenedContext{
+				of.TargetingKey: "user-123",
+				"platform":      tt.platform,
+			})
+
+			if tt.expectedErr {
+				assert.ErrorContains(t, err, tt.platform)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tt.platform, user.Platform)
+			}
+			assert.Len(t, loggerProvider.warnings, tt.expectedWarnings)
+		})
+	}
+}
+
+func TestProvider_PlatformValidation_InvalidContext(t *testing.T) {
+	provider := newTestProvider(t, &mockClientAdapter{},
+		WithPlatformValidation(ValidationError),
+	)
+
+	result := provider.BooleanEvaluation(context.Background(), "flag", true, of.FlattenedContext{
+		of.TargetingKey: "user-123",
+		"platform":      "iphone",
+	})
+
+	assert.True(t, result.Value)
+	assert.Equal(t, of.ErrorReason, result.Reason)
+	assert.ErrorContains(t, result.ResolutionError, string(of.InvalidContextCode))
+}
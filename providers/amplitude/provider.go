@@ -5,7 +5,15 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"maps"
+	"math"
+	"reflect"
+	"slices"
 	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
 
 	analytics "github.com/amplitude/analytics-go/amplitude"
 	experiment "github.com/amplitude/experiment-go-server/pkg/experiment"
@@ -16,11 +24,32 @@ import (
 
 // Compile-time interface checks.
 var (
-	_ of.FeatureProvider = (*Provider)(nil)
-	_ of.StateHandler    = (*Provider)(nil)
-	_ of.Tracker         = (*Provider)(nil)
+	_ of.FeatureProvider          = (*Provider)(nil)
+	_ of.StateHandler             = (*Provider)(nil)
+	_ of.ContextAwareStateHandler = (*Provider)(nil)
+	_ of.Tracker                  = (*Provider)(nil)
+	_ FlagEvaluator               = (*Provider)(nil)
+	_ io.Closer                   = (*Provider)(nil)
 )
 
+// FlagEvaluator is the interface implemented by [Provider]: an OpenFeature provider and tracker,
+// with the Amplitude-specific methods for evaluating several flags at once.
+// Depend on it rather than *Provider so that tests can substitute a fake.
+type FlagEvaluator interface {
+	of.FeatureProvider
+	of.StateHandler
+	of.Tracker
+
+	// EvaluateAll evaluates all flags; see [Provider.EvaluateAll].
+	EvaluateAll(ctx context.Context, evalCtx of.FlattenedContext) (map[string]experiment.Variant, error)
+	// EvaluateFlags evaluates the given flags; see [Provider.EvaluateFlags].
+	EvaluateFlags(ctx context.Context, evalCtx of.FlattenedContext, flags []string) (map[string]experiment.Variant, error)
+	// ActiveExperiments returns the flags the user is in a variant of; see [Provider.ActiveExperiments].
+	ActiveExperiments(ctx context.Context, evalCtx of.FlattenedContext) (map[string]string, error)
+	// FlagConfigStats describes the local flag configs; see [Provider.FlagConfigStats].
+	FlagConfigStats() FlagConfigStats
+}
+
 // Provider is an OpenFeature provider implementation for Amplitude.
 type Provider struct {
 	config            Config
@@ -29,17 +58,126 @@ type Provider struct {
 	client            clientAdapter
 	logger            *logger.Logger
 	analyticsClient   analytics.Client
+	// analyticsClosed is set when Close shuts the analytics client down,
+	// so that Init creates a new one.
+	analyticsClosed bool
+	// trackingDisabled suppresses tracking at runtime; see [Provider.SetTrackingEnabled].
+	trackingDisabled atomic.Bool
+	// foldedKeyMap is the key map keyed by folded context keys,
+	// if [WithCaseInsensitiveKeyMatching] is set; see [foldKeyMap].
+	foldedKeyMap map[string]Key
+	// changeTracker detects evaluation changes, if an evaluation change callback is configured.
+	changeTracker *evaluationChangeTracker
+	// exposureDeduplicator suppresses repeated exposures, if [WithExposureDeduplication] is set.
+	exposureDeduplicator *exposureDeduplicator
+	// exposureSink passes exposure records to the sink, if [WithExposureSink] is set.
+	exposureSink *sinkWorker[ExposureRecord]
+	// auditSink passes audit records to the decision audit sink, if it is set with [AuditAsync].
+	auditSink *sinkWorker[AuditRecord]
+	// lastErrors records the last evaluation error of each flag; see [Provider.LastError].
+	lastErrors lastErrors
 }
 
 const (
-	providerNotReady = "Amplitude provider not ready"
-	generalError     = "Amplitude general error"
+	defaultProviderName = "Amplitude"
+	providerNotReady    = "Amplitude provider not ready"
+	generalError        = "Amplitude general error"
+	emptyFlagKey        = "flag key must not be empty"
 
 	// variantKeyOff is the variant key returned by Amplitude when a user
-	// is not included in a feature flag's rollout.
+	// is not included in a feature flag's rollout, unless configured with [WithOffVariantKeys].
 	variantKeyOff = "off"
 )
 
+// ContextKeySurface is a reserved evaluation context key identifying where a flag is evaluated
+// (e.g. "checkout-page"). It is recorded on exposure events rather than being used for targeting.
+const ContextKeySurface = "amplitude_surface"
+
+// ContextKeyAnonymous is a reserved evaluation context key which, when set to true,
+// marks the user as anonymous (e.g. logged out): flags are evaluated as usual, but no exposure
+// events are tracked, so that exposures are only attributed once the user is identified.
+// It is not used for targeting.
+const ContextKeyAnonymous = "amplitude_anonymous"
+
+// ContextKeyNoExposure is a reserved evaluation context key which, when set to true, suppresses
+// exposure events for the evaluation, like [ContextWithoutExposures], for callers which can only
+// set the evaluation context (e.g. the client-level context of an admin dashboard).
+// It is not used for targeting.
+const ContextKeyNoExposure = "amplitude_no_exposure"
+
+// noExposuresContextKey is the context key set by [ContextWithoutExposures].
+type noExposuresContextKey struct{}
+
+// ContextWithoutExposures returns a context with which flags are evaluated as usual, but no exposure
+// events are tracked, for call sites such as health checks or previews whose evaluations shouldn't
+// count as exposures of experiments. Unlike [ContextKeyAnonymous], it says nothing about the user,
+// and since the context is passed through the OpenFeature client, it works with every evaluation method:
+//
+//	value, err := client.BooleanValue(amplitude.ContextWithoutExposures(ctx), "new-checkout", false, evalCtx)
+//
+// Assignment events sent by the local evaluation SDK itself are not affected.
+func ContextWithoutExposures(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noExposuresContextKey{}, true)
+}
+
+// Reserved evaluation context keys which force flags to specific variants for a single evaluation,
+// if enabled with [WithContextForcedVariantsEnabled]. They are not used for targeting.
+const (
+	// ContextKeyForcedVariants is a map of flag keys to the variant keys they are forced to,
+	// as a map[string]string or map[string]any with string values.
+	ContextKeyForcedVariants = "amplitude_forced_variants"
+	// ContextKeyForcedVariantPrefix is the prefix of keys which force a single flag:
+	// "amplitude_force_variant.my-flag" set to a variant key forces "my-flag" to that variant.
+	// It takes precedence over [ContextKeyForcedVariants].
+	ContextKeyForcedVariantPrefix = "amplitude_force_variant."
+)
+
+// Keys which the provider adds to the FlagMetadata of resolution details,
+// describing how the evaluation was performed.
+const (
+	// MetadataKeyNormalizerApplied is set to true when a user normalizer
+	// (see [WithUserNormalizer]) ran while building the Amplitude user.
+	MetadataKeyNormalizerApplied = "amplitude_normalizer_applied"
+	// MetadataKeyEvalMs is the time taken to evaluate the flag, in (fractional) milliseconds.
+	// This includes the round-trip to Amplitude when using remote evaluation.
+	MetadataKeyEvalMs = "amplitude_eval_ms"
+	// MetadataKeyFlagVersion is the version of the flag config which produced the variant.
+	// It is only present if Amplitude included it in the variant's metadata,
+	// which is typically the case for local evaluation but may not be for remote evaluation.
+	MetadataKeyFlagVersion = "amplitude_flag_version"
+	// MetadataKeyDeployed records whether the flag config which produced the variant was deployed.
+	// Like [MetadataKeyFlagVersion], it is only present if Amplitude included it.
+	MetadataKeyDeployed = "amplitude_deployed"
+	// MetadataKeySegmentName is the name of the targeting segment (rule) which matched,
+	// e.g. "beta-users". It is absent when Amplitude didn't report a segment name.
+	MetadataKeySegmentName = "amplitude_segment_name"
+	// MetadataKeyForcedVariant is set to true when the variant was forced by the evaluation context
+	// (see [WithContextForcedVariantsEnabled]) rather than evaluated by Amplitude.
+	MetadataKeyForcedVariant = "amplitude_forced_variant"
+	// MetadataKeyOverrideApplied is set to true when the variant was chosen by an override
+	// rather than evaluated by Amplitude, such as a forced variant (see [MetadataKeyForcedVariant]).
+	MetadataKeyOverrideApplied = "amplitude_override_applied"
+	// MetadataKeyDefaultContextMerged is set to true when attributes from the Go context
+	// (see [WithContextValueExtractors]) were merged into the user because the evaluation context lacked them.
+	MetadataKeyDefaultContextMerged = "amplitude_default_context_merged"
+)
+
+// NotReadyDefaultReason is the reason reported for flags resolved to their safe value
+// while the provider isn't ready; see [WithNotReadyDefaults].
+const NotReadyDefaultReason of.Reason = "NOT_READY_DEFAULT"
+
+// ErrMaxFlagsExceeded is returned by [Provider.EvaluateAll] when more flags were evaluated
+// than allowed by [WithMaxFlagsPerEvaluation] and [WithErrorOnMaxFlagsExceeded] is set.
+var ErrMaxFlagsExceeded = errors.New("maximum number of flags per evaluation exceeded")
+
+// ErrCacheKeyUnavailable is returned by [Provider.CacheKeyForContext] with local evaluation,
+// where results aren't cached.
+var ErrCacheKeyUnavailable = errors.New("cache keys are only computed with remote evaluation")
+
+// ErrDeploymentKeyRejected is returned by [Provider.Init] when the remote readiness probe
+// (see [WithRemoteReadinessProbe]) finds that Amplitude rejects the deployment key.
+var ErrDeploymentKeyRejected = errors.New("amplitude rejected the deployment key")
+
 // New creates a new [Provider] from a deployment key and options.
 func New(ctx context.Context, deploymentKey string, options ...Option) (*Provider, error) {
 	config := Config{
@@ -53,14 +191,43 @@ func New(ctx context.Context, deploymentKey string, options ...Option) (*Provide
 
 // NewFromConfig creates a new [Provider] from a [Config].
 func NewFromConfig(_ context.Context, config Config) (*Provider, error) {
-	if config.DeploymentKey == "" {
-		return nil, errors.New("you must provide a deployment key")
+	if errs := config.invalidSettings(); len(errs) > 0 {
+		return nil, errors.Join(errs...)
 	}
 
 	provider := &Provider{
 		state:  of.NotReadyState,
 		config: config,
 	}
+	switch {
+	case config.RemoteConfig != nil:
+		provider.logger = newLogger(config.RemoteConfig.LogLevel, config.getLoggerProvider(config.RemoteConfig.LoggerProvider))
+	case config.LocalConfig != nil:
+		provider.logger = newLogger(config.LocalConfig.LogLevel, config.getLoggerProvider(config.LocalConfig.LoggerProvider))
+	}
+
+	if err := provider.checkSuspectSettings(); err != nil {
+		return nil, err
+	}
+	if config.CaseInsensitiveKeyMatching {
+		provider.foldedKeyMap = foldKeyMap(config.getKeyMap())
+	}
+	if config.EvaluationChangeCallback != nil {
+		provider.changeTracker = newEvaluationChangeTracker(config.EvaluationChangeCallback, config.EvaluationChangeTrackingSize)
+	}
+	if config.ExposureDeduplicationTTL > 0 {
+		provider.exposureDeduplicator = newExposureDeduplicator(config.ExposureDeduplicationTTL, config.ExposureDeduplicationSize)
+	}
+	if config.ExposureSink != nil {
+		provider.exposureSink = newSinkWorker("exposure sink", config.ExposureSink, config.ExposureSinkBufferSize, func(message string, args ...any) {
+			provider.getLogger().Error(message, args...)
+		})
+	}
+	if config.DecisionAudit != nil && config.DecisionAudit.Async {
+		provider.auditSink = newSinkWorker("decision audit sink", config.DecisionAudit.Sink, config.DecisionAudit.BufferSize, func(message string, args ...any) {
+			provider.getLogger().Error(message, args...)
+		})
+	}
 
 	// Allow injecting a test client adapter for testing
 	if config.testClientAdapter != nil {
@@ -69,11 +236,8 @@ func NewFromConfig(_ context.Context, config Config) (*Provider, error) {
 	}
 
 	switch {
-	case config.LocalConfig != nil && config.RemoteConfig != nil:
-		return nil, errors.New("you cannot configure the provider to use both local and remote evaluation at the same time")
-	case config.RemoteConfig != nil:
+	case config.RemoteConfig != nil && len(config.RemoteFlags) == 0:
 		provider.client = newClientAdapterRemote(config.DeploymentKey, config.getRemoteConfig())
-		provider.logger = logger.New(config.RemoteConfig.LogLevel, config.RemoteConfig.LoggerProvider)
 	default:
 		localCfg := config.getLocalConfig()
 		// Ensure that if the user provided an analytics config, 
@@ -86,11 +250,10 @@ func NewFromConfig(_ context.Context, config Config) (*Provider, error) {
 			}
 		}
 		provider.client = newClientAdapterLocal(config.DeploymentKey, config.getLocalConfig())
-		provider.logger = logger.New(config.LocalConfig.LogLevel, config.LocalConfig.LoggerProvider)
-	}
-
-	if provider.logger == nil {
-		provider.logger = logger.New(logger.Error, logger.NewDefault())
+		if len(config.RemoteFlags) > 0 {
+			remoteClient := newClientAdapterRemote(config.DeploymentKey, config.getRemoteConfig())
+			provider.client = newClientAdapterHybrid(provider.client, remoteClient, config.RemoteFlags)
+		}
 	}
 
 	if provider.config.AnalyticsConfig != nil {
@@ -100,30 +263,154 @@ func NewFromConfig(_ context.Context, config Config) (*Provider, error) {
 	return provider, nil
 }
 
+// checkSuspectSettings reports likely configuration mistakes according to [Config.ConfigValidation].
+// A remote evaluation cache with local evaluation is logged as a warning even if validation is disabled.
+func (p *Provider) checkSuspectSettings() error {
+	if p.config.ConfigValidation == ValidationDisabled {
+		// An ignored cache is a particularly confusing mistake, so it is always reported.
+		if err := p.config.checkRemoteCacheMode(); err != nil {
+			p.getLogger().Warn("amplitude: %v; use WithRemoteConfig to enable remote evaluation", err)
+		}
+		return nil
+	}
+	err := errors.Join(p.config.suspectSettings()...)
+	if err == nil {
+		return nil
+	}
+	if p.config.ConfigValidation == ValidationError {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+	p.getLogger().Warn("amplitude: possible configuration mistakes: %v", err)
+	return nil
+}
+
 // Init initializes the Amplitude Experiment provider.
 // This must be called before using the provider.
 // For local evaluation, this starts the flag config polling.
 // For remote evaluation, this is a no-op as fetching happens per-request.
+// With [WithRemoteFlags], both are started.
+// With [WithInitRetry], a failed start is retried.
+// After [Provider.Close], a new analytics client is created, so that tracking resumes.
 // The evaluation context passed is not used by this provider.
-func (p *Provider) Init(_ of.EvaluationContext) error {
+func (p *Provider) Init(evalCtx of.EvaluationContext) error {
+	return p.InitWithContext(context.Background(), evalCtx)
+}
+
+// InitWithContext initializes the provider like [Provider.Init]; retrying to start the client
+// (see [WithInitRetry]) stops when ctx is done.
+func (p *Provider) InitWithContext(ctx context.Context, _ of.EvaluationContext) error {
+	if p.analyticsClosed && p.config.AnalyticsConfig != nil {
+		p.analyticsClient = analytics.NewClient(*p.config.AnalyticsConfig)
+		p.analyticsClosed = false
+	}
+	if p.exposureSink != nil {
+		p.exposureSink.start()
+	}
+	if p.auditSink != nil {
+		p.auditSink.start()
+	}
 	// Only local client needs to be started
-	startErr := p.client.Start()
+	startErr := p.startClient(ctx)
 	if startErr != nil {
 		p.state = of.ErrorState
 		return startErr
 	}
+	if probeErr := p.probeReadiness(); probeErr != nil {
+		p.state = of.ErrorState
+		return probeErr
+	}
 
 	p.state = of.ReadyState
 	return nil
 }
 
-// Shutdown shuts down the Amplitude Experiment provider.
-// Note: The Amplitude local evaluation client does not have an explicit Close method.
-// It manages its own lifecycle via internal goroutines.
+// probeReadiness checks that Amplitude accepts the deployment key, if [WithRemoteReadinessProbe] is set.
+// Only a rejected key is an error; other failures are logged.
+func (p *Provider) probeReadiness() error {
+	prober, ok := p.client.(readinessProber)
+	if !p.config.RemoteReadinessProbe || !ok {
+		return nil
+	}
+	err := prober.probeReadiness()
+	if err == nil {
+		return nil
+	}
+	if isAuthError(err) {
+		return fmt.Errorf("%w: %w", ErrDeploymentKeyRejected, err)
+	}
+	p.getLogger().Warn("amplitude: the remote readiness probe failed: %v", err)
+	return nil
+}
+
+// maxInitRetryDelay caps the doubling delay between the retries of [WithInitRetry].
+const maxInitRetryDelay = 30 * time.Second
+
+// startClient starts the client, retrying with backoff as configured by [WithInitRetry]
+// until ctx is done or the budget of [WithStartTimeout] runs out.
+func (p *Provider) startClient(ctx context.Context) error {
+	if p.config.StartTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.config.StartTimeout)
+		defer cancel()
+	}
+	delay := p.config.InitRetryDelay
+	for attempt := 1; ; attempt++ {
+		err := p.client.Start()
+		if err == nil || attempt >= p.config.InitRetryAttempts {
+			return err
+		}
+		p.getLogger().Warn("amplitude: failed to start the client (attempt %d of %d), retrying in %s: %v", attempt, p.config.InitRetryAttempts, delay, err)
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("amplitude: stopped retrying to start the client after %d attempts: %w (last error: %w)", attempt, ctx.Err(), err)
+		case <-timer.C:
+		}
+		delay = min(delay*2, maxInitRetryDelay)
+	}
+}
+
+// Shutdown shuts down the Amplitude Experiment provider, like [Provider.Close]:
+// it stops the client and flushes any pending tracked events.
+// An error stopping the client is logged, since Shutdown can't return it.
+// Note: the Amplitude local evaluation SDK can't stop polling for flag configs,
+// but it shares one client per deployment key, so providers created again with the same key
+// (such as across openfeature.Shutdown calls) reuse its poller rather than starting more.
 func (p *Provider) Shutdown() {
-	// TODO: Investigate if there's a way to properly stop the Amplitude client.
-	// The local.Client doesn't expose a Stop/Close method in the current SDK version.
+	if err := p.Close(); err != nil {
+		p.getLogger().Error("amplitude: %v", err)
+	}
+}
+
+// ShutdownWithContext shuts down the provider like [Provider.Close], returning its error.
+// Closing doesn't wait on Amplitude, so ctx isn't used.
+func (p *Provider) ShutdownWithContext(_ context.Context) error {
+	return p.Close()
+}
+
+// Close stops the Amplitude client and flushes any pending tracked events and exposure records
+// (see [WithExposureSink]),
+// so that the provider can be used with `defer provider.Close()` and other io.Closer cleanup.
+// The provider can't be used for tracking afterwards until it is initialized again.
+// It returns any error from stopping the client.
+func (p *Provider) Close() error {
+	stopErr := p.client.Stop()
+	if p.analyticsClient != nil {
+		p.analyticsClient.Shutdown()
+		p.analyticsClosed = true
+	}
+	if p.exposureSink != nil {
+		p.exposureSink.stop()
+	}
+	if p.auditSink != nil {
+		p.auditSink.stop()
+	}
 	p.state = of.NotReadyState
+	if stopErr != nil {
+		return fmt.Errorf("failed to stop the Amplitude client: %w", stopErr)
+	}
+	return nil
 }
 
 // Status returns the current state of the provider.
@@ -138,33 +425,60 @@ func (p *Provider) Hooks() []of.Hook {
 
 // Metadata returns value of Metadata (name of current service, exposed to openfeature sdk).
 func (p *Provider) Metadata() of.Metadata {
+	name := p.config.ProviderName
+	if name == "" {
+		name = defaultProviderName
+	}
 	return of.Metadata{
-		Name: "Amplitude",
+		Name: name,
 	}
 }
 
 // BooleanEvaluation evaluates a boolean feature flag.
 // If the payload can be unmarshalled to a boolean, that value is used.
-// Otherwise, falls back to variant key logic: "off" returns the default value,
-// any other variant key returns true.
-func (p *Provider) BooleanEvaluation(ctx context.Context, flag string, defaultValue bool, evalCtx of.FlattenedContext) of.BoolResolutionDetail {
-	variant, resErr := p.evaluateFlag(ctx, flag, evalCtx)
+// Otherwise, falls back to variant key logic: "off" returns the default value
+// (or false, if [WithOffMeansFalse] is set), any other variant key returns true.
+func (p *Provider) BooleanEvaluation(ctx context.Context, flag string, defaultValue bool, evalCtx of.FlattenedContext) (result of.BoolResolutionDetail) {
+	defer func() { p.recordLastError(flag, result.Error()) }()
+	eval, resErr := p.evaluateFlag(ctx, flag, evalCtx)
 	if resErr != nil {
+		if p.useFallback(resErr) {
+			return p.config.FallbackProvider.BooleanEvaluation(ctx, flag, defaultValue, evalCtx)
+		}
+		if p.config.MissingBooleanFlagAsFalse && resErr.code == of.FlagNotFoundCode {
+			return of.BoolResolutionDetail{
+				Value: false,
+				ProviderResolutionDetail: of.ProviderResolutionDetail{
+					Reason: of.DefaultReason,
+				},
+			}
+		}
 		return of.BoolResolutionDetail{
 			Value: defaultValue,
 			ProviderResolutionDetail: of.ProviderResolutionDetail{
-				ResolutionError: *resErr,
+				ResolutionError: resErr.ResolutionError,
 				Reason:          of.ErrorReason,
 			},
 		}
 	}
 
+	variant := eval.variant
+
 	// nil variant indicates "off" - return default value
-	if variant == nil || variant.Key == variantKeyOff {
+	if variant == nil || p.isOffVariant(variant) {
+		if p.config.OffMeansFalse && !eval.forcedDefault {
+			return of.BoolResolutionDetail{
+				Value: false,
+				ProviderResolutionDetail: of.ProviderResolutionDetail{
+					Variant: eval.evaluated.Key,
+					Reason:  of.TargetingMatchReason,
+				},
+			}
+		}
 		return of.BoolResolutionDetail{
 			Value: defaultValue,
 			ProviderResolutionDetail: of.ProviderResolutionDetail{
-				Reason: of.DefaultReason,
+				Reason: eval.offReason,
 			},
 		}
 	}
@@ -173,54 +487,63 @@ func (p *Provider) BooleanEvaluation(ctx context.Context, flag string, defaultVa
 	if castType, ok := variant.Payload.(bool); ok {
 		return of.BoolResolutionDetail{
 			Value: castType,
-			ProviderResolutionDetail: of.ProviderResolutionDetail{
-				Variant:      variant.Key,
-				FlagMetadata: variantMetadata(variant),
-			},
+			ProviderResolutionDetail: eval.resolutionDetail(),
 		}
 	}
 
 	// Any other variant value means "enabled", as documented in the README.md
 	return of.BoolResolutionDetail{
 		Value: true,
-		ProviderResolutionDetail: of.ProviderResolutionDetail{
-			Variant:      variant.Key,
-			FlagMetadata: variantMetadata(variant),
-		},
+		ProviderResolutionDetail: eval.resolutionDetail(),
 	}
 }
 
 // StringEvaluation evaluates a string feature flag.
-func (p *Provider) StringEvaluation(ctx context.Context, flag string, defaultValue string, evalCtx of.FlattenedContext) of.StringResolutionDetail {
-	variant, resErr := p.evaluateFlag(ctx, flag, evalCtx)
+func (p *Provider) StringEvaluation(ctx context.Context, flag string, defaultValue string, evalCtx of.FlattenedContext) (result of.StringResolutionDetail) {
+	defer func() { p.recordLastError(flag, result.Error()) }()
+	eval, resErr := p.evaluateFlag(ctx, flag, evalCtx)
+	defer func() { p.notifyResultFallback(flag, resErr, result.ProviderResolutionDetail) }()
 	if resErr != nil {
+		if p.useFallback(resErr) {
+			return p.config.FallbackProvider.StringEvaluation(ctx, flag, defaultValue, evalCtx)
+		}
 		return of.StringResolutionDetail{
 			Value: defaultValue,
 			ProviderResolutionDetail: of.ProviderResolutionDetail{
-				ResolutionError: *resErr,
+				ResolutionError: resErr.ResolutionError,
 				Reason:          of.ErrorReason,
 			},
 		}
 	}
 
+	variant := eval.variant
+
 	// nil variant indicates "off" - return default value
 	if variant == nil {
 		return of.StringResolutionDetail{
 			Value: defaultValue,
 			ProviderResolutionDetail: of.ProviderResolutionDetail{
-				Reason: of.DefaultReason,
+				Reason: eval.offReason,
 			},
 		}
 	}
 
-	switch castType := variant.Payload.(type) {
+	switch castType := p.payloadOrDefault(reflect.String, variant).(type) {
 	case string:
+		if allowed, ok := p.config.AllowedStringValues[flag]; ok && !slices.Contains(allowed, castType) {
+			return of.StringResolutionDetail{
+				Value: defaultValue,
+				ProviderResolutionDetail: of.ProviderResolutionDetail{
+					Reason: of.ErrorReason,
+					ResolutionError: of.NewTypeMismatchResolutionError(
+						fmt.Sprintf("StringEvaluation value %q for %s is not one of the allowed values %q",
+							castType, flag, allowed)),
+				},
+			}
+		}
 		return of.StringResolutionDetail{
 			Value: castType,
-			ProviderResolutionDetail: of.ProviderResolutionDetail{
-				Variant:      variant.Key,
-				FlagMetadata: variantMetadata(variant),
-			},
+			ProviderResolutionDetail: eval.resolutionDetail(),
 		}
 	case nil:
 		return of.StringResolutionDetail{
@@ -244,37 +567,41 @@ func (p *Provider) StringEvaluation(ctx context.Context, flag string, defaultVal
 }
 
 // FloatEvaluation evaluates a float feature flag.
-func (p *Provider) FloatEvaluation(ctx context.Context, flag string, defaultValue float64, evalCtx of.FlattenedContext) of.FloatResolutionDetail {
-	variant, resErr := p.evaluateFlag(ctx, flag, evalCtx)
+func (p *Provider) FloatEvaluation(ctx context.Context, flag string, defaultValue float64, evalCtx of.FlattenedContext) (result of.FloatResolutionDetail) {
+	defer func() { p.recordLastError(flag, result.Error()) }()
+	eval, resErr := p.evaluateFlag(ctx, flag, evalCtx)
+	defer func() { p.notifyResultFallback(flag, resErr, result.ProviderResolutionDetail) }()
 	if resErr != nil {
+		if p.useFallback(resErr) {
+			return p.config.FallbackProvider.FloatEvaluation(ctx, flag, defaultValue, evalCtx)
+		}
 		return of.FloatResolutionDetail{
 			Value: defaultValue,
 			ProviderResolutionDetail: of.ProviderResolutionDetail{
-				ResolutionError: *resErr,
+				ResolutionError: resErr.ResolutionError,
 				Reason:          of.ErrorReason,
 			},
 		}
 	}
 
+	variant := eval.variant
+
 	// nil variant indicates "off" - return default value
 	if variant == nil {
 		return of.FloatResolutionDetail{
 			Value: defaultValue,
 			ProviderResolutionDetail: of.ProviderResolutionDetail{
-				Reason: of.DefaultReason,
+				Reason: eval.offReason,
 			},
 		}
 	}
 
 	// Extract the value from the payload:
-	switch castType := variant.Payload.(type) {
+	switch castType := p.payloadOrDefault(reflect.Float64, variant).(type) {
 	case float64:
 		return of.FloatResolutionDetail{
 			Value: castType,
-			ProviderResolutionDetail: of.ProviderResolutionDetail{
-				Variant:      variant.Key,
-				FlagMetadata: variantMetadata(variant),
-			},
+			ProviderResolutionDetail: eval.resolutionDetail(),
 		}
 	// The Amplitude SDK does not currently invoke `UseNumber` on the JSON decoder,
 	// but if it starts doing it in the future we should handle it correctly.
@@ -291,10 +618,23 @@ func (p *Provider) FloatEvaluation(ctx context.Context, flag string, defaultValu
 		}
 		return of.FloatResolutionDetail{
 			Value: value,
-			ProviderResolutionDetail: of.ProviderResolutionDetail{
-				Variant:      variant.Key,
-				FlagMetadata: variantMetadata(variant),
-			},
+			ProviderResolutionDetail: eval.resolutionDetail(),
+		}
+	// Like IntEvaluation, accept numbers configured as JSON strings, such as "3" or "2.5".
+	case string:
+		value, err := strconv.ParseFloat(castType, 64)
+		if err != nil {
+			return of.FloatResolutionDetail{
+				Value: defaultValue,
+				ProviderResolutionDetail: of.ProviderResolutionDetail{
+					ResolutionError: of.NewTypeMismatchResolutionError(err.Error()),
+					Reason:          of.ErrorReason,
+				},
+			}
+		}
+		return of.FloatResolutionDetail{
+			Value: value,
+			ProviderResolutionDetail: eval.resolutionDetail(),
 		}
 	case nil:
 		return of.FloatResolutionDetail{
@@ -317,39 +657,44 @@ func (p *Provider) FloatEvaluation(ctx context.Context, flag string, defaultValu
 }
 
 // IntEvaluation evaluates an integer feature flag.
-func (p *Provider) IntEvaluation(ctx context.Context, flag string, defaultValue int64, evalCtx of.FlattenedContext) of.IntResolutionDetail {
-	variant, resErr := p.evaluateFlag(ctx, flag, evalCtx)
+func (p *Provider) IntEvaluation(ctx context.Context, flag string, defaultValue int64, evalCtx of.FlattenedContext) (result of.IntResolutionDetail) {
+	defer func() { p.recordLastError(flag, result.Error()) }()
+	eval, resErr := p.evaluateFlag(ctx, flag, evalCtx)
+	defer func() { p.notifyResultFallback(flag, resErr, result.ProviderResolutionDetail) }()
 	if resErr != nil {
+		if p.useFallback(resErr) {
+			return p.config.FallbackProvider.IntEvaluation(ctx, flag, defaultValue, evalCtx)
+		}
 		return of.IntResolutionDetail{
 			Value: defaultValue,
 			ProviderResolutionDetail: of.ProviderResolutionDetail{
-				ResolutionError: *resErr,
+				ResolutionError: resErr.ResolutionError,
 				Reason:          of.ErrorReason,
 			},
 		}
 	}
 
+	variant := eval.variant
+
 	// nil variant indicates "off" - return default value
 	if variant == nil {
 		return of.IntResolutionDetail{
 			Value: defaultValue,
 			ProviderResolutionDetail: of.ProviderResolutionDetail{
-				Reason: of.DefaultReason,
+				Reason: eval.offReason,
 			},
 		}
 	}
 
-	switch castType := variant.Payload.(type) {
+	switch castType := p.payloadOrDefault(reflect.Int64, variant).(type) {
 	// JSON numbers are automatically unmarshalled to float64,
 	// so we need to convert them to int64.
 	case float64:
 		return of.IntResolutionDetail{
 			Value: int64(castType),
-			ProviderResolutionDetail: of.ProviderResolutionDetail{
-				Variant:      variant.Key,
-				FlagMetadata: variantMetadata(variant),
-			},
+			ProviderResolutionDetail: eval.resolutionDetail(),
 		}
+
 	// The Amplitude SDK does not currently invoke `UseNumber` on the JSON decoder,
 	// but if it starts doing it in the future we should handle it correctly.
 	case json.Number:
@@ -365,10 +710,7 @@ func (p *Provider) IntEvaluation(ctx context.Context, flag string, defaultValue
 		}
 		return of.IntResolutionDetail{
 			Value: value,
-			ProviderResolutionDetail: of.ProviderResolutionDetail{
-				Variant:      variant.Key,
-				FlagMetadata: variantMetadata(variant),
-			},
+			ProviderResolutionDetail: eval.resolutionDetail(),
 		}
 	// Sometimes users may need to represent a number as a string,
 	// (e.g. to avoid floating point precision issues).
@@ -386,10 +728,7 @@ func (p *Provider) IntEvaluation(ctx context.Context, flag string, defaultValue
 		}
 		return of.IntResolutionDetail{
 			Value: value,
-			ProviderResolutionDetail: of.ProviderResolutionDetail{
-				Variant:      variant.Key,
-				FlagMetadata: variantMetadata(variant),
-			},
+			ProviderResolutionDetail: eval.resolutionDetail(),
 		}
 	case nil:
 		return of.IntResolutionDetail{
@@ -414,86 +753,173 @@ func (p *Provider) IntEvaluation(ctx context.Context, flag string, defaultValue
 
 // ObjectEvaluation evaluates an object/JSON feature flag.
 func (p *Provider) ObjectEvaluation(ctx context.Context, flag string, defaultValue any, evalCtx of.FlattenedContext) of.InterfaceResolutionDetail {
-	variant, resErr := p.evaluateFlag(ctx, flag, evalCtx)
+	eval, resErr := p.evaluateFlag(ctx, flag, evalCtx)
+	return p.objectResolution(ctx, flag, defaultValue, evalCtx, eval, resErr)
+}
+
+// objectResolution builds the result of [Provider.ObjectEvaluation] from the evaluation of the flag
+// (or the error evaluating it), also for [Provider.BatchEvaluation].
+func (p *Provider) objectResolution(ctx context.Context, flag string, defaultValue any, evalCtx of.FlattenedContext, eval *flagEvaluation, resErr *resolutionError) (detail of.InterfaceResolutionDetail) {
+	defer func() { p.recordLastError(flag, detail.Error()) }()
+	defer func() { p.notifyResultFallback(flag, resErr, detail.ProviderResolutionDetail) }()
 	if resErr != nil {
+		if p.useFallback(resErr) {
+			return p.config.FallbackProvider.ObjectEvaluation(ctx, flag, defaultValue, evalCtx)
+		}
 		return of.InterfaceResolutionDetail{
 			Value: defaultValue,
 			ProviderResolutionDetail: of.ProviderResolutionDetail{
-				ResolutionError: *resErr,
+				ResolutionError: resErr.ResolutionError,
 				Reason:          of.ErrorReason,
 			},
 		}
 	}
 
+	variant := eval.variant
+
 	// nil variant indicates "off" - return default value
 	if variant == nil {
 		return of.InterfaceResolutionDetail{
 			Value: defaultValue,
 			ProviderResolutionDetail: of.ProviderResolutionDetail{
-				Reason: of.DefaultReason,
+				Reason: eval.offReason,
 			},
 		}
 	}
 
 	// For object evaluation, return the payload directly as it's already the correct type.
-	result := variant.Payload
+	result := p.payloadOrDefault(reflect.Interface, variant)
 	if result == nil {
 		result = defaultValue
 	}
 
+	// Optionally decode structured data stored as a base64-encoded or stringified JSON string.
+	if stringPayload, ok := result.(string); ok {
+		if p.config.Base64JSONPayloads {
+			if decoded, ok := decodeBase64JSONPayload(stringPayload, p.config.UseNumberDecoding); ok {
+				result = decoded
+			}
+		}
+		if p.config.UnwrapStringifiedObjects {
+			if decoded, ok := decodeStringifiedJSONPayload(stringPayload, p.config.UseNumberDecoding); ok {
+				result = decoded
+			}
+		}
+	}
+
 	return of.InterfaceResolutionDetail{
 		Value: result,
-		ProviderResolutionDetail: of.ProviderResolutionDetail{
-			Variant:      variant.Key,
-			FlagMetadata: variantMetadata(variant),
-		},
+		ProviderResolutionDetail: eval.resolutionDetail(),
 	}
 }
 
 // Track sends a tracking event to Amplitude. This implements the [of.Tracker] interface.
-// If the analytics client is not configured, this is a no-op.
+// If the analytics client is not configured, or tracking is suspended
+// (see [Provider.SetTrackingEnabled]), this is a no-op.
 func (p *Provider) Track(ctx context.Context, trackingEventName string, evalCtx of.EvaluationContext, details of.TrackingEventDetails) {
 
-	if p.analyticsClient == nil {
+	if !p.trackingEnabled() {
 		return
 	}
 
 	event, err := p.toAmplitudeEvent(ctx, trackingEventName, evalCtx, details)
 	if err != nil {
-		p.logger.Error("failed to create event: %w", err)
+		p.getLogger().Error("failed to create event: %w", err)
 		return
 	}
 
+	p.trackEvent(event)
+}
+
+// SetTrackingEnabled suspends (false) or resumes (true) tracking at runtime, without reconfiguring
+// the provider: while suspended, no exposure events or custom events (see [Provider.Track]) are sent.
+// This is useful to keep load tests or canary deploys from polluting Amplitude.
+// Tracking is resumed by default; it still requires an analytics client (see [WithTrackingEnabled]).
+// Assignment events sent by the local evaluation SDK itself are not affected.
+// It is safe to call concurrently with evaluations.
+func (p *Provider) SetTrackingEnabled(enabled bool) {
+	p.trackingDisabled.Store(!enabled)
+}
+
+// trackingEnabled returns true if events are tracked: the analytics client is configured,
+// and tracking isn't suspended.
+func (p *Provider) trackingEnabled() bool {
+	return p.analyticsClient != nil && !p.trackingDisabled.Load()
+}
+
+// trackEvent sends the event to the analytics client.
+// A panic in the analytics client is logged and recovered,
+// so that tracking can never bring down flag evaluation or the caller.
+func (p *Provider) trackEvent(event analytics.Event) {
+	defer func() {
+		if r := recover(); r != nil {
+			p.getLogger().Error("amplitude: recovered from panic while tracking %s event: %v", event.EventType, r)
+		}
+	}()
 	p.analyticsClient.Track(event)
 }
 
-func (p *Provider) toAmplitudeEvent(ctx context.Context, trackingEventName string, evalCtx of.EvaluationContext, details of.TrackingEventDetails) (analytics.Event, error) {
-	attributes := evalCtx.Attributes()
-	if evalCtx.TargetingKey() != "" {
-		attributes[string(KeyUserID)] = evalCtx.TargetingKey()
+// trackExposureEvent tracks an exposure event, after modifying it with the customizer
+// set with [WithExposureEventCustomizer], if any. Like tracking, customizing the event
+// never interrupts evaluation: a panic is recovered and logged, and the event dropped.
+func (p *Provider) trackExposureEvent(event analytics.Event) {
+	if p.config.ExposureEventCustomizer != nil {
+		customized := func() (ok bool) {
+			defer func() {
+				if r := recover(); r != nil {
+					p.getLogger().Error("amplitude: recovered from panic while customizing %s event: %v", event.EventType, r)
+				}
+			}()
+			p.config.ExposureEventCustomizer(&event)
+			return true
+		}()
+		if !customized {
+			return
+		}
 	}
+	p.trackEvent(event)
+}
 
+func (p *Provider) toAmplitudeEvent(ctx context.Context, trackingEventName string, evalCtx of.EvaluationContext, details of.TrackingEventDetails) (analytics.Event, error) {
 	var event analytics.Event
 
-	eventMap, _ := p.normalizeContext(attributes)
-	eventMapJSON, err := json.Marshal(eventMap)
-	if err != nil {
-		return event, fmt.Errorf("failed to marshal event map: %w", err)
+	attributes := evalCtx.Attributes()
+	targetingKey := evalCtx.TargetingKey()
+	if p.config.ContextEnricher != nil {
+		flattened := of.FlattenedContext(maps.Clone(attributes))
+		flattened[of.TargetingKey] = targetingKey
+		enriched, err := p.enrichContext(ctx, flattened)
+		if err != nil {
+			return event, err
+		}
+		targetingKey, _ = enriched[of.TargetingKey].(string)
+		attributes = maps.Clone(enriched)
+		delete(attributes, of.TargetingKey)
 	}
-
-	err = json.Unmarshal(eventMapJSON, &event)
-	if err != nil {
-		return event, fmt.Errorf("failed to unmarshal event map: %w", err)
+	targetingKeyField := p.config.getTargetingKeyField()
+	if targetingKeyField == TargetingKeyIgnored {
+		targetingKey = ""
 	}
-
-	detailsMap, extraEventProperties  := p.normalizeContext(details.Attributes())
-	detailsMapJSON, err := json.Marshal(detailsMap)
-	if err != nil {
-		return event, fmt.Errorf("failed to marshal details map: %w", err)	
+	if targetingKey != "" {
+		attributes[string(targetingKeyField)] = targetingKey
 	}
-	err = json.Unmarshal(detailsMapJSON, &event)
-	if err != nil {
-		return event, fmt.Errorf("failed to unmarshal event details map: %w", err)
+
+	eventMap, _ := p.normalizeContext(attributes)
+	p.extractContextValues(ctx, eventMap, nil)
+	detailsMap, extraEventProperties := p.normalizeContext(details.Attributes())
+	mergeEventMaps(eventMap, detailsMap)
+	parseRevenueFields(eventMap)
+
+	// Decode the mapped keys into the event in a single round-trip.
+	if len(eventMap) > 0 {
+		eventMapJSON, err := json.Marshal(eventMap)
+		if err != nil {
+			return event, fmt.Errorf("failed to marshal event map: %w", err)
+		}
+		err = json.Unmarshal(eventMapJSON, &event)
+		if err != nil {
+			return event, fmt.Errorf("failed to unmarshal event map: %w", err)
+		}
 	}
 	if event.EventProperties == nil {
 		event.EventProperties = make(map[string]any, len(extraEventProperties))
@@ -502,8 +928,25 @@ func (p *Provider) toAmplitudeEvent(ctx context.Context, trackingEventName strin
 		event.EventProperties[k] = v
 	}
 
+	// A user ID in the context or details was decoded into EventOptions.UserID, which the analytics SDK
+	// prefers to event.UserID, so a user_id in the details would silently override the targeting key.
+	// Keep the user ID only on event.UserID, where the targeting key takes precedence.
+	decodedUserID := event.EventOptions.UserID
+	event.EventOptions.UserID = ""
+
 	// Assign the direct fields which may not have been set from the context or details.
-	event.UserID = evalCtx.TargetingKey()
+	switch targetingKeyField {
+	case TargetingKeyIgnored:
+	case KeyDeviceID:
+		event.DeviceID = targetingKey
+	default:
+		event.UserID = targetingKey
+	}
+	if event.UserID == "" {
+		event.UserID = decodedUserID
+	} else if decodedUserID != "" && decodedUserID != event.UserID {
+		p.getLogger().Warn("amplitude: the tracking event details have a %s attribute which differs from the targeting key; using the targeting key", KeyUserID)
+	}
 	event.EventType = trackingEventName
 
 	// Map the TrackingEventDetails value to the Amplitude revenue field.
@@ -514,7 +957,7 @@ func (p *Provider) toAmplitudeEvent(ctx context.Context, trackingEventName strin
 	}
 
 	if p.config.EventNormalizer != nil {
-		err = p.config.EventNormalizer(ctx, EventNormalizationContext{
+		err := p.config.EventNormalizer(ctx, EventNormalizationContext{
 			EvaluationContext: evalCtx,
 			TrackingKey:       trackingEventName,
 			Event:             &event,
@@ -528,64 +971,748 @@ func (p *Provider) toAmplitudeEvent(ctx context.Context, trackingEventName strin
 	return event, nil
 }
 
-// evaluateFlag evaluates a flag for the given context and returns the variant.
-// Returns nil variant (with no error) when the variant key is "off", indicating
+// parseRevenueFields parses numeric strings (such as form values) given for the price, quantity
+// and revenue of an event, which would otherwise fail to decode into the numeric fields of the event.
+// Values which aren't numbers are left as they are, to fail decoding.
+func parseRevenueFields(eventMap map[Key]any) {
+	for _, key := range []Key{KeyPrice, KeyRevenue} {
+		if str, ok := eventMap[key].(string); ok {
+			if value, err := strconv.ParseFloat(strings.TrimSpace(str), 64); err == nil {
+				eventMap[key] = value
+			}
+		}
+	}
+	if str, ok := eventMap[KeyQuantity].(string); ok {
+		if value, err := strconv.Atoi(strings.TrimSpace(str)); err == nil {
+			eventMap[KeyQuantity] = value
+		}
+	}
+}
+
+// mergeEventMaps merges the normalized tracking event details into the normalized
+// evaluation context, giving the same result as decoding one after the other into an event:
+// details take precedence, except that null details leave the context's value in place,
+// and objects present in both (such as event properties) are merged key by key.
+func mergeEventMaps(eventMap, detailsMap map[Key]any) {
+	for key, value := range detailsMap {
+		if value == nil {
+			if _, ok := eventMap[key]; ok {
+				continue
+			}
+		}
+		if existing, ok := eventMap[key].(map[string]any); ok {
+			if override, ok := value.(map[string]any); ok {
+				merged := maps.Clone(existing)
+				maps.Copy(merged, override)
+				eventMap[key] = merged
+				continue
+			}
+		}
+		eventMap[key] = value
+	}
+}
+
+// EvaluateAll evaluates all flags for the given context and returns the variants keyed by flag key.
+// Flags for which the user is not in the rollout have the "off" variant.
+// Unlike the typed evaluation methods, no exposure events are tracked,
+// unless [WithBatchedExposures] is set, in which case a single exposure event covers all the flags.
+// If [WithMaxFlagsPerEvaluation] is set, the number of flags returned is capped
+// (see [WithErrorOnMaxFlagsExceeded] for what happens when the cap is exceeded).
+func (p *Provider) EvaluateAll(ctx context.Context, evalCtx of.FlattenedContext) (map[string]experiment.Variant, error) {
+	variants, user, err := p.evaluateAll(ctx, evalCtx)
+	if err != nil {
+		return nil, err
+	}
+	p.observeEvaluationChanges(user, variants)
+	p.trackBatchedExposure(ctx, evalCtx, user, variants)
+	return variants, nil
+}
+
+// evaluateAll implements [Provider.EvaluateAll] without tracking exposures,
+// also returning the Amplitude user the flags were evaluated for.
+func (p *Provider) evaluateAll(ctx context.Context, evalCtx of.FlattenedContext) (map[string]experiment.Variant, *experiment.User, error) {
+	variants, user, err := p.evaluateVariants(ctx, evalCtx, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	maxFlags := p.config.MaxFlagsPerEvaluation
+	if maxFlags <= 0 || len(variants) <= maxFlags {
+		return variants, user, nil
+	}
+	if p.config.ErrorOnMaxFlagsExceeded {
+		return nil, nil, fmt.Errorf("%w: evaluated %d flags, but the maximum is %d", ErrMaxFlagsExceeded, len(variants), maxFlags)
+	}
+
+	// Truncate deterministically so that callers see the same subset of flags on every evaluation.
+	flagKeys := slices.Sorted(maps.Keys(variants))
+	p.getLogger().Warn("amplitude: evaluated %d flags, returning only the first %d (sorted by key)", len(variants), maxFlags)
+	truncated := make(map[string]experiment.Variant, maxFlags)
+	for _, flagKey := range flagKeys[:maxFlags] {
+		truncated[flagKey] = variants[flagKey]
+	}
+	return truncated, user, nil
+}
+
+// ActiveExperiments returns the variant key of every flag for which the user
+// is in a variant other than "off" (or Amplitude's default variant), keyed by flag key.
+// It is intended for building UIs listing the experiments a user is part of.
+// No exposure events are tracked (even with [WithBatchedExposures]), and [WithMaxFlagsPerEvaluation] applies.
+func (p *Provider) ActiveExperiments(ctx context.Context, evalCtx of.FlattenedContext) (map[string]string, error) {
+	variants, _, err := p.evaluateAll(ctx, evalCtx)
+	if err != nil {
+		return nil, err
+	}
+
+	active := make(map[string]string)
+	for flagKey, variant := range variants {
+		if p.isOffVariant(&variant) || isDefaultVariant(&variant) {
+			continue
+		}
+		active[flagKey] = variant.Key
+	}
+	return active, nil
+}
+
+// FlagConfigStats returns statistics about the flag configs used for local evaluation,
+// such as the number of flags and targeted cohorts and the approximate size of the configs,
+// to help decide when a deployment should switch to remote evaluation.
+// The statistics are computed from the flag configs held by the local evaluation client,
+// without contacting Amplitude; memory figures are approximate.
+// Zero values are returned for remote evaluation, or if the flag configs can't be retrieved
+// (in which case the error is logged).
+func (p *Provider) FlagConfigStats() FlagConfigStats {
+	statsProvider, ok := p.client.(flagConfigStatsProvider)
+	if !ok {
+		return FlagConfigStats{}
+	}
+	stats, err := statsProvider.flagConfigStats()
+	if err != nil {
+		p.getLogger().Error("amplitude: %s", err)
+		return FlagConfigStats{}
+	}
+	return stats
+}
+
+// ForceSync immediately refreshes the flag configs of local evaluation,
+// rather than waiting for the next poll, e.g. after editing flags in an admin UI.
+// It depends on the local SDK re-fetching flag configs when its client is started again,
+// and returns an error if cohort sync is configured, where that isn't safe.
+// Flag config change callbacks (see [WithFlagConfigChangeCallback]) run before it returns.
+// For remote evaluation, which always fetches the current flag configs, it does nothing.
+func (p *Provider) ForceSync(_ context.Context) error {
+	if p.state != of.ReadyState {
+		return errors.New(providerNotReady)
+	}
+	syncer, ok := p.client.(flagConfigSyncer)
+	if !ok {
+		return nil
+	}
+	return syncer.forceSync()
+}
+
+// EvaluateFlags evaluates the given flags for the given context and returns the variants keyed by flag key.
+// Flags which don't exist are omitted from the result.
+// Unlike the typed evaluation methods, no exposure events are tracked,
+// unless [WithBatchedExposures] is set, in which case a single exposure event covers all the flags.
+// Because the caller chooses the flags explicitly, [WithMaxFlagsPerEvaluation] does not apply.
+func (p *Provider) EvaluateFlags(ctx context.Context, evalCtx of.FlattenedContext, flags []string) (map[string]experiment.Variant, error) {
+	variants, user, err := p.evaluateFlags(ctx, evalCtx, flags)
+	if err != nil {
+		return nil, err
+	}
+	p.observeEvaluationChanges(user, variants)
+	p.trackBatchedExposure(ctx, evalCtx, user, variants)
+	return variants, nil
+}
+
+// evaluateFlags implements [Provider.EvaluateFlags] without tracking exposures,
+// also returning the Amplitude user the flags were evaluated for (nil if no flags were given).
+func (p *Provider) evaluateFlags(ctx context.Context, evalCtx of.FlattenedContext, flags []string) (map[string]experiment.Variant, *experiment.User, error) {
+	if len(flags) == 0 {
+		return map[string]experiment.Variant{}, nil, nil
+	}
+	variants, user, err := p.evaluateVariants(ctx, evalCtx, flags)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Remote evaluation returns all flags, so only keep the requested ones.
+	requested := make(map[string]experiment.Variant, len(flags))
+	for _, flag := range flags {
+		if variant, ok := variants[flag]; ok {
+			requested[flag] = variant
+		}
+	}
+	return requested, user, nil
+}
+
+// evaluateVariants evaluates the given flags (or all flags, if flags is empty) for the given context,
+// also returning the Amplitude user the flags were evaluated for.
+func (p *Provider) evaluateVariants(ctx context.Context, evalCtx of.FlattenedContext, flags []string) (map[string]experiment.Variant, *experiment.User, error) {
+	if p.state != of.ReadyState {
+		return nil, nil, p.stateError()
+	}
+
+	user, err := p.toAmplitudeUser(ctx, evalCtx)
+	if err != nil {
+		return nil, nil, of.NewInvalidContextResolutionError(err.Error())
+	}
+
+	if len(p.config.FlagAllowlist) > 0 {
+		flags = p.allowedFlags(flags)
+		if len(flags) == 0 {
+			return map[string]experiment.Variant{}, user, nil
+		}
+	}
+
+	variants, err := p.client.Evaluate(ctx, user, flags)
+	if err != nil {
+		return nil, nil, of.NewGeneralResolutionError(err.Error())
+	}
+	if len(p.config.FlagAllowlist) > 0 {
+		// Remote evaluation returns all flags, so drop the ones which aren't allowed.
+		maps.DeleteFunc(variants, func(flag string, _ experiment.Variant) bool {
+			return !p.flagAllowed(flag)
+		})
+	}
+	return variants, user, nil
+}
+
+// flagAllowed returns true if the flag may be evaluated; see [WithFlagAllowlist].
+func (p *Provider) flagAllowed(flag string) bool {
+	return len(p.config.FlagAllowlist) == 0 || slices.Contains(p.config.FlagAllowlist, flag)
+}
+
+// allowedFlags returns the given flags which may be evaluated,
+// or all the allowlisted flags if none are given.
+func (p *Provider) allowedFlags(flags []string) []string {
+	if len(flags) == 0 {
+		return slices.Clone(p.config.FlagAllowlist)
+	}
+	return slices.DeleteFunc(slices.Clone(flags), func(flag string) bool {
+		return !p.flagAllowed(flag)
+	})
+}
+
+// trackBatchedExposure tracks a single exposure event for all the given variants,
+// if [WithBatchedExposures] is set and tracking is enabled.
+// The event mirrors the per-flag exposure event, but with the evaluated flag keys
+// in "flag_keys" (sorted) and their variant keys in "variants", keyed by flag key.
+func (p *Provider) trackBatchedExposure(ctx context.Context, evalCtx of.FlattenedContext, user *experiment.User, variants map[string]experiment.Variant) {
+	if !p.config.BatchedExposures || !p.trackingEnabled() || user == nil || len(variants) == 0 || !exposuresAllowed(ctx, evalCtx) {
+		return
+	}
+
+	flagKeys := slices.Sorted(maps.Keys(variants))
+	variantKeys := make(map[string]string, len(variants))
+	for _, flagKey := range flagKeys {
+		variantKeys[flagKey] = variants[flagKey].Key
+	}
+	eventProperties := map[string]any{
+		"flag_keys": flagKeys,
+		"variants":  variantKeys,
+	}
+	p.addExposureContext(eventProperties, evalCtx)
+	event := newExposureEvent(user, eventProperties)
+	if p.config.CombinedExposureAssignment {
+		addAssignment(&event, variants)
+	}
+	p.trackExposureEvent(event)
+}
+
+// addAssignment adds the properties of an assignment event for the variants to an exposure event,
+// if [WithCombinedExposureAssignment] is set. Like the assignment events of the local evaluation SDK,
+// the event gets "<flag>.variant" and "<flag>.details" event properties, and sets (or, for default
+// variants, unsets) the "[Experiment] <flag>" user properties, except for mutual exclusion groups.
+func addAssignment(event *analytics.Event, variants map[string]experiment.Variant) {
+	set := make(map[string]any)
+	unset := make(map[string]any)
+	for flag, variant := range variants {
+		event.EventProperties[flag+".variant"] = variant.Key
+		version, _ := variant.Metadata["flagVersion"].(float64)
+		segmentName, _ := variant.Metadata["segmentName"].(string)
+		if version != 0 && segmentName != "" {
+			event.EventProperties[flag+".details"] = fmt.Sprintf("v%v rule:%v", version, segmentName)
+		}
+
+		if flagType, _ := variant.Metadata["flagType"].(string); flagType == "mutual-exclusion-group" {
+			continue
+		}
+		if isDefault, _ := variant.Metadata["default"].(bool); isDefault {
+			unset["[Experiment] "+flag] = "-"
+		} else {
+			set["[Experiment] "+flag] = variant.Key
+		}
+	}
+	event.UserProperties = map[analytics.IdentityOp]map[string]any{
+		"$set":   set,
+		"$unset": unset,
+	}
+}
+
+// flagEvaluation is the outcome of evaluating a single flag.
+type flagEvaluation struct {
+	// variant is the evaluated variant, or nil when the variant key is "off",
+	// indicating that the caller should use the default value.
+	variant *experiment.Variant
+	// normalizerApplied records whether a user normalizer ran while building the Amplitude user.
+	normalizerApplied bool
+	// reason is the reason reported for a successfully evaluated variant.
+	reason of.Reason
+	// duration is the time taken to evaluate the flag.
+	duration time.Duration
+	// offReason is the reason reported when the variant key is "off" and the default value is used.
+	offReason of.Reason
+	// user is the Amplitude user the flag was evaluated for.
+	user *experiment.User
+	// evaluated is the variant returned by Amplitude, including the "off" variant.
+	evaluated experiment.Variant
+	// forcedDefault is true when the flag is listed in [Config.ForcedDefaults],
+	// so the default value is used without consulting Amplitude.
+	forcedDefault bool
+	// forcedVariant is true when the variant was forced by the evaluation context.
+	forcedVariant bool
+	// defaultContextMerged is true when attributes from the Go context were merged into the user.
+	defaultContextMerged bool
+}
+
+// flagMetadata returns the flag metadata describing the evaluation.
+func (e *flagEvaluation) flagMetadata() of.FlagMetadata {
+	metadata := variantMetadata(e.variant)
+	if e.normalizerApplied {
+		metadata[MetadataKeyNormalizerApplied] = true
+	}
+	metadata[MetadataKeyEvalMs] = float64(e.duration) / float64(time.Millisecond)
+	if e.forcedVariant {
+		metadata[MetadataKeyForcedVariant] = true
+		metadata[MetadataKeyOverrideApplied] = true
+	}
+	if e.defaultContextMerged {
+		metadata[MetadataKeyDefaultContextMerged] = true
+	}
+	if e.variant != nil {
+		if version, ok := flagVersion(e.variant.Metadata["flagVersion"]); ok {
+			metadata[MetadataKeyFlagVersion] = version
+		}
+		if deployed, ok := e.variant.Metadata["deployed"].(bool); ok {
+			metadata[MetadataKeyDeployed] = deployed
+		}
+		if segmentName, ok := e.variant.Metadata["segmentName"].(string); ok && segmentName != "" {
+			metadata[MetadataKeySegmentName] = segmentName
+		}
+	}
+	return metadata
+}
+
+// resolutionDetail returns the resolution detail for a successfully evaluated variant.
+func (e *flagEvaluation) resolutionDetail() of.ProviderResolutionDetail {
+	return of.ProviderResolutionDetail{
+		Variant:      e.variant.Key,
+		Reason:       e.reason,
+		FlagMetadata: e.flagMetadata(),
+	}
+}
+
+// evaluateFlag evaluates a flag for the given context and returns the evaluation.
+// The evaluation has a nil variant (with no error) when the variant key is "off", indicating
 // that the caller should use the default value.
 // Returns a resolution error if something goes wrong.
-func (p *Provider) evaluateFlag(ctx context.Context, flag string, evalCtx of.FlattenedContext) (*experiment.Variant, *of.ResolutionError) {
+func (p *Provider) evaluateFlag(ctx context.Context, flag string, evalCtx of.FlattenedContext) (*flagEvaluation, *resolutionError) {
+	start := time.Now()
+	eval, resErr := p.resolveFlag(ctx, flag, evalCtx)
+	if p.config.Metrics != nil {
+		p.config.Metrics.RecordEvaluation(ctx, flag, decisionReason(eval, resErr), time.Since(start))
+	}
+	if p.config.DecisionAudit != nil {
+		p.auditDecision(ctx, flag, evalCtx, eval, resErr)
+	}
+	p.notifyFallback(flag, resErr)
+	return eval, resErr
+}
+
+// notifyFallback calls the fallback callback, if any, if the evaluation of the flag failed
+// and the fallback provider won't evaluate it instead; see [WithFallbackCallback].
+func (p *Provider) notifyFallback(flag string, resErr *resolutionError) {
+	if resErr == nil || p.config.FallbackCallback == nil || p.useFallback(resErr) {
+		return
+	}
+	p.config.FallbackCallback(flag, resErr.ResolutionError)
+}
+
+// notifyResultFallback calls the fallback callback, if any, if the typed evaluation methods
+// return the default value for an error found after the flag was evaluated, such as a payload
+// which doesn't match the requested type. Errors evaluating the flag are reported by [Provider.evaluateFlag].
+func (p *Provider) notifyResultFallback(flag string, resErr *resolutionError, detail of.ProviderResolutionDetail) {
+	if resErr != nil || p.config.FallbackCallback == nil || detail.Error() == nil {
+		return
+	}
+	p.config.FallbackCallback(flag, detail.ResolutionError)
+}
+
+// resolveFlag evaluates a flag for the given context; see [Provider.evaluateFlag].
+func (p *Provider) resolveFlag(ctx context.Context, flag string, evalCtx of.FlattenedContext) (*flagEvaluation, *resolutionError) {
+	start := time.Now()
+	// An empty flag key is a wiring bug in the caller, which Amplitude would report confusingly.
+	if flag == "" {
+		return nil, newResolutionError(of.GeneralCode, emptyFlagKey)
+	}
 	if p.state != of.ReadyState {
-		resErr := p.stateError()
-		return nil, &resErr
+		if value, ok := p.config.NotReadyDefaults[flag]; ok {
+			return notReadyDefaultEvaluation(value, time.Since(start)), nil
+		}
+		return nil, p.stateResolutionError()
+	}
+
+	if !p.flagAllowed(flag) {
+		return nil, newResolutionError(of.FlagNotFoundCode, fmt.Sprintf("flag %s is not in the flag allowlist", flag))
+	}
+
+	// Forced defaults are a local kill switch, so they don't depend on Amplitude at all.
+	if slices.Contains(p.config.ForcedDefaults, flag) {
+		return &flagEvaluation{
+			offReason:     of.DisabledReason,
+			forcedDefault: true,
+			duration:      time.Since(start),
+		}, nil
+	}
+
+	// Forced variants are chosen by the caller, so they don't depend on Amplitude either,
+	// and aren't exposures of the experiment.
+	if p.config.ContextForcedVariants {
+		if variantKey, ok := forcedVariantKey(evalCtx, flag); ok {
+			return p.forcedVariantEvaluation(variantKey, time.Since(start)), nil
+		}
 	}
 
-	user, userErr := p.toAmplitudeUser(ctx, evalCtx)
+	eval := &flagEvaluation{
+		normalizerApplied: p.config.UserNormalizer != nil,
+	}
+
+	var user *experiment.User
+	var userErr error
+	if slices.Contains(p.config.IdentityOnlyFlags, flag) {
+		user, userErr = p.toIdentityUser(ctx, evalCtx)
+	} else {
+		user, eval.defaultContextMerged, userErr = p.buildAmplitudeUser(ctx, evalCtx)
+	}
 	if userErr != nil {
-		resErr := of.NewInvalidContextResolutionError(userErr.Error())
-		return nil, &resErr
+		return nil, newResolutionError(of.InvalidContextCode, userErr.Error())
 	}
 
-	variants, evalErr := p.client.Evaluate(ctx, user, []string{flag})
+	if err := p.checkRequiredAttributes(flag, user); err != nil {
+		return nil, newResolutionError(of.InvalidContextCode, err.Error())
+	}
+
+	variants, evalErr := p.evaluateWithMemo(ctx, user, flag)
 	if evalErr != nil {
-		resErr := of.NewGeneralResolutionError(evalErr.Error())
-		return nil, &resErr
+		return nil, newResolutionError(of.GeneralCode, evalErr.Error())
 	}
 
+	// A nil variant map (which the SDK may return for some configs) reads as empty, like a missing flag.
 	variant, ok := variants[flag]
 	if !ok {
-		resErr := of.NewFlagNotFoundResolutionError(fmt.Sprintf("flag %s not found", flag))
-		return nil, &resErr
+		return nil, newResolutionError(of.FlagNotFoundCode, fmt.Sprintf("flag %s not found", flag))
+	}
+	eval.duration = time.Since(start)
+	eval.user = user
+	eval.evaluated = variant
+
+	p.observeEvaluationChanges(user, map[string]experiment.Variant{flag: variant})
+	p.trackExposure(ctx, evalCtx, user, flag, variant, time.Time{})
+
+	// When variant key is "off", Amplitude indicates the user is not in the rollout.
+	// Leave the variant nil to signal that the default value should be used.
+	if !p.isOffVariant(&variant) {
+		payload, payloadErr := p.resolvePayload(&variant)
+		if payloadErr != nil {
+			return nil, newResolutionError(of.ParseErrorCode, payloadErr.Error())
+		}
+		variant.Payload = payload
+		eval.variant = &variant
+		if p.config.ReasonMapper != nil {
+			eval.reason = p.config.ReasonMapper(flag, eval.variant)
+		}
+	} else {
+		eval.offReason = offVariantReason(&variant)
+	}
+
+	return eval, nil
+}
+
+// trackExposure tracks an exposure event for the flag, if tracking is enabled and the context isn't anonymous,
+// and passes it to the exposure sink, if any. If at isn't zero, it is used as the time of the event; otherwise the current time is used.
+func (p *Provider) trackExposure(ctx context.Context, evalCtx of.FlattenedContext, user *experiment.User, flag string, variant experiment.Variant, at time.Time) {
+	if !exposuresAllowed(ctx, evalCtx) {
+		return
+	}
+	p.sinkExposure(ctx, user, flag, variant, at)
+	if !p.trackingEnabled() {
+		return
+	}
+	// Backdated exposures are replayed deliberately, so they aren't deduplicated.
+	if p.exposureDeduplicator != nil && at.IsZero() && !p.exposureDeduplicator.shouldExpose(user, flag, variant) {
+		return
 	}
 
 	// Create the tracking event details for the exposure event.
 	// These fields are based on the documentation at 
 	// https://amplitude.com/docs/feature-experiment/under-the-hood/event-tracking#exposure-events
-	if p.analyticsClient != nil {
-		p.analyticsClient.Track(analytics.Event{
-			EventType: "$exposure",
-			UserID: user.UserId,
-			EventProperties: map[string]any{
-				"flag_key": flag,
-				"variant": variant.Key,
-				"metadata": variant.Metadata,
-			},
-		})
+	eventProperties := map[string]any{
+		"flag_key": flag,
+		"variant": variant.Key,
+		"metadata": variant.Metadata,
 	}
+	p.addExposureContext(eventProperties, evalCtx)
+	p.addExposureEventProperties(ctx, eventProperties, flag, variant, user)
+	event := newExposureEvent(user, eventProperties)
+	if p.config.CombinedExposureAssignment {
+		addAssignment(&event, map[string]experiment.Variant{flag: variant})
+	}
+	if !at.IsZero() {
+		event.Time = at.UnixMilli()
+	}
+	p.trackExposureEvent(event)
+}
 
-	// When variant key is "off", Amplitude indicates the user is not in the rollout.
-	// Return nil to signal that the default value should be used.
-	if variant.Key == variantKeyOff {
-		return nil, nil
+// newExposureEvent returns an exposure event for the user with the event properties.
+// Amplitude attributes events to the user ID or, without one, to the device ID, so the device ID
+// is always sent, so that exposures of device-only contexts can be attributed.
+func newExposureEvent(user *experiment.User, eventProperties map[string]any) analytics.Event {
+	event := analytics.Event{
+		EventType:       "$exposure",
+		EventProperties: eventProperties,
+	}
+	if user.UserId != "" {
+		event.UserID = user.UserId
 	}
+	if user.DeviceId != "" {
+		event.EventOptions.DeviceID = user.DeviceId
+	}
+	return event
+}
 
-	return &variant, nil
+// useFallback returns true if the evaluation should be delegated to the fallback provider,
+// which is only the case when the flag was not found.
+func (p *Provider) useFallback(resErr *resolutionError) bool {
+	return p.config.FallbackProvider != nil && resErr.code == of.FlagNotFoundCode
+}
+
+// resolutionError is an [of.ResolutionError] together with its error code, which ResolutionError
+// doesn't expose, so that the provider can act on the code without parsing the message.
+type resolutionError struct {
+	of.ResolutionError
+	// code is the error code of the ResolutionError, if known.
+	code of.ErrorCode
+}
+
+// newResolutionError returns a resolution error with the given code and message.
+func newResolutionError(code of.ErrorCode, msg string) *resolutionError {
+	var resErr of.ResolutionError
+	switch code {
+	case of.ProviderNotReadyCode:
+		resErr = of.NewProviderNotReadyResolutionError(msg)
+	case of.FlagNotFoundCode:
+		resErr = of.NewFlagNotFoundResolutionError(msg)
+	case of.ParseErrorCode:
+		resErr = of.NewParseErrorResolutionError(msg)
+	case of.TypeMismatchCode:
+		resErr = of.NewTypeMismatchResolutionError(msg)
+	case of.InvalidContextCode:
+		resErr = of.NewInvalidContextResolutionError(msg)
+	default:
+		code = of.GeneralCode
+		resErr = of.NewGeneralResolutionError(msg)
+	}
+	return &resolutionError{ResolutionError: resErr, code: code}
+}
+
+// addExposureEventProperties adds the properties computed by the function set with
+// [WithExposureEventPropertiesFunc], if any, to the properties of the exposure event of the flag,
+// except for the properties identifying the exposure.
+func (p *Provider) addExposureEventProperties(ctx context.Context, eventProperties map[string]any, flag string, variant experiment.Variant, user *experiment.User) {
+	if p.config.ExposureEventPropertiesFunc == nil {
+		return
+	}
+	for key, value := range p.config.ExposureEventPropertiesFunc(ctx, flag, &variant, user) {
+		switch key {
+		case "flag_key", "variant", "metadata":
+		default:
+			eventProperties[key] = value
+		}
+	}
+}
+
+// addExposureContext copies the evaluation context keys which are allowed on exposure events
+// (the reserved [ContextKeySurface] and any keys set with [WithExposureContextKeys])
+// into the event properties.
+func (p *Provider) addExposureContext(eventProperties map[string]any, evalCtx of.FlattenedContext) {
+	if surface, ok := evalCtx[ContextKeySurface]; ok {
+		eventProperties[ContextKeySurface] = surface
+	}
+	for _, key := range p.config.ExposureContextKeys {
+		if value, ok := evalCtx[key]; ok {
+			eventProperties[key] = value
+		}
+	}
+}
+
+// getLogger returns the provider's logger.
+// It falls back to a default logger if the provider was not constructed
+// via [NewFromConfig] (e.g. in tests), so methods can always log safely.
+func (p *Provider) getLogger() *logger.Logger {
+	if p.logger == nil {
+		return newLogger(logger.Unknown, nil)
+	}
+	return p.logger
+}
+
+// newLogger creates a logger from the level and provider configured on an Amplitude SDK config,
+// defaulting to logging errors to the standard library logger (with redaction) when they are unset.
+func newLogger(level logger.LogLevel, provider logger.LoggerProvider) *logger.Logger {
+	if level == logger.Unknown {
+		level = logger.Error
+	}
+	if provider == nil {
+		provider = newRedactingLoggerProvider(logger.NewDefault())
+	}
+	return logger.New(level, provider)
 }
 
 // stateError returns the appropriate resolution error based on provider state.
 func (p *Provider) stateError() of.ResolutionError {
+	return p.stateResolutionError().ResolutionError
+}
+
+// stateResolutionError is like [Provider.stateError], but also returns the error code.
+func (p *Provider) stateResolutionError() *resolutionError {
 	if p.state == of.NotReadyState {
-		return of.NewProviderNotReadyResolutionError(providerNotReady)
+		return newResolutionError(of.ProviderNotReadyCode, providerNotReady)
+	}
+	return newResolutionError(of.GeneralCode, generalError)
+}
+
+// flagVersion converts a flag version from variant metadata to an int64.
+// Versions decoded from JSON are float64, but may also be any integer type.
+func flagVersion(value any) (int64, bool) {
+	switch v := value.(type) {
+	case float64:
+		if v != math.Trunc(v) {
+			return 0, false
+		}
+		return int64(v), true
+	case int:
+		return int64(v), true
+	case int64:
+		return v, true
+	case json.Number:
+		version, err := v.Int64()
+		return version, err == nil
+	}
+	return 0, false
+}
+
+// forcedVariantKey returns the variant key which the context forces the flag to, if any;
+// see [ContextKeyForcedVariantPrefix] and [ContextKeyForcedVariants].
+func forcedVariantKey(evalCtx of.FlattenedContext, flag string) (string, bool) {
+	if variantKey, ok := evalCtx[ContextKeyForcedVariantPrefix+flag].(string); ok {
+		return variantKey, true
+	}
+	switch forced := evalCtx[ContextKeyForcedVariants].(type) {
+	case map[string]string:
+		variantKey, ok := forced[flag]
+		return variantKey, ok
+	case map[string]any:
+		variantKey, ok := forced[flag].(string)
+		return variantKey, ok
+	}
+	return "", false
+}
+
+// forcedVariantEvaluation returns the evaluation of a flag forced to the given variant key.
+// The variant has no payload, since the flag config isn't consulted.
+func (p *Provider) forcedVariantEvaluation(variantKey string, duration time.Duration) *flagEvaluation {
+	eval := &flagEvaluation{
+		forcedVariant: true,
+		duration:      duration,
+	}
+	variant := experiment.Variant{Key: variantKey, Value: variantKey}
+	eval.evaluated = variant
+	if p.isOffVariant(&variant) {
+		eval.offReason = of.StaticReason
+	} else {
+		eval.variant = &variant
+		eval.reason = of.StaticReason
+	}
+	return eval
+}
+
+// notReadyDefaultEvaluation returns the evaluation of a flag to the safe value configured with
+// [WithNotReadyDefaults]. The value is the payload of a variant without a key, as if Amplitude had
+// served it, except that integers are passed as [json.Number], which [Provider.IntEvaluation] and
+// [Provider.FloatEvaluation] accept without losing precision.
+func notReadyDefaultEvaluation(value any, duration time.Duration) *flagEvaluation {
+	switch v := value.(type) {
+	case int:
+		value = json.Number(strconv.Itoa(v))
+	case int64:
+		value = json.Number(strconv.FormatInt(v, 10))
+	}
+	variant := experiment.Variant{Payload: value}
+	return &flagEvaluation{
+		variant:   &variant,
+		evaluated: variant,
+		reason:    NotReadyDefaultReason,
+		duration:  duration,
+	}
+}
+
+// isAnonymous returns true if the context marks the user as anonymous; see [ContextKeyAnonymous].
+func isAnonymous(evalCtx of.FlattenedContext) bool {
+	anonymous, _ := evalCtx[ContextKeyAnonymous].(bool)
+	return anonymous
+}
+
+// exposuresAllowed returns false if exposures mustn't be tracked for the evaluation,
+// because the context is anonymous or exposures are suppressed with [ContextWithoutExposures]
+// or [ContextKeyNoExposure].
+func exposuresAllowed(ctx context.Context, evalCtx of.FlattenedContext) bool {
+	noExposure, _ := evalCtx[ContextKeyNoExposure].(bool)
+	return !isAnonymous(evalCtx) && !noExposure && ctx.Value(noExposuresContextKey{}) == nil
+}
+
+// isOffVariant returns true if the variant indicates that the user is not in the flag's rollout:
+// its key is "off", or one of the keys configured with [WithOffVariantKeys].
+func (p *Provider) isOffVariant(variant *experiment.Variant) bool {
+	if len(p.config.OffVariantKeys) == 0 {
+		return variant.Key == variantKeyOff
+	}
+	return slices.ContainsFunc(p.config.OffVariantKeys, func(key string) bool {
+		if p.config.OffVariantKeysIgnoreCase {
+			return strings.EqualFold(key, variant.Key)
+		}
+		return key == variant.Key
+	})
+}
+
+// offVariantReason returns the reason for an "off" variant.
+// Amplitude marks the variant as the default when the user fell through all targeting rules,
+// which is reported as [of.DefaultReason]. An "off" variant which isn't the default was assigned
+// by a matching rule (identified by its segment name), which is reported as [of.TargetingMatchReason].
+// Without metadata the two can't be distinguished, so [of.DefaultReason] is reported.
+func offVariantReason(variant *experiment.Variant) of.Reason {
+	segmentName, _ := variant.Metadata["segmentName"].(string)
+	if segmentName != "" && !isDefaultVariant(variant) {
+		return of.TargetingMatchReason
 	}
-	return of.NewGeneralResolutionError(generalError)
+	return of.DefaultReason
+}
+
+// isDefaultVariant returns true if Amplitude marked the variant as the default
+// because no targeting rule matched the user.
+func isDefaultVariant(variant *experiment.Variant) bool {
+	isDefault, _ := variant.Metadata["default"].(bool)
+	return isDefault
 }
 
 // variantMetadata returns the standard metadata for a variant.
@@ -596,18 +1723,52 @@ func variantMetadata(variant *experiment.Variant) map[string]any {
 	}
 }
 
+// BuildUser returns the Amplitude user which would be evaluated for the given context,
+// after applying the context enricher, key map and user normalizer.
+// It doesn't require the provider to be initialized, so it can be used to unit-test
+// a configuration, e.g. of [WithKeyMap] or [WithUserNormalizer], without evaluating flags.
+// [WithIdentityOnlyEvaluation] is not applied, as it depends on the flag.
+func (p *Provider) BuildUser(ctx context.Context, evalCtx of.FlattenedContext) (*experiment.User, error) {
+	return p.toAmplitudeUser(ctx, evalCtx)
+}
+
+// CacheKeyForContext returns the key under which remote evaluation results for the given context
+// are cached (see [WithRemoteEvaluationCache]), computed exactly as the provider does when evaluating,
+// including [WithCacheKeyAttributes]. Use it to inspect cache hits and misses, to pre-seed a
+// distributed cache, or to assert in tests that a change doesn't alter cache keys.
+// The key is a raw SHA-256 digest rather than text, so format it with %x to print it.
+// It returns [ErrCacheKeyUnavailable] for local evaluation.
+func (p *Provider) CacheKeyForContext(ctx context.Context, evalCtx of.FlattenedContext) (string, error) {
+	keyer, ok := p.client.(cacheKeyer)
+	if !ok {
+		return "", ErrCacheKeyUnavailable
+	}
+	user, err := p.toAmplitudeUser(ctx, evalCtx)
+	if err != nil {
+		return "", err
+	}
+	return keyer.cacheKey(user, nil)
+}
+
 // toAmplitudeUser converts an OpenFeature evaluation context to an Amplitude User.
 func (p *Provider) toAmplitudeUser(ctx context.Context, evalCtx of.FlattenedContext) (*experiment.User, error) {
-	userMap, userProperties := p.normalizeContext( evalCtx)
-	userMapJSON, err := json.Marshal(userMap)
+	user, _, err := p.buildAmplitudeUser(ctx, evalCtx)
+	return user, err
+}
+
+// buildAmplitudeUser converts an OpenFeature evaluation context to an Amplitude User like
+// [Provider.toAmplitudeUser], also returning whether attributes from the Go context were merged in.
+func (p *Provider) buildAmplitudeUser(ctx context.Context, evalCtx of.FlattenedContext) (*experiment.User, bool, error) {
+	evalCtx, err := p.enrichContext(ctx, evalCtx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal user map: %w", err)
+		return nil, false, err
 	}
 
+	userMap, userProperties := p.normalizeContext( evalCtx)
+	merged := p.extractContextValues(ctx, userMap, userProperties)
 	var user experiment.User
-	err = json.Unmarshal(userMapJSON, &user)
-	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal user map: %w", err)
+	if err := decodeUser(userMap, &user); err != nil {
+		return nil, false, err
 	}
 
 	// Ensure that we include the user properties if the context explicitly contained
@@ -619,24 +1780,212 @@ func (p *Provider) toAmplitudeUser(ctx context.Context, evalCtx of.FlattenedCont
 	for k, v := range userProperties {
 		user.UserProperties[k] = v
 	}
+	// The reserved keys control exposure tracking and forced variants rather than targeting.
+	delete(user.UserProperties, ContextKeySurface)
+	delete(user.UserProperties, ContextKeyAnonymous)
+	delete(user.UserProperties, ContextKeyNoExposure)
+	delete(user.UserProperties, ContextKeyForcedVariants)
+	maps.DeleteFunc(user.UserProperties, func(key string, _ any) bool {
+		return strings.HasPrefix(key, ContextKeyForcedVariantPrefix)
+	})
+
+	finished, err := p.finishAmplitudeUser(ctx, evalCtx, &user)
+	return finished, merged, err
+}
+
+// userStringFields returns pointers to the string fields of [experiment.User] by canonical key.
+var userStringFields = map[Key]func(user *experiment.User) *string{
+	KeyUserID:             func(user *experiment.User) *string { return &user.UserId },
+	KeyDeviceID:           func(user *experiment.User) *string { return &user.DeviceId },
+	KeyCountry:            func(user *experiment.User) *string { return &user.Country },
+	KeyRegion:             func(user *experiment.User) *string { return &user.Region },
+	KeyDMA:                func(user *experiment.User) *string { return &user.Dma },
+	KeyCity:               func(user *experiment.User) *string { return &user.City },
+	KeyLanguage:           func(user *experiment.User) *string { return &user.Language },
+	KeyPlatform:           func(user *experiment.User) *string { return &user.Platform },
+	KeyVersion:            func(user *experiment.User) *string { return &user.Version },
+	KeyOS:                 func(user *experiment.User) *string { return &user.Os },
+	KeyDeviceManufacturer: func(user *experiment.User) *string { return &user.DeviceManufacturer },
+	KeyDeviceBrand:        func(user *experiment.User) *string { return &user.DeviceBrand },
+	KeyDeviceModel:        func(user *experiment.User) *string { return &user.DeviceModel },
+	KeyCarrier:            func(user *experiment.User) *string { return &user.Carrier },
+	KeyLibrary:            func(user *experiment.User) *string { return &user.Library },
+}
+
+// decodeUser sets the fields of the user from the normalized context map.
+// String values of string fields are assigned directly, which is the common case on the hot path;
+// everything else (structured fields like groups and cohorts, or values of the wrong type)
+// is decoded via a JSON round-trip, exactly as if the whole map had been.
+func decodeUser(userMap map[Key]any, user *experiment.User) error {
+	var structured map[Key]any
+	for key, value := range userMap {
+		if field, ok := userStringFields[key]; ok {
+			if str, ok := value.(string); ok {
+				*field(user) = str
+				continue
+			}
+		}
+		if structured == nil {
+			structured = make(map[Key]any)
+		}
+		structured[key] = value
+	}
+	if structured == nil {
+		return nil
+	}
 
+	structuredJSON, err := json.Marshal(structured)
+	if err != nil {
+		return fmt.Errorf("failed to marshal user map: %w", err)
+	}
+	err = json.Unmarshal(structuredJSON, user)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal user map: %w", err)
+	}
+	return nil
+}
+
+// toIdentityUser converts an OpenFeature evaluation context to an Amplitude User
+// with only the identity fields (user ID and device ID) populated.
+// This avoids the cost of building the full user for flags which only target on identity
+// (see [WithIdentityOnlyEvaluation]).
+func (p *Provider) toIdentityUser(ctx context.Context, evalCtx of.FlattenedContext) (*experiment.User, error) {
+	evalCtx, err := p.enrichContext(ctx, evalCtx)
+	if err != nil {
+		return nil, err
+	}
+
+	// The context is normalized as for the full user, so that the targeting key
+	// takes precedence over a user_id attribute in the same way.
+	normalized, _ := p.normalizeContext(evalCtx)
+	var user experiment.User
+	user.UserId, _ = normalized[KeyUserID].(string)
+	user.DeviceId, _ = normalized[KeyDeviceID].(string)
+
+	return p.finishAmplitudeUser(ctx, evalCtx, &user)
+}
+
+// extractContextValues adds the attributes returned by the context value extractors
+// (see [WithContextValueExtractors]) to the normalized context, unless it already has them.
+// Attributes for Amplitude fields are added to normalized; others are added to extra,
+// unless it is nil. It returns whether any attribute was added.
+func (p *Provider) extractContextValues(ctx context.Context, normalized map[Key]any, extra map[string]any) bool {
+	added := false
+	for _, extractor := range p.config.ContextValueExtractors {
+		key, value, ok := extractor(ctx)
+		if !ok {
+			continue
+		}
+		if slices.Contains(allKeys, key) {
+			if _, exists := normalized[key]; !exists {
+				normalized[key] = value
+				added = true
+			}
+		} else if extra != nil {
+			if _, exists := extra[string(key)]; !exists {
+				extra[string(key)] = value
+				added = true
+			}
+		}
+	}
+	return added
+}
+
+// enrichContext applies the context enricher (see [WithContextEnricher]), if any, to the evaluation context.
+func (p *Provider) enrichContext(ctx context.Context, evalCtx of.FlattenedContext) (of.FlattenedContext, error) {
+	if p.config.ContextEnricher == nil {
+		return evalCtx, nil
+	}
+	enriched, err := p.config.ContextEnricher(ctx, evalCtx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to enrich context: %w", err)
+	}
+	return enriched, nil
+}
+
+// finishAmplitudeUser applies the user normalizer and validation to a user built from the evaluation context.
+func (p *Provider) finishAmplitudeUser(ctx context.Context, evalCtx of.FlattenedContext, user *experiment.User) (*experiment.User, error) {
 	if p.config.UserNormalizer != nil {
-		err = p.config.UserNormalizer(ctx, UserNormalizationContext{
+		err := p.config.UserNormalizer(ctx, UserNormalizationContext{
 			EvaluationContext: evalCtx,
-			User:              &user,
+			User:              user,
 		})
 		if err != nil {
 			return nil, fmt.Errorf("failed to normalize user: %w", err)
 		}
 	}
 
+	if p.config.PlatformValidation != ValidationDisabled {
+		if platformErr := validatePlatform(user.Platform); platformErr != nil {
+			if p.config.PlatformValidation == ValidationError {
+				return nil, platformErr
+			}
+			p.getLogger().Warn("amplitude: %s", platformErr)
+		}
+	}
+
 	if user.UserId == "" && user.DeviceId == "" {
 		return nil, fmt.Errorf("context must contain a %s, %s, or %s", of.TargetingKey, KeyUserID, KeyDeviceID)
 	}
+	if err := p.checkStickyBucketingKeys(user); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// checkStickyBucketingKeys returns an error if any of the sticky bucketing keys (see [WithStickyBucketing])
+// is missing from the user, which would otherwise re-bucket the user.
+func (p *Provider) checkStickyBucketingKeys(user *experiment.User) error {
+	var missing []string
+	for _, key := range p.config.StickyBucketingKeys {
+		field, ok := userStringFields[key]
+		if ok && *field(user) == "" {
+			missing = append(missing, string(key))
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("sticky bucketing requires %s, which the context is missing", strings.Join(missing, ", "))
+	}
+	return nil
+}
 
-	return &user, nil
+// checkRequiredAttributes returns an error naming the attributes required for the flag
+// (see [WithRequiredAttributes]) which are missing from the user.
+func (p *Provider) checkRequiredAttributes(flag string, user *experiment.User) error {
+	var missing []string
+	for _, key := range p.config.RequiredAttributes[flag] {
+		if !userHasAttribute(user, key) {
+			missing = append(missing, string(key))
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("flag %s requires %s, which the context is missing", flag, strings.Join(missing, ", "))
+	}
+	return nil
 }
 
+// userHasAttribute returns true if the user has a non-empty value for the key:
+// one of its fields, or a user property for keys which aren't fields of the user.
+func userHasAttribute(user *experiment.User, key Key) bool {
+	if field, ok := userStringFields[key]; ok {
+		return *field(user) != ""
+	}
+	switch key {
+	case KeyUserProperties:
+		return len(user.UserProperties) > 0
+	case KeyGroupProperties:
+		return len(user.GroupProperties) > 0
+	case KeyGroups:
+		return len(user.Groups) > 0
+	case KeyCohortIDs:
+		return len(user.CohortIds) > 0
+	case KeyGroupCohortIDSet:
+		return len(user.GroupCohortIds) > 0
+	}
+	_, ok := user.UserProperties[string(key)]
+	return ok
+}
 
 // normalizeContext normalizes the context map into an Amplitude User or Event.
 // It returns a map of the normalized keys and a map of the extra keys.
@@ -645,13 +1994,71 @@ func (p *Provider) normalizeContext(contextMap map[string]any) (normalized map[K
 	normalizedMap := make(map[Key]any, len(contextMap)+1)
 	extraMap := make(map[string]any)
 	keyMap := p.config.getKeyMap()
+	var foldedKeys []string
 	for key, val := range contextMap {
-		resolvedKey, ok := keyMap[key]
-		if ok {
+		if key == of.TargetingKey {
+			continue
+		}
+		resolvedKey, ok := p.resolveKey(keyMap, key)
+		switch {
+		case ok:
 			normalizedMap[resolvedKey] = val
-		} else {
+		case p.foldedKeyMap != nil && p.foldedKeyMap[foldKey(key)] != "":
+			foldedKeys = append(foldedKeys, key)
+		default:
 			extraMap[key] = val
 		}
 	}
+
+	// Keys matched ignoring case are applied after exact matches, in sorted order,
+	// so that the field they populate doesn't depend on the iteration order of the context.
+	slices.Sort(foldedKeys)
+	for _, key := range foldedKeys {
+		resolvedKey, _ := p.resolveKeyIgnoringCase(key)
+		if _, conflict := normalizedMap[resolvedKey]; conflict {
+			p.getLogger().Warn("amplitude: ignoring the evaluation context key %q, which matches %s ignoring case, since another key already set it", key, resolvedKey)
+			continue
+		}
+		normalizedMap[resolvedKey] = contextMap[key]
+	}
+
+	// The targeting key is applied last, so that it deterministically takes precedence
+	// over an attribute mapped to the same field, such as an explicit user_id.
+	targetingKey, ok := contextMap[of.TargetingKey]
+	if !ok || p.config.TargetingKeyField == TargetingKeyIgnored {
+		return normalizedMap, extraMap
+	}
+	resolvedKey, ok := p.resolveKey(keyMap, of.TargetingKey)
+	if !ok {
+		extraMap[of.TargetingKey] = targetingKey
+		return normalizedMap, extraMap
+	}
+	if existing, conflict := normalizedMap[resolvedKey]; conflict && !reflect.DeepEqual(existing, targetingKey) {
+		p.getLogger().Warn("amplitude: the evaluation context has a %s attribute which differs from the targeting key; using the targeting key", resolvedKey)
+	}
+	normalizedMap[resolvedKey] = targetingKey
 	return normalizedMap, extraMap
 }
+
+// resolveKey resolves a context key to its canonical key using the key map.
+func (p *Provider) resolveKey(keyMap map[string]Key, key string) (Key, bool) {
+	// An explicitly configured targeting key field takes precedence over the key map.
+	if key == of.TargetingKey && p.config.TargetingKeyField != "" {
+		return p.config.TargetingKeyField, p.config.TargetingKeyField != TargetingKeyIgnored
+	}
+	if p.config.DeviceIDKey != "" && key == p.config.DeviceIDKey {
+		return KeyDeviceID, true
+	}
+	resolvedKey, ok := keyMap[key]
+	return resolvedKey, ok
+}
+
+// resolveKeyIgnoringCase resolves a context key which isn't in the key map to its canonical key,
+// ignoring case and separators, if [WithCaseInsensitiveKeyMatching] is set.
+func (p *Provider) resolveKeyIgnoringCase(key string) (Key, bool) {
+	if p.foldedKeyMap == nil {
+		return "", false
+	}
+	resolvedKey, ok := p.foldedKeyMap[foldKey(key)]
+	return resolvedKey, ok
+}
@@ -2,11 +2,24 @@ package amplitude
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	analytics "github.com/amplitude/analytics-go/amplitude"
 	experiment "github.com/amplitude/experiment-go-server/pkg/experiment"
+	"github.com/amplitude/experiment-go-server/pkg/logger"
 	of "github.com/open-feature/go-sdk/openfeature"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -19,16 +32,27 @@ func withMockClient(mock *mockClientAdapter) func(*Config) {
 	}
 }
 
-// newTestProvider creates a provider with a mock client for testing.
-func newTestProvider(t *testing.T, mock *mockClientAdapter) *Provider {
+// newTestProvider creates and initializes a provider with a mock client and the options for testing.
+func newTestProvider(t *testing.T, mock *mockClientAdapter, options ...Option) *Provider {
 	t.Helper()
 
-	provider, err := New(context.Background(), "test-deployment-key", withMockClient(mock))
+	provider, err := New(context.Background(), "test-deployment-key", append([]Option{withMockClient(mock)}, options...)...)
 	require.NoError(t, err)
 	require.NoError(t, provider.Init(of.EvaluationContext{}))
 	return provider
 }
 
+// newTrackingTestProvider is like newTestProvider, but tracks events with a mock analytics client,
+// which is also returned.
+func newTrackingTestProvider(t *testing.T, mock *mockClientAdapter, options ...Option) (*Provider, *mockAnalyticsClient) {
+	t.Helper()
+
+	provider := newTestProvider(t, mock, options...)
+	analyticsClient := &mockAnalyticsClient{}
+	provider.analyticsClient = analyticsClient
+	return provider, analyticsClient
+}
+
 func TestNew(t *testing.T) {
 	tests := []struct {
 		name          string
@@ -107,13 +131,180 @@ func TestProvider_Init(t *testing.T) {
 	}
 }
 
+func TestProvider_InitRetry(t *testing.T) {
+	t.Run("retries until the client starts", func(t *testing.T) {
+		attempts := 0
+		mock := &mockClientAdapter{
+			StartFunc: func() error {
+				attempts++
+				if attempts <= 2 {
+					return errMockStart
+				}
+				return nil
+			},
+		}
+		provider, err := New(context.Background(), "test-key", withMockClient(mock), WithInitRetry(3, time.Millisecond))
+		require.NoError(t, err)
+
+		require.NoError(t, provider.Init(of.EvaluationContext{}))
+		assert.Equal(t, 3, attempts)
+		assert.Equal(t, of.ReadyState, provider.state)
+	})
+
+	t.Run("gives up after the maximum attempts", func(t *testing.T) {
+		attempts := 0
+		mock := &mockClientAdapter{
+			StartFunc: func() error {
+				attempts++
+				return errMockStart
+			},
+		}
+		provider, err := New(context.Background(), "test-key", withMockClient(mock), WithInitRetry(2, time.Millisecond))
+		require.NoError(t, err)
+
+		initErr := provider.Init(of.EvaluationContext{})
+		assert.Equal(t, errMockStart, initErr)
+		assert.Equal(t, 2, attempts)
+		assert.Equal(t, of.ErrorState, provider.state)
+	})
+
+	t.Run("doubles the delay between attempts", func(t *testing.T) {
+		var startTimes []time.Time
+		mock := &mockClientAdapter{
+			StartFunc: func() error {
+				startTimes = append(startTimes, time.Now())
+				return errMockStart
+			},
+		}
+		provider, err := New(context.Background(), "test-key", withMockClient(mock), WithInitRetry(3, 10*time.Millisecond))
+		require.NoError(t, err)
+
+		require.Error(t, provider.Init(of.EvaluationContext{}))
+		require.Len(t, startTimes, 3)
+		assert.GreaterOrEqual(t, startTimes[1].Sub(startTimes[0]), 10*time.Millisecond)
+		assert.GreaterOrEqual(t, startTimes[2].Sub(startTimes[1]), 20*time.Millisecond)
+	})
+
+	t.Run("the start timeout stops the retries", func(t *testing.T) {
+		attempts := 0
+		mock := &mockClientAdapter{
+			StartFunc: func() error {
+				attempts++
+				return errMockStart
+			},
+		}
+		provider, err := New(context.Background(), "test-key", withMockClient(mock),
+			WithInitRetry(10, 20*time.Millisecond), WithStartTimeout(50*time.Millisecond))
+		require.NoError(t, err)
+
+		start := time.Now()
+		initErr := provider.Init(of.EvaluationContext{})
+
+		assert.ErrorIs(t, initErr, context.DeadlineExceeded)
+		assert.ErrorIs(t, initErr, errMockStart)
+		assert.Less(t, time.Since(start), time.Second)
+		assert.Less(t, attempts, 10)
+		assert.Equal(t, of.ErrorState, provider.state)
+	})
+
+	t.Run("a done context stops the retries", func(t *testing.T) {
+		mock := &mockClientAdapter{
+			StartFunc: func() error {
+				return errMockStart
+			},
+		}
+		provider, err := New(context.Background(), "test-key", withMockClient(mock), WithInitRetry(3, time.Minute))
+		require.NoError(t, err)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		initErr := provider.InitWithContext(ctx, of.EvaluationContext{})
+
+		assert.ErrorIs(t, initErr, context.Canceled)
+		assert.ErrorIs(t, initErr, errMockStart)
+	})
+}
+
 func TestProvider_Shutdown(t *testing.T) {
-	mock := &mockClientAdapter{}
-	provider := newTestProvider(t, mock)
+	t.Run("stops the client and flushes events", func(t *testing.T) {
+		mock := &mockClientAdapter{}
+		provider, analyticsClient := newTrackingTestProvider(t, mock)
+
+		assert.Equal(t, of.ReadyState, provider.state)
+		provider.Shutdown()
+
+		assert.True(t, mock.stopCalled)
+		assert.True(t, analyticsClient.shutdownCalled)
+		assert.Equal(t, of.NotReadyState, provider.state)
+	})
+
+	t.Run("logs stop errors", func(t *testing.T) {
+		mock := &mockClientAdapter{StopFunc: func() error { return errors.New("stop failed") }}
+		provider := newTestProvider(t, mock)
+		loggerProvider := &recordingLoggerProvider{}
+		provider.logger = logger.New(logger.Error, loggerProvider)
+
+		provider.Shutdown()
+
+		require.Len(t, loggerProvider.errors, 1)
+		assert.Contains(t, loggerProvider.errors[0], "stop failed")
+		assert.Equal(t, of.NotReadyState, provider.state)
+	})
+}
+
+func TestProvider_InitAfterShutdown(t *testing.T) {
+	var mu sync.Mutex
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		bodies = append(bodies, string(body))
+		mu.Unlock()
+		_, _ = w.Write([]byte(`{"code":200}`))
+	}))
+	defer server.Close()
+	provider := newTestProvider(t, &mockClientAdapter{}, WithTrackingEnabled(analytics.Config{
+		APIKey:    "api-key",
+		ServerURL: server.URL,
+	}))
+	// The mock client adapter skips creating the analytics client, as NewFromConfig would.
+	provider.analyticsClient = analytics.NewClient(*provider.config.AnalyticsConfig)
 
-	assert.Equal(t, of.ReadyState, provider.state)
 	provider.Shutdown()
-	assert.Equal(t, of.NotReadyState, provider.state)
+	require.NoError(t, provider.Init(of.EvaluationContext{}))
+	provider.Track(context.Background(), "purchase", of.NewEvaluationContext("user-1", nil), of.NewTrackingEventDetails(1))
+	provider.Shutdown()
+
+	// The analytics client sends the flushed events asynchronously.
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(bodies) == 1 && strings.Contains(bodies[0], `"event_type":"purchase"`)
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestProvider_Close(t *testing.T) {
+	t.Run("stops the client and flushes events", func(t *testing.T) {
+		mock := &mockClientAdapter{}
+		provider, analyticsClient := newTrackingTestProvider(t, mock)
+
+		var closer io.Closer = provider
+		require.NoError(t, closer.Close())
+
+		assert.True(t, mock.stopCalled)
+		assert.True(t, analyticsClient.shutdownCalled)
+		assert.Equal(t, of.NotReadyState, provider.state)
+	})
+
+	t.Run("returns stop errors", func(t *testing.T) {
+		mock := &mockClientAdapter{StopFunc: func() error { return errors.New("stop failed") }}
+		provider := newTestProvider(t, mock)
+
+		err := provider.Close()
+
+		assert.ErrorContains(t, err, "stop failed")
+		assert.Equal(t, of.NotReadyState, provider.state)
+	})
 }
 
 func TestProvider_Hooks(t *testing.T) {
@@ -132,6 +323,16 @@ func TestProvider_Metadata(t *testing.T) {
 	assert.Equal(t, "Amplitude", metadata.Name)
 }
 
+func TestProvider_Metadata_ProviderName(t *testing.T) {
+	provider, err := New(context.Background(), "test-key",
+		withMockClient(&mockClientAdapter{}),
+		WithProviderName("Amplitude[tenant-a]"),
+	)
+	require.NoError(t, err)
+
+	assert.Equal(t, "Amplitude[tenant-a]", provider.Metadata().Name)
+}
+
 func TestProvider_BooleanEvaluation(t *testing.T) {
 	tests := []struct {
 		name          string
@@ -416,7 +617,29 @@ func TestProvider_FloatEvaluation(t *testing.T) {
 			reason:        of.DefaultReason,
 		},
 		{
-			name:         "returns default when payload is wrong type",
+			name:         "parses integer string payload",
+			flagName:     "test-flag",
+			defaultValue: 0.0,
+			evalCtx:      of.FlattenedContext{of.TargetingKey: "user-1"},
+			variants: map[string]experiment.Variant{
+				"test-flag": makeVariant("variant-a", "value-a", "3"),
+			},
+			expectedValue: 3.0,
+			expectedError: false,
+		},
+		{
+			name:         "parses decimal string payload",
+			flagName:     "test-flag",
+			defaultValue: 0.0,
+			evalCtx:      of.FlattenedContext{of.TargetingKey: "user-1"},
+			variants: map[string]experiment.Variant{
+				"test-flag": makeVariant("variant-a", "value-a", "2.5"),
+			},
+			expectedValue: 2.5,
+			expectedError: false,
+		},
+		{
+			name:         "returns default when string payload is not a number",
 			flagName:     "test-flag",
 			defaultValue: 1.0,
 			evalCtx:      of.FlattenedContext{of.TargetingKey: "user-1"},
@@ -427,6 +650,18 @@ func TestProvider_FloatEvaluation(t *testing.T) {
 			expectedError: true,
 			reason:        of.ErrorReason,
 		},
+		{
+			name:         "returns default when payload is wrong type",
+			flagName:     "test-flag",
+			defaultValue: 1.0,
+			evalCtx:      of.FlattenedContext{of.TargetingKey: "user-1"},
+			variants: map[string]experiment.Variant{
+				"test-flag": makeVariant("variant-a", "value-a", true),
+			},
+			expectedValue: 1.0,
+			expectedError: true,
+			reason:        of.ErrorReason,
+		},
 		{
 			name:         "returns default when variant is off",
 			flagName:     "test-flag",
@@ -735,6 +970,142 @@ func TestProvider_stateError(t *testing.T) {
 	}
 }
 
+func TestProvider_NotReadyDefaults(t *testing.T) {
+	mock := &mockClientAdapter{
+		EvaluateFunc: func(context.Context, *experiment.User, []string) (map[string]experiment.Variant, error) {
+			return map[string]experiment.Variant{"bool-flag": makeVariant("on", "on", true)}, nil
+		},
+	}
+	newProvider := func(t *testing.T, options ...Option) *Provider {
+		t.Helper()
+		provider, err := New(context.Background(), "test-key", append(options, withMockClient(mock))...)
+		require.NoError(t, err)
+		return provider
+	}
+	evalCtx := of.FlattenedContext{of.TargetingKey: "user-1"}
+
+	t.Run("listed flags resolve to their safe value before Init", func(t *testing.T) {
+		provider := newProvider(t, WithNotReadyDefaults(map[string]any{
+			"bool-flag":   false,
+			"string-flag": "safe",
+		}), WithNotReadyDefaults(map[string]any{
+			"int-flag":   int64(9007199254740993),
+			"float-flag": 0.5,
+		}))
+
+		boolResult := provider.BooleanEvaluation(context.Background(), "bool-flag", true, evalCtx)
+		stringResult := provider.StringEvaluation(context.Background(), "string-flag", "default", evalCtx)
+		intResult := provider.IntEvaluation(context.Background(), "int-flag", 1, evalCtx)
+		floatResult := provider.FloatEvaluation(context.Background(), "float-flag", 1, evalCtx)
+
+		assert.NoError(t, boolResult.Error())
+		assert.False(t, boolResult.Value)
+		assert.Equal(t, NotReadyDefaultReason, boolResult.Reason)
+		assert.Equal(t, "safe", stringResult.Value)
+		assert.Equal(t, int64(9007199254740993), intResult.Value)
+		assert.Equal(t, 0.5, floatResult.Value)
+		assert.Equal(t, NotReadyDefaultReason, floatResult.Reason)
+		assert.Empty(t, mock.evaluateCalls)
+	})
+
+	t.Run("other flags still fail", func(t *testing.T) {
+		provider := newProvider(t, WithNotReadyDefaults(map[string]any{"bool-flag": false}))
+
+		result := provider.StringEvaluation(context.Background(), "string-flag", "default", evalCtx)
+
+		assert.Equal(t, "default", result.Value)
+		assert.Equal(t, of.ErrorReason, result.Reason)
+		assert.Contains(t, result.Error().Error(), providerNotReady)
+	})
+
+	t.Run("without safe values, not-ready evaluations fail", func(t *testing.T) {
+		result := newProvider(t).BooleanEvaluation(context.Background(), "bool-flag", true, evalCtx)
+
+		assert.True(t, result.Value)
+		assert.Equal(t, of.ErrorReason, result.Reason)
+	})
+
+	t.Run("safe values aren't used once ready", func(t *testing.T) {
+		provider := newProvider(t, WithNotReadyDefaults(map[string]any{"bool-flag": false}))
+		require.NoError(t, provider.Init(of.EvaluationContext{}))
+
+		result := provider.BooleanEvaluation(context.Background(), "bool-flag", false, evalCtx)
+
+		assert.True(t, result.Value)
+		assert.NotEqual(t, NotReadyDefaultReason, result.Reason)
+	})
+}
+
+func TestProvider_EmptyFlagKey(t *testing.T) {
+	mock := &mockClientAdapter{
+		EvaluateFunc: func(context.Context, *experiment.User, []string) (map[string]experiment.Variant, error) {
+			return map[string]experiment.Variant{"": makeVariant("on", "on", true)}, nil
+		},
+	}
+	provider := newTestProvider(t, mock)
+	evalCtx := of.FlattenedContext{of.TargetingKey: "user-1"}
+
+	tests := []struct {
+		name     string
+		evaluate func() (any, of.ProviderResolutionDetail)
+		expected any
+	}{
+		{
+			name: "boolean",
+			evaluate: func() (any, of.ProviderResolutionDetail) {
+				result := provider.BooleanEvaluation(context.Background(), "", false, evalCtx)
+				return result.Value, result.ProviderResolutionDetail
+			},
+			expected: false,
+		},
+		{
+			name: "string",
+			evaluate: func() (any, of.ProviderResolutionDetail) {
+				result := provider.StringEvaluation(context.Background(), "", "default", evalCtx)
+				return result.Value, result.ProviderResolutionDetail
+			},
+			expected: "default",
+		},
+		{
+			name: "float",
+			evaluate: func() (any, of.ProviderResolutionDetail) {
+				result := provider.FloatEvaluation(context.Background(), "", 1.5, evalCtx)
+				return result.Value, result.ProviderResolutionDetail
+			},
+			expected: 1.5,
+		},
+		{
+			name: "int",
+			evaluate: func() (any, of.ProviderResolutionDetail) {
+				result := provider.IntEvaluation(context.Background(), "", 3, evalCtx)
+				return result.Value, result.ProviderResolutionDetail
+			},
+			expected: int64(3),
+		},
+		{
+			name: "object",
+			evaluate: func() (any, of.ProviderResolutionDetail) {
+				result := provider.ObjectEvaluation(context.Background(), "", "default", evalCtx)
+				return result.Value, result.ProviderResolutionDetail
+			},
+			expected: "default",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, detail := tt.evaluate()
+
+			assert.Equal(t, tt.expected, value)
+			assert.Equal(t, of.ErrorReason, detail.Reason)
+			require.Error(t, detail.Error())
+			assert.Contains(t, detail.Error().Error(), string(of.GeneralCode))
+			assert.Contains(t, detail.Error().Error(), "flag key must not be empty")
+		})
+	}
+	assert.Empty(t, mock.evaluateCalls, "Amplitude isn't called")
+}
+
 func TestVariantMetadata(t *testing.T) {
 	variant := &experiment.Variant{
 		Key:   "test-key",
@@ -1192,3 +1563,2125 @@ func TestProvider_toAmplitudeEvent(t *testing.T) {
 }
 
 
+
+func TestProvider_toAmplitudeEvent_RevenueFields(t *testing.T) {
+	tests := []struct {
+		name             string
+		details          of.TrackingEventDetails
+		expectedPrice    float64
+		expectedQuantity int
+		expectedRevenue  float64
+	}{
+		{
+			name:             "numeric attributes",
+			details:          of.NewTrackingEventDetails(0).Add("price", 19.99).Add("quantity", 2).Add("revenue", 39.98),
+			expectedPrice:    19.99,
+			expectedQuantity: 2,
+			expectedRevenue:  39.98,
+		},
+		{
+			name:             "whole float quantity",
+			details:          of.NewTrackingEventDetails(0).Add("quantity", 3.0),
+			expectedQuantity: 3,
+		},
+		{
+			name:             "numeric strings",
+			details:          of.NewTrackingEventDetails(0).Add("price", "19.99").Add("quantity", " 2").Add("revenue", "39.98"),
+			expectedPrice:    19.99,
+			expectedQuantity: 2,
+			expectedRevenue:  39.98,
+		},
+		{
+			name:             "the details value takes precedence over the revenue attribute",
+			details:          of.NewTrackingEventDetails(50).Add("price", 25.0).Add("quantity", 2).Add("revenue", 40.0),
+			expectedPrice:    25,
+			expectedQuantity: 2,
+			expectedRevenue:  50,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := newTestProvider(t, &mockClientAdapter{})
+
+			event, err := provider.toAmplitudeEvent(context.Background(), "purchase", of.NewEvaluationContext("buyer-1", nil), tt.details)
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedPrice, event.Price)
+			assert.Equal(t, tt.expectedQuantity, event.Quantity)
+			assert.Equal(t, tt.expectedRevenue, event.Revenue)
+			assert.NotContains(t, event.EventProperties, "price")
+			assert.NotContains(t, event.EventProperties, "quantity")
+			assert.NotContains(t, event.EventProperties, "revenue")
+		})
+	}
+
+	t.Run("a fractional quantity fails the event", func(t *testing.T) {
+		provider := newTestProvider(t, &mockClientAdapter{})
+
+		_, err := provider.toAmplitudeEvent(context.Background(), "purchase", of.NewEvaluationContext("buyer-1", nil),
+			of.NewTrackingEventDetails(0).Add("quantity", 2.5))
+
+		assert.ErrorContains(t, err, "quantity")
+	})
+}
+
+func TestProvider_FlagMetadata_NormalizerApplied(t *testing.T) {
+	variants := map[string]experiment.Variant{
+		"test-flag": makeVariant("on", "on", true),
+	}
+	evalCtx := of.FlattenedContext{of.TargetingKey: "user-1"}
+
+	t.Run("absent without a normalizer", func(t *testing.T) {
+		mock := &mockClientAdapter{
+			EvaluateFunc: func(_ context.Context, _ *experiment.User, _ []string) (map[string]experiment.Variant, error) {
+				return variants, nil
+			},
+		}
+		provider := newTestProvider(t, mock)
+
+		result := provider.BooleanEvaluation(context.Background(), "test-flag", false, evalCtx)
+
+		assert.NotContains(t, result.FlagMetadata, MetadataKeyNormalizerApplied)
+	})
+
+	t.Run("present when a normalizer ran", func(t *testing.T) {
+		mock := &mockClientAdapter{
+			EvaluateFunc: func(_ context.Context, _ *experiment.User, _ []string) (map[string]experiment.Variant, error) {
+				return variants, nil
+			},
+		}
+		provider := newTestProvider(t, mock,
+			WithUserNormalizer(func(_ context.Context, _ UserNormalizationContext) error { return nil }),
+		)
+
+		result := provider.BooleanEvaluation(context.Background(), "test-flag", false, evalCtx)
+
+		assert.Equal(t, true, result.FlagMetadata[MetadataKeyNormalizerApplied])
+		assert.Equal(t, "on", result.FlagMetadata["key"])
+	})
+}
+
+func TestProvider_toAmplitudeEvent_TargetingKeyAs(t *testing.T) {
+	tests := []struct {
+		name             string
+		targetingKeyAs   Key
+		expectedUserID   string
+		expectedDeviceID string
+	}{
+		{
+			name:           "user ID",
+			targetingKeyAs: KeyUserID,
+			expectedUserID: "key-123",
+		},
+		{
+			name:             "device ID",
+			targetingKeyAs:   KeyDeviceID,
+			expectedDeviceID: "key-123",
+		},
+		{
+			name:           "ignored",
+			targetingKeyAs: TargetingKeyIgnored,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider, err := New(context.Background(), "test-key",
+				withMockClient(&mockClientAdapter{}),
+				WithTargetingKeyAs(tt.targetingKeyAs),
+			)
+			require.NoError(t, err)
+
+			event, eventErr := provider.toAmplitudeEvent(context.Background(), "test-event",
+				of.NewEvaluationContext("key-123", nil), of.NewTrackingEventDetails(0))
+			require.NoError(t, eventErr)
+
+			assert.Equal(t, tt.expectedUserID, event.UserID)
+			assert.Equal(t, tt.expectedDeviceID, event.DeviceID)
+		})
+	}
+}
+
+func TestNew_TargetingKeyAsValidation(t *testing.T) {
+	_, err := New(context.Background(), "test-key",
+		withMockClient(&mockClientAdapter{}),
+		WithTargetingKeyAs(KeyCountry),
+	)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), string(KeyCountry))
+}
+
+func TestProvider_getLogger(t *testing.T) {
+	t.Run("falls back to a default logger when unset", func(t *testing.T) {
+		provider := &Provider{}
+
+		log := provider.getLogger()
+
+		require.NotNil(t, log)
+		assert.NotPanics(t, func() { log.Error("test error: %s", "details") })
+	})
+
+	t.Run("tolerates SDK configs without a logger provider", func(t *testing.T) {
+		log := newLogger(logger.Unknown, nil)
+
+		require.NotNil(t, log)
+		assert.NotPanics(t, func() { log.Error("test error: %s", "details") })
+	})
+
+	t.Run("tracking errors are logged without a configured logger", func(t *testing.T) {
+		provider := &Provider{
+			analyticsClient: analytics.NewClient(analytics.Config{APIKey: "test-key", OptOut: true}),
+			config: Config{
+				EventNormalizer: func(_ context.Context, _ EventNormalizationContext) error {
+					return fmt.Errorf("normalizer failed")
+				},
+			},
+		}
+
+		assert.NotPanics(t, func() {
+			provider.Track(context.Background(), "test-event", of.NewEvaluationContext("user-1", nil), of.NewTrackingEventDetails(0))
+		})
+	})
+}
+
+func TestProvider_ObjectEvaluation_Base64JSONPayloads(t *testing.T) {
+	encoded := base64.StdEncoding.EncodeToString([]byte(`{"a":"A","b":["B"]}`))
+
+	tests := []struct {
+		name          string
+		enabled       bool
+		payload       any
+		expectedValue any
+	}{
+		{
+			name:          "decodes base64 object when enabled",
+			enabled:       true,
+			payload:       encoded,
+			expectedValue: map[string]any{"a": "A", "b": []any{"B"}},
+		},
+		{
+			name:          "returns string unchanged when disabled",
+			enabled:       false,
+			payload:       encoded,
+			expectedValue: encoded,
+		},
+		{
+			name:          "returns non-base64 string unchanged",
+			enabled:       true,
+			payload:       "not base64!",
+			expectedValue: "not base64!",
+		},
+		{
+			name:          "returns base64 of a JSON scalar unchanged",
+			enabled:       true,
+			payload:       base64.StdEncoding.EncodeToString([]byte(`"scalar"`)),
+			expectedValue: base64.StdEncoding.EncodeToString([]byte(`"scalar"`)),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockClientAdapter{
+				EvaluateFunc: func(_ context.Context, _ *experiment.User, _ []string) (map[string]experiment.Variant, error) {
+					return map[string]experiment.Variant{
+						"test-flag": makeVariant("on", "on", tt.payload),
+					}, nil
+				},
+			}
+			options := []Option{withMockClient(mock)}
+			if tt.enabled {
+				options = append(options, WithBase64JSONPayloads())
+			}
+			provider, err := New(context.Background(), "test-key", options...)
+			require.NoError(t, err)
+			require.NoError(t, provider.Init(of.EvaluationContext{}))
+
+			result := provider.ObjectEvaluation(context.Background(), "test-flag", nil, of.FlattenedContext{of.TargetingKey: "user-1"})
+
+			assert.Equal(t, of.ResolutionError{}, result.ResolutionError)
+			assert.Equal(t, tt.expectedValue, result.Value)
+		})
+	}
+}
+
+func TestProvider_ReasonMapper(t *testing.T) {
+	mock := &mockClientAdapter{
+		EvaluateFunc: func(_ context.Context, _ *experiment.User, flagKeys []string) (map[string]experiment.Variant, error) {
+			return map[string]experiment.Variant{
+				"kill-switch": makeVariant("on", "on", nil),
+				"off-flag":    makeVariant("off", "", nil),
+			}, nil
+		},
+	}
+	var mappedFlags []string
+	provider := newTestProvider(t, mock,
+		WithReasonMapper(func(flag string, variant *experiment.Variant) of.Reason {
+			mappedFlags = append(mappedFlags, flag+":"+variant.Key)
+			return "KILL_SWITCH"
+		}),
+	)
+	evalCtx := of.FlattenedContext{of.TargetingKey: "user-1"}
+
+	result := provider.BooleanEvaluation(context.Background(), "kill-switch", false, evalCtx)
+	assert.Equal(t, of.Reason("KILL_SWITCH"), result.Reason)
+	assert.True(t, result.Value)
+
+	offResult := provider.BooleanEvaluation(context.Background(), "off-flag", false, evalCtx)
+	assert.Equal(t, of.DefaultReason, offResult.Reason)
+
+	assert.Equal(t, []string{"kill-switch:on"}, mappedFlags)
+}
+
+func TestProvider_EvaluateAll(t *testing.T) {
+	allVariants := map[string]experiment.Variant{
+		"flag-c": makeVariant("on", "on", nil),
+		"flag-a": makeVariant("on", "on", nil),
+		"flag-b": makeVariant("off", "", nil),
+	}
+	evalCtx := of.FlattenedContext{of.TargetingKey: "user-123"}
+
+	tests := []struct {
+		name         string
+		options      []Option
+		expectedKeys []string
+		expectedErr  error
+	}{
+		{
+			name:         "returns all flags without a cap",
+			expectedKeys: []string{"flag-a", "flag-b", "flag-c"},
+		},
+		{
+			name:         "returns all flags within the cap",
+			options:      []Option{WithMaxFlagsPerEvaluation(3)},
+			expectedKeys: []string{"flag-a", "flag-b", "flag-c"},
+		},
+		{
+			name:         "truncates to the first flags by key when the cap is exceeded",
+			options:      []Option{WithMaxFlagsPerEvaluation(2)},
+			expectedKeys: []string{"flag-a", "flag-b"},
+		},
+		{
+			name:        "errors when the cap is exceeded if configured",
+			options:     []Option{WithMaxFlagsPerEvaluation(2), WithErrorOnMaxFlagsExceeded()},
+			expectedErr: ErrMaxFlagsExceeded,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockClientAdapter{
+				EvaluateFunc: func(_ context.Context, _ *experiment.User, flagKeys []string) (map[string]experiment.Variant, error) {
+					assert.Empty(t, flagKeys)
+					return allVariants, nil
+				},
+			}
+			provider, err := New(context.Background(), "test-deployment-key", append(tt.options, withMockClient(mock))...)
+			require.NoError(t, err)
+			require.NoError(t, provider.Init(of.EvaluationContext{}))
+
+			variants, err := provider.EvaluateAll(context.Background(), evalCtx)
+
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				assert.Nil(t, variants)
+				return
+			}
+			require.NoError(t, err)
+			assert.ElementsMatch(t, tt.expectedKeys, slices.Collect(maps.Keys(variants)))
+		})
+	}
+
+	t.Run("returns an error when not ready", func(t *testing.T) {
+		provider, err := New(context.Background(), "test-deployment-key", withMockClient(&mockClientAdapter{}))
+		require.NoError(t, err)
+
+		_, err = provider.EvaluateAll(context.Background(), evalCtx)
+		assert.ErrorContains(t, err, string(of.ProviderNotReadyCode))
+	})
+
+	t.Run("returns an error when the evaluation fails", func(t *testing.T) {
+		provider := newTestProvider(t, &mockClientAdapter{
+			EvaluateFunc: func(_ context.Context, _ *experiment.User, _ []string) (map[string]experiment.Variant, error) {
+				return nil, errMockEvaluate
+			},
+		})
+
+		_, err := provider.EvaluateAll(context.Background(), evalCtx)
+		assert.ErrorContains(t, err, errMockEvaluate.Error())
+	})
+}
+
+func TestProvider_EvaluateFlags(t *testing.T) {
+	mock := &mockClientAdapter{
+		EvaluateFunc: func(_ context.Context, _ *experiment.User, _ []string) (map[string]experiment.Variant, error) {
+			return map[string]experiment.Variant{
+				"flag-a": makeVariant("on", "on", nil),
+				"flag-b": makeVariant("on", "on", nil),
+				"flag-c": makeVariant("on", "on", nil),
+			}, nil
+		},
+	}
+	provider := newTestProvider(t, mock, WithMaxFlagsPerEvaluation(1), WithErrorOnMaxFlagsExceeded())
+
+	variants, err := provider.EvaluateFlags(context.Background(), of.FlattenedContext{of.TargetingKey: "user-123"}, []string{"flag-a", "flag-b", "missing"})
+
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"flag-a", "flag-b"}, slices.Collect(maps.Keys(variants)), "the cap should not apply and only requested flags should be returned")
+	require.Len(t, mock.evaluateCalls, 1)
+	assert.Equal(t, []string{"flag-a", "flag-b", "missing"}, mock.evaluateCalls[0].FlagKeys)
+}
+
+func TestProvider_FlagMetadata_EvalMs(t *testing.T) {
+	mock := &mockClientAdapter{
+		EvaluateFunc: func(_ context.Context, _ *experiment.User, _ []string) (map[string]experiment.Variant, error) {
+			time.Sleep(time.Millisecond)
+			return map[string]experiment.Variant{"test-flag": makeVariant("on", "on", "true")}, nil
+		},
+	}
+	provider := newTestProvider(t, mock)
+
+	result := provider.BooleanEvaluation(context.Background(), "test-flag", false, of.FlattenedContext{of.TargetingKey: "user-1"})
+
+	require.Contains(t, result.FlagMetadata, MetadataKeyEvalMs)
+	evalMs, err := result.FlagMetadata.GetFloat(MetadataKeyEvalMs)
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, evalMs, 1.0)
+	assert.Less(t, evalMs, float64(time.Minute/time.Millisecond))
+}
+
+// fallbackProvider is a FeatureProvider which resolves every flag statically, for testing fallbacks.
+type fallbackProvider struct {
+	of.NoopProvider
+	flags []string
+}
+
+func (f *fallbackProvider) BooleanEvaluation(_ context.Context, flag string, _ bool, _ of.FlattenedContext) of.BoolResolutionDetail {
+	f.flags = append(f.flags, flag)
+	return of.BoolResolutionDetail{Value: true, ProviderResolutionDetail: of.ProviderResolutionDetail{Reason: of.StaticReason}}
+}
+
+func (f *fallbackProvider) StringEvaluation(_ context.Context, flag string, _ string, _ of.FlattenedContext) of.StringResolutionDetail {
+	f.flags = append(f.flags, flag)
+	return of.StringResolutionDetail{Value: "fallback", ProviderResolutionDetail: of.ProviderResolutionDetail{Reason: of.StaticReason}}
+}
+
+func (f *fallbackProvider) FloatEvaluation(_ context.Context, flag string, _ float64, _ of.FlattenedContext) of.FloatResolutionDetail {
+	f.flags = append(f.flags, flag)
+	return of.FloatResolutionDetail{Value: 1.5, ProviderResolutionDetail: of.ProviderResolutionDetail{Reason: of.StaticReason}}
+}
+
+func (f *fallbackProvider) IntEvaluation(_ context.Context, flag string, _ int64, _ of.FlattenedContext) of.IntResolutionDetail {
+	f.flags = append(f.flags, flag)
+	return of.IntResolutionDetail{Value: 7, ProviderResolutionDetail: of.ProviderResolutionDetail{Reason: of.StaticReason}}
+}
+
+func (f *fallbackProvider) ObjectEvaluation(_ context.Context, flag string, _ any, _ of.FlattenedContext) of.InterfaceResolutionDetail {
+	f.flags = append(f.flags, flag)
+	return of.InterfaceResolutionDetail{Value: "fallback-object", ProviderResolutionDetail: of.ProviderResolutionDetail{Reason: of.StaticReason}}
+}
+
+func TestProvider_FallbackProvider(t *testing.T) {
+	evalCtx := of.FlattenedContext{of.TargetingKey: "user-1"}
+	newProvider := func(t *testing.T, evaluate func(context.Context, *experiment.User, []string) (map[string]experiment.Variant, error)) (*Provider, *fallbackProvider) {
+		t.Helper()
+		fallback := &fallbackProvider{}
+		provider := newTestProvider(t, &mockClientAdapter{EvaluateFunc: evaluate},
+			WithFallbackProvider(fallback),
+		)
+		return provider, fallback
+	}
+
+	t.Run("flag not found delegates to the fallback for every type", func(t *testing.T) {
+		provider, fallback := newProvider(t, nil)
+		ctx := context.Background()
+
+		boolResult := provider.BooleanEvaluation(ctx, "bool-flag", false, evalCtx)
+		assert.True(t, boolResult.Value)
+		assert.Equal(t, of.StaticReason, boolResult.Reason)
+		assert.Equal(t, "fallback", provider.StringEvaluation(ctx, "string-flag", "", evalCtx).Value)
+		assert.Equal(t, 1.5, provider.FloatEvaluation(ctx, "float-flag", 0, evalCtx).Value)
+		assert.Equal(t, int64(7), provider.IntEvaluation(ctx, "int-flag", 0, evalCtx).Value)
+		assert.Equal(t, "fallback-object", provider.ObjectEvaluation(ctx, "object-flag", nil, evalCtx).Value)
+		assert.Equal(t, []string{"bool-flag", "string-flag", "float-flag", "int-flag", "object-flag"}, fallback.flags)
+	})
+
+	t.Run("flags found in Amplitude don't use the fallback", func(t *testing.T) {
+		provider, fallback := newProvider(t, func(_ context.Context, _ *experiment.User, _ []string) (map[string]experiment.Variant, error) {
+			return map[string]experiment.Variant{"test-flag": makeVariant("on", "on", "amplitude")}, nil
+		})
+
+		result := provider.StringEvaluation(context.Background(), "test-flag", "", evalCtx)
+
+		assert.Equal(t, "amplitude", result.Value)
+		assert.Empty(t, fallback.flags)
+	})
+
+	t.Run("evaluation errors don't use the fallback", func(t *testing.T) {
+		provider, fallback := newProvider(t, func(_ context.Context, _ *experiment.User, _ []string) (map[string]experiment.Variant, error) {
+			return nil, errMockEvaluate
+		})
+
+		result := provider.BooleanEvaluation(context.Background(), "test-flag", false, evalCtx)
+
+		assert.False(t, result.Value)
+		assert.Equal(t, of.ErrorReason, result.Reason)
+		assert.Empty(t, fallback.flags)
+	})
+}
+
+func TestNewResolutionError(t *testing.T) {
+	for _, code := range []of.ErrorCode{of.ProviderNotReadyCode, of.FlagNotFoundCode, of.ParseErrorCode, of.TypeMismatchCode, of.InvalidContextCode, of.GeneralCode} {
+		resErr := newResolutionError(code, "flag not found: nope")
+
+		assert.Equal(t, code, resErr.code)
+		assert.Equal(t, string(code)+": flag not found: nope", resErr.Error())
+	}
+}
+
+func TestProvider_ObjectEvaluation_UseNumberDecoding(t *testing.T) {
+	encoded := base64.StdEncoding.EncodeToString([]byte(`{"id": 9007199254740993}`))
+	mock := &mockClientAdapter{
+		EvaluateFunc: func(_ context.Context, _ *experiment.User, _ []string) (map[string]experiment.Variant, error) {
+			return map[string]experiment.Variant{"test-flag": makeVariant("on", "on", encoded)}, nil
+		},
+	}
+	provider := newTestProvider(t, mock, WithBase64JSONPayloads(), WithUseNumberDecoding())
+
+	result := provider.ObjectEvaluation(context.Background(), "test-flag", nil, of.FlattenedContext{of.TargetingKey: "user-1"})
+
+	require.NoError(t, result.Error())
+	assert.Equal(t, map[string]any{"id": json.Number("9007199254740993")}, result.Value)
+}
+
+func TestProvider_ActiveExperiments(t *testing.T) {
+	mock := &mockClientAdapter{
+		EvaluateFunc: func(_ context.Context, _ *experiment.User, _ []string) (map[string]experiment.Variant, error) {
+			return map[string]experiment.Variant{
+				"experiment-a": makeVariant("treatment", "treatment", nil),
+				"experiment-b": makeVariant("control", "control", nil),
+				"rolled-out":   makeVariant("on", "on", nil),
+				"not-in":       makeVariant("off", "", nil),
+				"default": {
+					Key:      "fallthrough",
+					Metadata: map[string]any{"default": true},
+				},
+			}, nil
+		},
+	}
+	provider := newTestProvider(t, mock)
+
+	active, err := provider.ActiveExperiments(context.Background(), of.FlattenedContext{of.TargetingKey: "user-1"})
+
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"experiment-a": "treatment",
+		"experiment-b": "control",
+		"rolled-out":   "on",
+	}, active)
+
+	t.Run("returns evaluation errors", func(t *testing.T) {
+		provider := newTestProvider(t, &mockClientAdapter{
+			EvaluateFunc: func(_ context.Context, _ *experiment.User, _ []string) (map[string]experiment.Variant, error) {
+				return nil, errMockEvaluate
+			},
+		})
+
+		_, err := provider.ActiveExperiments(context.Background(), of.FlattenedContext{of.TargetingKey: "user-1"})
+		assert.Error(t, err)
+	})
+}
+
+func TestProvider_ExposureContext(t *testing.T) {
+	evalCtx := of.FlattenedContext{
+		of.TargetingKey:   "user-1",
+		ContextKeySurface: "checkout-page",
+		"experiment_slot": "hero",
+		"email":           "user@example.com",
+	}
+	newProvider := func(t *testing.T, options ...Option) (*Provider, *mockAnalyticsClient, *mockClientAdapter) {
+		t.Helper()
+		mock := &mockClientAdapter{
+			EvaluateFunc: func(_ context.Context, _ *experiment.User, _ []string) (map[string]experiment.Variant, error) {
+				return map[string]experiment.Variant{"test-flag": makeVariant("on", "on", true)}, nil
+			},
+		}
+		provider, analyticsClient := newTrackingTestProvider(t, mock, options...)
+		return provider, analyticsClient, mock
+	}
+
+	t.Run("surface is copied to the exposure event but not the user", func(t *testing.T) {
+		provider, analyticsClient, mock := newProvider(t)
+
+		provider.BooleanEvaluation(context.Background(), "test-flag", false, evalCtx)
+
+		require.Len(t, analyticsClient.events, 1)
+		properties := analyticsClient.events[0].EventProperties
+		assert.Equal(t, "checkout-page", properties[ContextKeySurface])
+		assert.NotContains(t, properties, "experiment_slot")
+		assert.NotContains(t, properties, "email")
+		require.Len(t, mock.evaluateCalls, 1)
+		assert.NotContains(t, mock.evaluateCalls[0].User.UserProperties, ContextKeySurface)
+		assert.Equal(t, "user@example.com", mock.evaluateCalls[0].User.UserProperties["email"])
+	})
+
+	t.Run("allowlisted keys are copied to the exposure event", func(t *testing.T) {
+		provider, analyticsClient, _ := newProvider(t, WithExposureContextKeys("experiment_slot", "missing"))
+
+		provider.BooleanEvaluation(context.Background(), "test-flag", false, evalCtx)
+
+		require.Len(t, analyticsClient.events, 1)
+		properties := analyticsClient.events[0].EventProperties
+		assert.Equal(t, "checkout-page", properties[ContextKeySurface])
+		assert.Equal(t, "hero", properties["experiment_slot"])
+		assert.NotContains(t, properties, "missing")
+		assert.NotContains(t, properties, "email")
+		assert.Equal(t, "test-flag", properties["flag_key"])
+	})
+
+	t.Run("computed properties are added to the exposure event", func(t *testing.T) {
+		provider, analyticsClient, _ := newProvider(t, WithExposureEventPropertiesFunc(
+			func(_ context.Context, flag string, variant *experiment.Variant, user *experiment.User) map[string]any {
+				return map[string]any{
+					"session_bucket": user.UserId + "/" + flag + "/" + variant.Key,
+					"flag_key":       "overridden",
+				}
+			},
+		))
+
+		provider.BooleanEvaluation(context.Background(), "test-flag", false, evalCtx)
+
+		require.Len(t, analyticsClient.events, 1)
+		properties := analyticsClient.events[0].EventProperties
+		assert.Equal(t, "user-1/test-flag/on", properties["session_bucket"])
+		assert.Equal(t, "test-flag", properties["flag_key"], "identifying properties aren't replaced")
+		assert.Equal(t, "checkout-page", properties[ContextKeySurface])
+	})
+}
+
+func TestProvider_SetTrackingEnabled(t *testing.T) {
+	provider, analyticsClient := newTrackingTestProvider(t, &mockClientAdapter{
+		EvaluateFunc: func(_ context.Context, _ *experiment.User, _ []string) (map[string]experiment.Variant, error) {
+			return map[string]experiment.Variant{"test-flag": makeVariant("treatment", "treatment", "treatment")}, nil
+		},
+	})
+	evalCtx := of.FlattenedContext{of.TargetingKey: "user-123"}
+	evaluateAndTrack := func() {
+		result := provider.StringEvaluation(context.Background(), "test-flag", "default", evalCtx)
+		require.NoError(t, result.Error())
+		provider.Track(context.Background(), "purchase", of.NewEvaluationContext("user-123", nil), of.NewTrackingEventDetails(1))
+	}
+
+	provider.SetTrackingEnabled(false)
+	evaluateAndTrack()
+	assert.Empty(t, analyticsClient.events)
+
+	provider.SetTrackingEnabled(true)
+	evaluateAndTrack()
+	require.Len(t, analyticsClient.events, 2)
+	assert.Equal(t, "$exposure", analyticsClient.events[0].EventType)
+	assert.Equal(t, "purchase", analyticsClient.events[1].EventType)
+}
+
+func TestProvider_ExposureDeviceID(t *testing.T) {
+	provider, analyticsClient := newTrackingTestProvider(t, &mockClientAdapter{
+		EvaluateFunc: func(context.Context, *experiment.User, []string) (map[string]experiment.Variant, error) {
+			return map[string]experiment.Variant{"test-flag": makeVariant("treatment", "treatment", "treatment")}, nil
+		},
+	})
+
+	t.Run("device-only contexts", func(t *testing.T) {
+		analyticsClient.events = nil
+		result := provider.StringEvaluation(context.Background(), "test-flag", "default", of.FlattenedContext{"device_id": "device-1"})
+
+		require.NoError(t, result.Error())
+		require.Len(t, analyticsClient.events, 1)
+		event := analyticsClient.events[0]
+		assert.Equal(t, "device-1", event.EventOptions.DeviceID)
+		assert.Empty(t, event.UserID)
+		assert.Empty(t, event.EventOptions.UserID)
+	})
+
+	t.Run("contexts with both identifiers", func(t *testing.T) {
+		analyticsClient.events = nil
+		provider.StringEvaluation(context.Background(), "test-flag", "default", of.FlattenedContext{
+			of.TargetingKey: "user-1",
+			"device_id":     "device-1",
+		})
+
+		require.Len(t, analyticsClient.events, 1)
+		assert.Equal(t, "user-1", analyticsClient.events[0].UserID)
+		assert.Equal(t, "device-1", analyticsClient.events[0].EventOptions.DeviceID)
+	})
+}
+
+func TestProvider_ExposureEventCustomizer(t *testing.T) {
+	newProvider := func(t *testing.T, customizer func(*analytics.Event)) (*Provider, *mockAnalyticsClient, *recordingLoggerProvider) {
+		t.Helper()
+		mock := &mockClientAdapter{
+			EvaluateFunc: func(context.Context, *experiment.User, []string) (map[string]experiment.Variant, error) {
+				return map[string]experiment.Variant{"test-flag": makeVariant("treatment", "treatment", "treatment")}, nil
+			},
+		}
+		provider, analyticsClient := newTrackingTestProvider(t, mock,
+			WithBatchedExposures(),
+			WithExposureEventCustomizer(customizer),
+		)
+		loggerProvider := &recordingLoggerProvider{}
+		provider.logger = logger.New(logger.Error, loggerProvider)
+		return provider, analyticsClient, loggerProvider
+	}
+	evalCtx := of.FlattenedContext{of.TargetingKey: "user-1"}
+
+	t.Run("exposure events are customized", func(t *testing.T) {
+		provider, analyticsClient, _ := newProvider(t, func(event *analytics.Event) {
+			event.EventProperties["experiment_flag"] = event.EventProperties["flag_key"]
+			delete(event.EventProperties, "flag_key")
+			event.EventOptions.Platform = "backend"
+		})
+
+		provider.StringEvaluation(context.Background(), "test-flag", "default", evalCtx)
+		_, err := provider.EvaluateAll(context.Background(), evalCtx)
+		require.NoError(t, err)
+
+		require.Len(t, analyticsClient.events, 2)
+		event := analyticsClient.events[0]
+		assert.Equal(t, "test-flag", event.EventProperties["experiment_flag"])
+		assert.NotContains(t, event.EventProperties, "flag_key")
+		assert.Equal(t, "backend", event.EventOptions.Platform)
+		assert.Equal(t, "backend", analyticsClient.events[1].EventOptions.Platform, "batched exposures are customized too")
+	})
+
+	t.Run("tracked events aren't customized", func(t *testing.T) {
+		provider, analyticsClient, _ := newProvider(t, func(event *analytics.Event) {
+			event.EventOptions.Platform = "backend"
+		})
+
+		provider.Track(context.Background(), "purchase", of.NewEvaluationContext("user-1", nil), of.NewTrackingEventDetails(1))
+
+		require.Len(t, analyticsClient.events, 1)
+		assert.Empty(t, analyticsClient.events[0].EventOptions.Platform)
+	})
+
+	t.Run("panics drop the event without breaking evaluation", func(t *testing.T) {
+		provider, analyticsClient, loggerProvider := newProvider(t, func(*analytics.Event) {
+			panic("customizer exploded")
+		})
+
+		result := provider.StringEvaluation(context.Background(), "test-flag", "default", evalCtx)
+
+		require.NoError(t, result.Error())
+		assert.Equal(t, "treatment", result.Value)
+		assert.Empty(t, analyticsClient.events)
+		require.Len(t, loggerProvider.errors, 1)
+		assert.Contains(t, loggerProvider.errors[0], "customizer exploded")
+	})
+}
+
+func TestProvider_CombinedExposureAssignment(t *testing.T) {
+	newProvider := func(t *testing.T, options ...Option) (*Provider, *mockAnalyticsClient) {
+		t.Helper()
+		treatment := makeVariant("treatment", "treatment", "treatment")
+		treatment.Metadata = map[string]any{"flagVersion": 3.0, "segmentName": "Beta users"}
+		control := makeVariant("control", "control", "control")
+		control.Metadata = map[string]any{"default": true}
+		variants := map[string]experiment.Variant{"test-flag": treatment, "default-flag": control}
+		mock := &mockClientAdapter{
+			EvaluateFunc: func(_ context.Context, _ *experiment.User, flagKeys []string) (map[string]experiment.Variant, error) {
+				if len(flagKeys) == 1 {
+					return map[string]experiment.Variant{flagKeys[0]: variants[flagKeys[0]]}, nil
+				}
+				return variants, nil
+			},
+		}
+		return newTrackingTestProvider(t, mock, options...)
+	}
+	evalCtx := of.FlattenedContext{of.TargetingKey: "user-123"}
+
+	t.Run("tracks one event with the exposure and assignment", func(t *testing.T) {
+		provider, analyticsClient := newProvider(t, WithCombinedExposureAssignment())
+
+		provider.StringEvaluation(context.Background(), "test-flag", "default", evalCtx)
+
+		require.Len(t, analyticsClient.events, 1)
+		event := analyticsClient.events[0]
+		assert.Equal(t, "$exposure", event.EventType)
+		assert.Equal(t, "test-flag", event.EventProperties["flag_key"])
+		assert.Equal(t, "treatment", event.EventProperties["variant"])
+		assert.Equal(t, "treatment", event.EventProperties["test-flag.variant"])
+		assert.Equal(t, "v3 rule:Beta users", event.EventProperties["test-flag.details"])
+		assert.Equal(t, map[string]any{"[Experiment] test-flag": "treatment"}, event.UserProperties["$set"])
+		assert.Empty(t, event.UserProperties["$unset"])
+	})
+
+	t.Run("unsets the user property for default variants", func(t *testing.T) {
+		provider, analyticsClient := newProvider(t, WithCombinedExposureAssignment())
+
+		provider.StringEvaluation(context.Background(), "default-flag", "default", evalCtx)
+
+		require.Len(t, analyticsClient.events, 1)
+		event := analyticsClient.events[0]
+		assert.Equal(t, "control", event.EventProperties["default-flag.variant"])
+		assert.NotContains(t, event.EventProperties, "default-flag.details")
+		assert.Empty(t, event.UserProperties["$set"])
+		assert.Equal(t, map[string]any{"[Experiment] default-flag": "-"}, event.UserProperties["$unset"])
+	})
+
+	t.Run("batched exposures carry the assignments of all flags", func(t *testing.T) {
+		provider, analyticsClient := newProvider(t, WithCombinedExposureAssignment(), WithBatchedExposures())
+
+		_, err := provider.EvaluateAll(context.Background(), evalCtx)
+
+		require.NoError(t, err)
+		require.Len(t, analyticsClient.events, 1)
+		event := analyticsClient.events[0]
+		assert.Equal(t, "treatment", event.EventProperties["test-flag.variant"])
+		assert.Equal(t, "control", event.EventProperties["default-flag.variant"])
+		assert.Equal(t, map[string]any{"[Experiment] test-flag": "treatment"}, event.UserProperties["$set"])
+		assert.Equal(t, map[string]any{"[Experiment] default-flag": "-"}, event.UserProperties["$unset"])
+	})
+
+	t.Run("exposures don't carry assignments by default", func(t *testing.T) {
+		provider, analyticsClient := newProvider(t)
+
+		provider.StringEvaluation(context.Background(), "test-flag", "default", evalCtx)
+
+		require.Len(t, analyticsClient.events, 1)
+		assert.NotContains(t, analyticsClient.events[0].EventProperties, "test-flag.variant")
+		assert.Nil(t, analyticsClient.events[0].UserProperties)
+	})
+}
+
+func TestProvider_BatchedExposures(t *testing.T) {
+	evalCtx := of.FlattenedContext{
+		of.TargetingKey:   "user-1",
+		ContextKeySurface: "checkout-page",
+	}
+	newProvider := func(t *testing.T, options ...Option) (*Provider, *mockAnalyticsClient) {
+		t.Helper()
+		mock := &mockClientAdapter{
+			EvaluateFunc: func(_ context.Context, _ *experiment.User, _ []string) (map[string]experiment.Variant, error) {
+				return map[string]experiment.Variant{
+					"flag-b": makeVariant("treatment", "treatment", nil),
+					"flag-a": makeVariant("off", "", nil),
+					"flag-c": makeVariant("control", "control", nil),
+				}, nil
+			},
+		}
+		return newTrackingTestProvider(t, mock, options...)
+	}
+
+	t.Run("EvaluateAll tracks a single exposure for all flags", func(t *testing.T) {
+		provider, analyticsClient := newProvider(t, WithBatchedExposures())
+
+		_, err := provider.EvaluateAll(context.Background(), evalCtx)
+
+		require.NoError(t, err)
+		require.Len(t, analyticsClient.events, 1)
+		event := analyticsClient.events[0]
+		assert.Equal(t, "$exposure", event.EventType)
+		assert.Equal(t, "user-1", event.UserID)
+		assert.Equal(t, map[string]any{
+			"flag_keys":       []string{"flag-a", "flag-b", "flag-c"},
+			"variants":        map[string]string{"flag-a": "off", "flag-b": "treatment", "flag-c": "control"},
+			ContextKeySurface: "checkout-page",
+		}, event.EventProperties)
+	})
+
+	t.Run("EvaluateFlags covers only the requested flags", func(t *testing.T) {
+		provider, analyticsClient := newProvider(t, WithBatchedExposures())
+
+		_, err := provider.EvaluateFlags(context.Background(), evalCtx, []string{"flag-c", "flag-b", "missing"})
+
+		require.NoError(t, err)
+		require.Len(t, analyticsClient.events, 1)
+		properties := analyticsClient.events[0].EventProperties
+		assert.Equal(t, []string{"flag-b", "flag-c"}, properties["flag_keys"])
+		assert.Equal(t, map[string]string{"flag-b": "treatment", "flag-c": "control"}, properties["variants"])
+	})
+
+	t.Run("the max flags cap applies", func(t *testing.T) {
+		provider, analyticsClient := newProvider(t, WithBatchedExposures(), WithMaxFlagsPerEvaluation(2))
+
+		_, err := provider.EvaluateAll(context.Background(), evalCtx)
+
+		require.NoError(t, err)
+		require.Len(t, analyticsClient.events, 1)
+		assert.Equal(t, []string{"flag-a", "flag-b"}, analyticsClient.events[0].EventProperties["flag_keys"])
+	})
+
+	t.Run("no exposures without the option", func(t *testing.T) {
+		provider, analyticsClient := newProvider(t)
+
+		_, err := provider.EvaluateAll(context.Background(), evalCtx)
+		require.NoError(t, err)
+		_, err = provider.EvaluateFlags(context.Background(), evalCtx, []string{"flag-b"})
+		require.NoError(t, err)
+
+		assert.Empty(t, analyticsClient.events)
+	})
+
+	t.Run("no exposures for anonymous users, active experiments or batches", func(t *testing.T) {
+		provider, analyticsClient := newProvider(t, WithBatchedExposures())
+		anonymous := of.FlattenedContext{of.TargetingKey: "user-1", ContextKeyAnonymous: true}
+
+		_, err := provider.EvaluateAll(context.Background(), anonymous)
+		require.NoError(t, err)
+		_, err = provider.ActiveExperiments(context.Background(), evalCtx)
+		require.NoError(t, err)
+		_, err = provider.EvaluateForUsers(context.Background(), []of.FlattenedContext{evalCtx, evalCtx}, nil)
+		require.NoError(t, err)
+
+		assert.Empty(t, analyticsClient.events)
+	})
+}
+
+func TestProvider_ContextWithoutExposures(t *testing.T) {
+	mock := &mockClientAdapter{
+		EvaluateFunc: func(context.Context, *experiment.User, []string) (map[string]experiment.Variant, error) {
+			return map[string]experiment.Variant{"test-flag": makeVariant("on", "on", nil)}, nil
+		},
+	}
+	provider, analyticsClient := newTrackingTestProvider(t, mock, WithBatchedExposures())
+	evalCtx := of.FlattenedContext{of.TargetingKey: "user-1"}
+	ctx := ContextWithoutExposures(context.Background())
+
+	result := provider.BooleanEvaluation(ctx, "test-flag", false, evalCtx)
+	_, err := provider.EvaluateAll(ctx, evalCtx)
+	require.NoError(t, err)
+
+	assert.True(t, result.Value)
+	assert.Empty(t, analyticsClient.events)
+
+	provider.BooleanEvaluation(context.Background(), "test-flag", false, evalCtx)
+	assert.Len(t, analyticsClient.events, 1, "other evaluations still track exposures")
+}
+
+func TestProvider_ContextKeyNoExposure(t *testing.T) {
+	mock := &mockClientAdapter{
+		EvaluateFunc: func(context.Context, *experiment.User, []string) (map[string]experiment.Variant, error) {
+			return map[string]experiment.Variant{"test-flag": makeVariant("on", "on", nil)}, nil
+		},
+	}
+	provider, analyticsClient := newTrackingTestProvider(t, mock)
+
+	result := provider.BooleanEvaluation(context.Background(), "test-flag", false, of.FlattenedContext{
+		of.TargetingKey:      "user-1",
+		ContextKeyNoExposure: true,
+	})
+
+	assert.True(t, result.Value)
+	assert.Empty(t, analyticsClient.events)
+	require.Len(t, mock.evaluateCalls, 1)
+	assert.NotContains(t, mock.evaluateCalls[0].User.UserProperties, ContextKeyNoExposure)
+
+	provider.BooleanEvaluation(context.Background(), "test-flag", false, of.FlattenedContext{
+		of.TargetingKey:      "user-1",
+		ContextKeyNoExposure: false,
+	})
+	assert.Len(t, analyticsClient.events, 1)
+}
+
+func TestProvider_TrackPanicRecovery(t *testing.T) {
+	newProvider := func(t *testing.T) (*Provider, *recordingLoggerProvider) {
+		t.Helper()
+		mock := &mockClientAdapter{
+			EvaluateFunc: func(_ context.Context, _ *experiment.User, _ []string) (map[string]experiment.Variant, error) {
+				return map[string]experiment.Variant{"test-flag": makeVariant("on", "on", "enabled")}, nil
+			},
+		}
+		provider := newTestProvider(t, mock)
+		loggerProvider := &recordingLoggerProvider{}
+		provider.logger = logger.New(logger.Error, loggerProvider)
+		provider.analyticsClient = &mockAnalyticsClient{
+			TrackFunc: func(analytics.Event) { panic("analytics client exploded") },
+		}
+		return provider, loggerProvider
+	}
+
+	t.Run("exposure tracking panics don't break evaluation", func(t *testing.T) {
+		provider, loggerProvider := newProvider(t)
+
+		var result of.StringResolutionDetail
+		require.NotPanics(t, func() {
+			result = provider.StringEvaluation(context.Background(), "test-flag", "default", of.FlattenedContext{of.TargetingKey: "user-1"})
+		})
+
+		assert.Equal(t, "enabled", result.Value)
+		assert.NoError(t, result.Error())
+		require.Len(t, loggerProvider.errors, 1)
+		assert.Contains(t, loggerProvider.errors[0], "analytics client exploded")
+	})
+
+	t.Run("custom tracking panics are recovered", func(t *testing.T) {
+		provider, loggerProvider := newProvider(t)
+
+		require.NotPanics(t, func() {
+			provider.Track(context.Background(), "test-event", of.NewEvaluationContext("user-1", nil), of.NewTrackingEventDetails(0))
+		})
+
+		require.Len(t, loggerProvider.errors, 1)
+		assert.Contains(t, loggerProvider.errors[0], "test-event")
+	})
+}
+
+func TestProvider_FlagMetadata_FlagVersion(t *testing.T) {
+	tests := []struct {
+		name             string
+		variantMetadata  map[string]any
+		expectedVersion  any
+		expectedDeployed any
+	}{
+		{
+			name:             "version and deployed from local evaluation",
+			variantMetadata:  map[string]any{"flagVersion": float64(12), "deployed": true},
+			expectedVersion:  int64(12),
+			expectedDeployed: true,
+		},
+		{
+			name:            "integer version",
+			variantMetadata: map[string]any{"flagVersion": 3},
+			expectedVersion: int64(3),
+		},
+		{
+			name:            "json.Number version",
+			variantMetadata: map[string]any{"flagVersion": json.Number("7")},
+			expectedVersion: int64(7),
+		},
+		{
+			name:            "non-integer version is ignored",
+			variantMetadata: map[string]any{"flagVersion": "v1"},
+		},
+		{
+			name: "absent without metadata",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockClientAdapter{
+				EvaluateFunc: func(_ context.Context, _ *experiment.User, _ []string) (map[string]experiment.Variant, error) {
+					variant := makeVariant("on", "on", true)
+					variant.Metadata = tt.variantMetadata
+					return map[string]experiment.Variant{"test-flag": variant}, nil
+				},
+			}
+			provider := newTestProvider(t, mock)
+
+			result := provider.BooleanEvaluation(context.Background(), "test-flag", false, of.FlattenedContext{of.TargetingKey: "user-1"})
+
+			require.NoError(t, result.Error())
+			if tt.expectedVersion == nil {
+				assert.NotContains(t, result.FlagMetadata, MetadataKeyFlagVersion)
+			} else {
+				assert.Equal(t, tt.expectedVersion, result.FlagMetadata[MetadataKeyFlagVersion])
+			}
+			if tt.expectedDeployed == nil {
+				assert.NotContains(t, result.FlagMetadata, MetadataKeyDeployed)
+			} else {
+				assert.Equal(t, tt.expectedDeployed, result.FlagMetadata[MetadataKeyDeployed])
+			}
+		})
+	}
+}
+
+func TestProvider_BooleanEvaluation_OffMeansFalse(t *testing.T) {
+	evaluate := func(_ context.Context, _ *experiment.User, _ []string) (map[string]experiment.Variant, error) {
+		return map[string]experiment.Variant{"kill-switch": makeVariant("off", "", nil)}, nil
+	}
+	evalCtx := of.FlattenedContext{of.TargetingKey: "user-1"}
+
+	t.Run("off returns the default value by default", func(t *testing.T) {
+		provider := newTestProvider(t, &mockClientAdapter{EvaluateFunc: evaluate})
+
+		result := provider.BooleanEvaluation(context.Background(), "kill-switch", true, evalCtx)
+
+		assert.True(t, result.Value)
+		assert.Equal(t, of.DefaultReason, result.Reason)
+	})
+
+	t.Run("off returns false with WithOffMeansFalse", func(t *testing.T) {
+		provider := newTestProvider(t, &mockClientAdapter{EvaluateFunc: evaluate}, WithOffMeansFalse())
+
+		result := provider.BooleanEvaluation(context.Background(), "kill-switch", true, evalCtx)
+
+		assert.False(t, result.Value)
+		assert.Equal(t, of.TargetingMatchReason, result.Reason)
+		assert.Equal(t, "off", result.Variant)
+		assert.NoError(t, result.Error())
+	})
+}
+
+func TestProvider_OffVariantKeys(t *testing.T) {
+	mock := &mockClientAdapter{
+		EvaluateFunc: func(context.Context, *experiment.User, []string) (map[string]experiment.Variant, error) {
+			return map[string]experiment.Variant{
+				"control-flag": makeVariant("Control", "Control", "payload"),
+				"off-flag":     makeVariant("off", "", nil),
+				"on-flag":      makeVariant("treatment", "treatment", "payload"),
+			}, nil
+		},
+	}
+	evalCtx := of.FlattenedContext{of.TargetingKey: "user-1"}
+
+	t.Run("only off by default", func(t *testing.T) {
+		provider := newTestProvider(t, mock)
+
+		assert.Equal(t, "payload", provider.StringEvaluation(context.Background(), "control-flag", "default", evalCtx).Value)
+		assert.Equal(t, "default", provider.StringEvaluation(context.Background(), "off-flag", "default", evalCtx).Value)
+	})
+
+	t.Run("configured keys replace off", func(t *testing.T) {
+		provider := newTestProvider(t, mock, WithOffVariantKeys(OffVariants{Keys: []string{"Control"}}))
+
+		control := provider.StringEvaluation(context.Background(), "control-flag", "default", evalCtx)
+		off := provider.BooleanEvaluation(context.Background(), "off-flag", false, evalCtx)
+
+		assert.Equal(t, "default", control.Value)
+		assert.Equal(t, of.DefaultReason, control.Reason)
+		assert.True(t, off.Value, "off is no longer special")
+		assert.Equal(t, "payload", provider.StringEvaluation(context.Background(), "on-flag", "default", evalCtx).Value)
+	})
+
+	t.Run("case-sensitive by default", func(t *testing.T) {
+		provider := newTestProvider(t, mock, WithOffVariantKeys(OffVariants{Keys: []string{"control"}}))
+
+		assert.Equal(t, "payload", provider.StringEvaluation(context.Background(), "control-flag", "default", evalCtx).Value)
+	})
+
+	t.Run("ignoring case", func(t *testing.T) {
+		provider := newTestProvider(t, mock,
+			WithOffVariantKeys(OffVariants{Keys: []string{"off", "control"}, IgnoreCase: true}),
+			WithOffMeansFalse(),
+		)
+
+		result := provider.BooleanEvaluation(context.Background(), "control-flag", true, evalCtx)
+		active, err := provider.ActiveExperiments(context.Background(), evalCtx)
+		require.NoError(t, err)
+
+		assert.False(t, result.Value)
+		assert.Equal(t, "Control", result.Variant)
+		assert.False(t, provider.BooleanEvaluation(context.Background(), "off-flag", true, evalCtx).Value)
+		assert.Equal(t, map[string]string{"on-flag": "treatment"}, active)
+	})
+}
+
+func TestProvider_MissingBooleanFlagAsFalse(t *testing.T) {
+	mock := &mockClientAdapter{
+		EvaluateFunc: func(context.Context, *experiment.User, []string) (map[string]experiment.Variant, error) {
+			return map[string]experiment.Variant{"existing-flag": makeVariant("on", "on", true)}, nil
+		},
+	}
+	provider := newTestProvider(t, mock, WithMissingBooleanFlagAsFalse())
+	evalCtx := of.FlattenedContext{of.TargetingKey: "user-1"}
+
+	t.Run("missing boolean flags are false", func(t *testing.T) {
+		result := provider.BooleanEvaluation(context.Background(), "missing-flag", true, evalCtx)
+
+		assert.NoError(t, result.Error())
+		assert.False(t, result.Value)
+		assert.Equal(t, of.DefaultReason, result.Reason)
+	})
+
+	t.Run("existing boolean flags are evaluated", func(t *testing.T) {
+		result := provider.BooleanEvaluation(context.Background(), "existing-flag", false, evalCtx)
+
+		assert.NoError(t, result.Error())
+		assert.True(t, result.Value)
+	})
+
+	t.Run("other types still fail", func(t *testing.T) {
+		result := provider.StringEvaluation(context.Background(), "missing-flag", "default", evalCtx)
+
+		assert.Equal(t, "default", result.Value)
+		assert.Equal(t, of.ErrorReason, result.Reason)
+		assert.ErrorContains(t, result.ResolutionError, string(of.FlagNotFoundCode))
+	})
+
+	t.Run("disabled by default", func(t *testing.T) {
+		result := newTestProvider(t, mock).BooleanEvaluation(context.Background(), "missing-flag", true, evalCtx)
+
+		assert.True(t, result.Value)
+		assert.ErrorContains(t, result.ResolutionError, string(of.FlagNotFoundCode))
+	})
+}
+
+func TestProvider_ObjectEvaluation_UnwrapStringifiedObjects(t *testing.T) {
+	tests := []struct {
+		name          string
+		enabled       bool
+		payload       any
+		expectedValue any
+	}{
+		{
+			name:          "decodes double-encoded object when enabled",
+			enabled:       true,
+			payload:       `{"a":"A","b":["B"]}`,
+			expectedValue: map[string]any{"a": "A", "b": []any{"B"}},
+		},
+		{
+			name:          "decodes double-encoded array when enabled",
+			enabled:       true,
+			payload:       `[1, 2]`,
+			expectedValue: []any{float64(1), float64(2)},
+		},
+		{
+			name:          "returns string unchanged when disabled",
+			enabled:       false,
+			payload:       `{"a":"A"}`,
+			expectedValue: `{"a":"A"}`,
+		},
+		{
+			name:          "returns plain string unchanged",
+			enabled:       true,
+			payload:       "just a string",
+			expectedValue: "just a string",
+		},
+		{
+			name:          "returns stringified JSON scalar unchanged",
+			enabled:       true,
+			payload:       `42`,
+			expectedValue: `42`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockClientAdapter{
+				EvaluateFunc: func(_ context.Context, _ *experiment.User, _ []string) (map[string]experiment.Variant, error) {
+					return map[string]experiment.Variant{
+						"test-flag": makeVariant("on", "on", tt.payload),
+					}, nil
+				},
+			}
+			options := []Option{withMockClient(mock)}
+			if tt.enabled {
+				options = append(options, WithUnwrapStringifiedObjects())
+			}
+			provider, err := New(context.Background(), "test-key", options...)
+			require.NoError(t, err)
+			require.NoError(t, provider.Init(of.EvaluationContext{}))
+
+			result := provider.ObjectEvaluation(context.Background(), "test-flag", nil, of.FlattenedContext{of.TargetingKey: "user-1"})
+
+			assert.Equal(t, of.ResolutionError{}, result.ResolutionError)
+			assert.Equal(t, tt.expectedValue, result.Value)
+		})
+	}
+}
+
+func TestProvider_IdentityOnlyEvaluation(t *testing.T) {
+	evalCtx := of.FlattenedContext{
+		of.TargetingKey: "user-1",
+		"device_id":     "device-1",
+		"country":       "US",
+		"tier":          "premium",
+	}
+	mock := &mockClientAdapter{
+		EvaluateFunc: func(_ context.Context, _ *experiment.User, _ []string) (map[string]experiment.Variant, error) {
+			return map[string]experiment.Variant{
+				"identity-flag": makeVariant("on", "on", true),
+				"regular-flag":  makeVariant("on", "on", true),
+			}, nil
+		},
+	}
+	provider := newTestProvider(t, mock, WithIdentityOnlyEvaluation("identity-flag"))
+
+	identityResult := provider.BooleanEvaluation(context.Background(), "identity-flag", false, evalCtx)
+	regularResult := provider.BooleanEvaluation(context.Background(), "regular-flag", false, evalCtx)
+
+	assert.True(t, identityResult.Value)
+	assert.True(t, regularResult.Value)
+	require.Len(t, mock.evaluateCalls, 2)
+	assert.Equal(t, &experiment.User{UserId: "user-1", DeviceId: "device-1"}, mock.evaluateCalls[0].User)
+	assert.Equal(t, "US", mock.evaluateCalls[1].User.Country)
+	assert.Equal(t, "premium", mock.evaluateCalls[1].User.UserProperties["tier"])
+}
+
+func TestProvider_IdentityOnlyEvaluation_TargetingKeyPrecedence(t *testing.T) {
+	evalCtx := of.FlattenedContext{
+		of.TargetingKey: "user-1",
+		"user_id":       "user-2",
+	}
+	mock := &mockClientAdapter{
+		EvaluateFunc: func(_ context.Context, _ *experiment.User, _ []string) (map[string]experiment.Variant, error) {
+			return map[string]experiment.Variant{"identity-flag": makeVariant("on", "on", true)}, nil
+		},
+	}
+	provider := newTestProvider(t, mock, WithIdentityOnlyEvaluation("identity-flag"))
+	loggerProvider := &recordingLoggerProvider{}
+	provider.logger = logger.New(logger.Warn, loggerProvider)
+
+	// Map iteration order is random, so evaluate repeatedly to catch a nondeterministic winner.
+	for range 20 {
+		provider.BooleanEvaluation(context.Background(), "identity-flag", false, evalCtx)
+	}
+
+	require.Len(t, mock.evaluateCalls, 20)
+	for _, call := range mock.evaluateCalls {
+		assert.Equal(t, "user-1", call.User.UserId)
+	}
+	require.NotEmpty(t, loggerProvider.warnings)
+	assert.Contains(t, loggerProvider.warnings[0], "differs from the targeting key")
+}
+
+func TestProvider_toIdentityUser(t *testing.T) {
+	t.Run("respects the targeting key field", func(t *testing.T) {
+		provider := &Provider{config: Config{TargetingKeyField: KeyDeviceID}}
+
+		user, err := provider.toIdentityUser(context.Background(), of.FlattenedContext{of.TargetingKey: "device-1"})
+
+		require.NoError(t, err)
+		assert.Equal(t, &experiment.User{DeviceId: "device-1"}, user)
+	})
+
+	t.Run("requires an identity", func(t *testing.T) {
+		provider := &Provider{}
+
+		_, err := provider.toIdentityUser(context.Background(), of.FlattenedContext{"country": "US"})
+
+		assert.Error(t, err)
+	})
+
+	t.Run("runs the user normalizer", func(t *testing.T) {
+		provider := &Provider{config: Config{
+			UserNormalizer: func(_ context.Context, normCtx UserNormalizationContext) error {
+				normCtx.User.UserId = "normalized-" + normCtx.User.UserId
+				return nil
+			},
+		}}
+
+		user, err := provider.toIdentityUser(context.Background(), of.FlattenedContext{of.TargetingKey: "user-1"})
+
+		require.NoError(t, err)
+		assert.Equal(t, "normalized-user-1", user.UserId)
+	})
+}
+
+func BenchmarkProvider_toAmplitudeUser(b *testing.B) {
+	evalCtx := of.FlattenedContext{of.TargetingKey: "user-1", "country": "US", "platform": PlatformIOS}
+	for i := range 50 {
+		evalCtx[fmt.Sprintf("property_%d", i)] = i
+	}
+	provider := &Provider{}
+
+	b.Run("full user", func(b *testing.B) {
+		for b.Loop() {
+			_, _ = provider.toAmplitudeUser(context.Background(), evalCtx)
+		}
+	})
+
+	b.Run("identity only", func(b *testing.B) {
+		for b.Loop() {
+			_, _ = provider.toIdentityUser(context.Background(), evalCtx)
+		}
+	})
+
+	b.Run("common fields", func(b *testing.B) {
+		commonCtx := of.FlattenedContext{
+			of.TargetingKey: "user-1",
+			"device_id":     "device-1",
+			"country":       "US",
+			"city":          "Boston",
+			"platform":      PlatformIOS,
+			"version":       "1.2.3",
+		}
+		for b.Loop() {
+			_, _ = provider.toAmplitudeUser(context.Background(), commonCtx)
+		}
+	})
+}
+
+func TestProvider_OffVariantReason(t *testing.T) {
+	tests := []struct {
+		name           string
+		metadata       map[string]any
+		expectedReason of.Reason
+	}{
+		{
+			name:           "fallthrough to the default variant",
+			metadata:       map[string]any{"default": true, "segmentName": "All Other Users"},
+			expectedReason: of.DefaultReason,
+		},
+		{
+			name:           "rule explicitly assigning off",
+			metadata:       map[string]any{"segmentName": "Internal Users"},
+			expectedReason: of.TargetingMatchReason,
+		},
+		{
+			name:           "no metadata",
+			expectedReason: of.DefaultReason,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockClientAdapter{
+				EvaluateFunc: func(_ context.Context, _ *experiment.User, _ []string) (map[string]experiment.Variant, error) {
+					variant := makeVariant("off", "", nil)
+					variant.Metadata = tt.metadata
+					return map[string]experiment.Variant{"test-flag": variant}, nil
+				},
+			}
+			provider := newTestProvider(t, mock)
+			evalCtx := of.FlattenedContext{of.TargetingKey: "user-1"}
+
+			boolResult := provider.BooleanEvaluation(context.Background(), "test-flag", true, evalCtx)
+			stringResult := provider.StringEvaluation(context.Background(), "test-flag", "default", evalCtx)
+
+			assert.True(t, boolResult.Value)
+			assert.Equal(t, tt.expectedReason, boolResult.Reason)
+			assert.Equal(t, "default", stringResult.Value)
+			assert.Equal(t, tt.expectedReason, stringResult.Reason)
+		})
+	}
+}
+
+func TestProvider_ContextEnricher(t *testing.T) {
+	enricher := func(_ context.Context, evalCtx of.FlattenedContext) (of.FlattenedContext, error) {
+		enriched := maps.Clone(evalCtx)
+		if evalCtx["ip"] == "203.0.113.1" {
+			enriched["country_code"] = "AU"
+		}
+		return enriched, nil
+	}
+	keyMap := DefaultKeyMap()
+	keyMap["country_code"] = KeyCountry
+
+	t.Run("enriched attributes go through key mapping for evaluation", func(t *testing.T) {
+		provider := &Provider{config: Config{ContextEnricher: enricher, KeyMap: keyMap}}
+
+		user, err := provider.toAmplitudeUser(context.Background(), of.FlattenedContext{
+			of.TargetingKey: "user-1",
+			"ip":            "203.0.113.1",
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, "AU", user.Country)
+		assert.Equal(t, "user-1", user.UserId)
+	})
+
+	t.Run("enriched attributes go through key mapping for tracking", func(t *testing.T) {
+		provider := &Provider{config: Config{ContextEnricher: enricher, KeyMap: keyMap}}
+
+		event, err := provider.toAmplitudeEvent(context.Background(), "test-event",
+			of.NewEvaluationContext("user-1", map[string]any{"ip": "203.0.113.1"}), of.NewTrackingEventDetails(0))
+
+		require.NoError(t, err)
+		assert.Equal(t, "AU", event.Country)
+		assert.Equal(t, "user-1", event.UserID)
+	})
+
+	t.Run("the enricher can change the targeting key for tracking", func(t *testing.T) {
+		provider := &Provider{config: Config{
+			ContextEnricher: func(_ context.Context, evalCtx of.FlattenedContext) (of.FlattenedContext, error) {
+				return of.FlattenedContext{of.TargetingKey: "enriched-user"}, nil
+			},
+		}}
+
+		event, err := provider.toAmplitudeEvent(context.Background(), "test-event",
+			of.NewEvaluationContext("user-1", nil), of.NewTrackingEventDetails(0))
+
+		require.NoError(t, err)
+		assert.Equal(t, "enriched-user", event.UserID)
+	})
+
+	t.Run("enricher errors abort evaluation", func(t *testing.T) {
+		provider := newTestProvider(t, &mockClientAdapter{},
+			WithContextEnricher(func(context.Context, of.FlattenedContext) (of.FlattenedContext, error) {
+				return nil, errors.New("geo lookup failed")
+			}),
+		)
+
+		result := provider.BooleanEvaluation(context.Background(), "test-flag", false, of.FlattenedContext{of.TargetingKey: "user-1"})
+
+		assert.Equal(t, of.ErrorReason, result.Reason)
+		assert.ErrorContains(t, result.ResolutionError, "geo lookup failed")
+	})
+}
+
+func TestProvider_NilVariantMap(t *testing.T) {
+	mock := &mockClientAdapter{
+		EvaluateFunc: func(_ context.Context, _ *experiment.User, _ []string) (map[string]experiment.Variant, error) {
+			return nil, nil
+		},
+	}
+	provider := newTestProvider(t, mock)
+
+	var result of.BoolResolutionDetail
+	require.NotPanics(t, func() {
+		result = provider.BooleanEvaluation(context.Background(), "test-flag", true, of.FlattenedContext{of.TargetingKey: "user-1"})
+	})
+
+	assert.True(t, result.Value)
+	assert.Equal(t, of.ErrorReason, result.Reason)
+	assert.ErrorContains(t, result.ResolutionError, string(of.FlagNotFoundCode))
+}
+
+func TestProvider_DeviceIDKey(t *testing.T) {
+	var capturedUser *experiment.User
+	mock := &mockClientAdapter{
+		EvaluateFunc: func(_ context.Context, user *experiment.User, _ []string) (map[string]experiment.Variant, error) {
+			capturedUser = user
+			return map[string]experiment.Variant{}, nil
+		},
+	}
+	provider := newTestProvider(t, mock, WithDeviceIDKey("deviceIdentifier"))
+
+	_ = provider.BooleanEvaluation(context.Background(), "test-flag", false, of.FlattenedContext{
+		of.TargetingKey:    "user-123",
+		"deviceIdentifier": "device-456",
+	})
+
+	require.NotNil(t, capturedUser)
+	assert.Equal(t, "user-123", capturedUser.UserId)
+	assert.Equal(t, "device-456", capturedUser.DeviceId)
+	assert.NotContains(t, capturedUser.UserProperties, "deviceIdentifier")
+}
+
+func TestProvider_CaseInsensitiveKeyMatching(t *testing.T) {
+	newProvider := func(t *testing.T, opts ...Option) (*Provider, *mockClientAdapter, *recordingLoggerProvider) {
+		t.Helper()
+		mock := &mockClientAdapter{
+			EvaluateFunc: func(context.Context, *experiment.User, []string) (map[string]experiment.Variant, error) {
+				return map[string]experiment.Variant{}, nil
+			},
+		}
+		provider := newTestProvider(t, mock, opts...)
+		loggerProvider := &recordingLoggerProvider{}
+		provider.logger = logger.New(logger.Warn, loggerProvider)
+		return provider, mock, loggerProvider
+	}
+
+	t.Run("unusually cased keys match", func(t *testing.T) {
+		provider, mock, _ := newProvider(t, WithCaseInsensitiveKeyMatching())
+
+		_ = provider.BooleanEvaluation(context.Background(), "test-flag", false, of.FlattenedContext{
+			of.TargetingKey: "user-1",
+			"DEVICE-ID":     "device-1",
+			"COUNTRY":       "NZ",
+			"Favorite":      "blue",
+		})
+
+		require.Len(t, mock.evaluateCalls, 1)
+		user := mock.evaluateCalls[0].User
+		assert.Equal(t, "device-1", user.DeviceId)
+		assert.Equal(t, "NZ", user.Country)
+		assert.Equal(t, map[string]any{"Favorite": "blue"}, user.UserProperties)
+	})
+
+	t.Run("exact matches take precedence", func(t *testing.T) {
+		provider, mock, loggerProvider := newProvider(t, WithCaseInsensitiveKeyMatching())
+
+		_ = provider.BooleanEvaluation(context.Background(), "test-flag", false, of.FlattenedContext{
+			of.TargetingKey: "user-1",
+			"device_id":     "exact",
+			"Device_Id":     "folded",
+			"DEVICEiD":      "also-folded",
+		})
+
+		require.Len(t, mock.evaluateCalls, 1)
+		assert.Equal(t, "exact", mock.evaluateCalls[0].User.DeviceId)
+		assert.Empty(t, mock.evaluateCalls[0].User.UserProperties)
+		require.Len(t, loggerProvider.warnings, 2)
+		assert.Contains(t, loggerProvider.warnings[0], `"DEVICEiD"`)
+		assert.Contains(t, loggerProvider.warnings[1], `"Device_Id"`)
+	})
+
+	t.Run("disabled by default", func(t *testing.T) {
+		provider, mock, _ := newProvider(t)
+
+		_ = provider.BooleanEvaluation(context.Background(), "test-flag", false, of.FlattenedContext{
+			of.TargetingKey: "user-1",
+			"Device_Id":     "device-1",
+		})
+
+		require.Len(t, mock.evaluateCalls, 1)
+		assert.Empty(t, mock.evaluateCalls[0].User.DeviceId)
+		assert.Equal(t, "device-1", mock.evaluateCalls[0].User.UserProperties["Device_Id"])
+	})
+}
+
+func TestProvider_FlagMetadata_SegmentName(t *testing.T) {
+	tests := []struct {
+		name            string
+		variantMetadata map[string]any
+		expected        string
+	}{
+		{
+			name:            "segment name from a matching rule",
+			variantMetadata: map[string]any{"segmentName": "beta-users"},
+			expected:        "beta-users",
+		},
+		{
+			name:            "empty segment name is ignored",
+			variantMetadata: map[string]any{"segmentName": ""},
+		},
+		{
+			name:            "non-string segment name is ignored",
+			variantMetadata: map[string]any{"segmentName": 1},
+		},
+		{
+			name: "absent without metadata",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockClientAdapter{
+				EvaluateFunc: func(_ context.Context, _ *experiment.User, _ []string) (map[string]experiment.Variant, error) {
+					variant := makeVariant("on", "on", true)
+					variant.Metadata = tt.variantMetadata
+					return map[string]experiment.Variant{"test-flag": variant}, nil
+				},
+			}
+			provider := newTestProvider(t, mock)
+
+			result := provider.BooleanEvaluation(context.Background(), "test-flag", false, of.FlattenedContext{of.TargetingKey: "user-1"})
+
+			require.NoError(t, result.Error())
+			if tt.expected == "" {
+				assert.NotContains(t, result.FlagMetadata, MetadataKeySegmentName)
+			} else {
+				segmentName, err := result.FlagMetadata.GetString(MetadataKeySegmentName)
+				require.NoError(t, err)
+				assert.Equal(t, tt.expected, segmentName)
+			}
+		})
+	}
+}
+
+func TestProvider_ForcedDefaults(t *testing.T) {
+	var tracked []analytics.Event
+	mock := &mockClientAdapter{
+		EvaluateFunc: func(_ context.Context, _ *experiment.User, flagKeys []string) (map[string]experiment.Variant, error) {
+			return map[string]experiment.Variant{flagKeys[0]: makeVariant("on", "on", true)}, nil
+		},
+	}
+	provider, err := New(context.Background(), "test-key",
+		withMockClient(mock),
+		WithForcedDefaults("killed-flag"),
+		WithOffMeansFalse(),
+	)
+	require.NoError(t, err)
+	provider.analyticsClient = &mockAnalyticsClient{TrackFunc: func(event analytics.Event) {
+		tracked = append(tracked, event)
+	}}
+	require.NoError(t, provider.Init(of.EvaluationContext{}))
+	evalCtx := of.FlattenedContext{of.TargetingKey: "user-1"}
+
+	t.Run("forced flags return the default without calling the client", func(t *testing.T) {
+		boolResult := provider.BooleanEvaluation(context.Background(), "killed-flag", true, evalCtx)
+		stringResult := provider.StringEvaluation(context.Background(), "killed-flag", "default", evalCtx)
+
+		assert.True(t, boolResult.Value)
+		assert.Equal(t, of.DisabledReason, boolResult.Reason)
+		assert.NoError(t, boolResult.Error())
+		assert.Equal(t, "default", stringResult.Value)
+		assert.Equal(t, of.DisabledReason, stringResult.Reason)
+		assert.Empty(t, mock.evaluateCalls)
+		assert.Empty(t, tracked)
+	})
+
+	t.Run("other flags are evaluated", func(t *testing.T) {
+		result := provider.BooleanEvaluation(context.Background(), "live-flag", false, evalCtx)
+
+		assert.True(t, result.Value)
+		assert.Len(t, mock.evaluateCalls, 1)
+		assert.Len(t, tracked, 1)
+	})
+}
+
+func TestProvider_FlagAllowlist(t *testing.T) {
+	// The mock returns all flags, like remote evaluation.
+	mock := &mockClientAdapter{
+		EvaluateFunc: func(context.Context, *experiment.User, []string) (map[string]experiment.Variant, error) {
+			return map[string]experiment.Variant{
+				"allowed-flag": makeVariant("on", "on", true),
+				"other-flag":   makeVariant("on", "on", true),
+			}, nil
+		},
+	}
+	provider := newTestProvider(t, mock, WithFlagAllowlist("allowed-flag"))
+	evalCtx := of.FlattenedContext{of.TargetingKey: "user-1"}
+
+	t.Run("allowlisted flags are evaluated", func(t *testing.T) {
+		mock.evaluateCalls = nil
+
+		result := provider.BooleanEvaluation(context.Background(), "allowed-flag", false, evalCtx)
+
+		assert.True(t, result.Value)
+		assert.NoError(t, result.Error())
+		assert.Len(t, mock.evaluateCalls, 1)
+	})
+
+	t.Run("other flags are rejected without calling the client", func(t *testing.T) {
+		mock.evaluateCalls = nil
+
+		result := provider.BooleanEvaluation(context.Background(), "other-flag", false, evalCtx)
+
+		assert.False(t, result.Value)
+		assert.Equal(t, of.ErrorReason, result.Reason)
+		assert.ErrorContains(t, result.Error(), "FLAG_NOT_FOUND")
+		assert.ErrorContains(t, result.Error(), "not in the flag allowlist")
+		assert.Empty(t, mock.evaluateCalls)
+	})
+
+	t.Run("evaluating many flags only returns allowlisted flags", func(t *testing.T) {
+		mock.evaluateCalls = nil
+
+		all, err := provider.EvaluateAll(context.Background(), evalCtx)
+		require.NoError(t, err)
+		some, err := provider.EvaluateFlags(context.Background(), evalCtx, []string{"other-flag"})
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"allowed-flag"}, slices.Collect(maps.Keys(all)))
+		assert.Empty(t, some)
+		require.Len(t, mock.evaluateCalls, 1, "no flags are left to evaluate")
+		assert.Equal(t, []string{"allowed-flag"}, mock.evaluateCalls[0].FlagKeys)
+	})
+}
+
+func TestProvider_AllowedStringValues(t *testing.T) {
+	payloads := map[string]any{"valid-flag": "b", "invalid-flag": "d", "other-flag": "anything"}
+	mock := &mockClientAdapter{
+		EvaluateFunc: func(_ context.Context, _ *experiment.User, flagKeys []string) (map[string]experiment.Variant, error) {
+			return map[string]experiment.Variant{flagKeys[0]: makeVariant("on", "on", payloads[flagKeys[0]])}, nil
+		},
+	}
+	provider := newTestProvider(t, mock,
+		WithAllowedStringValues("valid-flag", "a", "b", "c"),
+		WithAllowedStringValues("invalid-flag", "a", "b"),
+		WithAllowedStringValues("invalid-flag", "c"),
+	)
+	evalCtx := of.FlattenedContext{of.TargetingKey: "user-1"}
+
+	t.Run("allowed value is returned", func(t *testing.T) {
+		result := provider.StringEvaluation(context.Background(), "valid-flag", "a", evalCtx)
+
+		assert.NoError(t, result.Error())
+		assert.Equal(t, "b", result.Value)
+		assert.Equal(t, "on", result.Variant)
+	})
+
+	t.Run("disallowed value returns the default with an error", func(t *testing.T) {
+		result := provider.StringEvaluation(context.Background(), "invalid-flag", "a", evalCtx)
+
+		assert.Equal(t, "a", result.Value)
+		assert.Equal(t, of.ErrorReason, result.Reason)
+		assert.ErrorContains(t, result.Error(), string(of.TypeMismatchCode))
+		assert.ErrorContains(t, result.Error(), `"d"`)
+	})
+
+	t.Run("unregistered flags are not validated", func(t *testing.T) {
+		result := provider.StringEvaluation(context.Background(), "other-flag", "a", evalCtx)
+
+		assert.NoError(t, result.Error())
+		assert.Equal(t, "anything", result.Value)
+	})
+}
+
+func TestProvider_ContextForcedVariants(t *testing.T) {
+	newProvider := func(t *testing.T, opts ...Option) (*Provider, *mockClientAdapter, *mockAnalyticsClient) {
+		t.Helper()
+		mock := &mockClientAdapter{
+			EvaluateFunc: func(_ context.Context, _ *experiment.User, flagKeys []string) (map[string]experiment.Variant, error) {
+				return map[string]experiment.Variant{flagKeys[0]: {Key: "off"}}, nil
+			},
+		}
+		provider, analyticsClient := newTrackingTestProvider(t, mock, opts...)
+		return provider, mock, analyticsClient
+	}
+
+	t.Run("prefixed key forces the flag without calling Amplitude", func(t *testing.T) {
+		provider, mock, analyticsClient := newProvider(t, WithContextForcedVariantsEnabled())
+
+		result := provider.BooleanEvaluation(context.Background(), "rollout-flag", false, of.FlattenedContext{
+			of.TargetingKey:                               "user-1",
+			ContextKeyForcedVariantPrefix + "rollout-flag": "treatment",
+		})
+
+		assert.True(t, result.Value)
+		assert.Equal(t, "treatment", result.Variant)
+		assert.Equal(t, of.StaticReason, result.Reason)
+		assert.Equal(t, true, result.FlagMetadata[MetadataKeyForcedVariant])
+		assert.Equal(t, true, result.FlagMetadata[MetadataKeyOverrideApplied])
+		assert.Empty(t, mock.evaluateCalls)
+		assert.Empty(t, analyticsClient.events)
+	})
+
+	t.Run("map forces the listed flags", func(t *testing.T) {
+		provider, mock, _ := newProvider(t, WithContextForcedVariantsEnabled())
+		evalCtx := of.FlattenedContext{
+			of.TargetingKey:          "user-1",
+			ContextKeyForcedVariants: map[string]any{"forced-flag": "on", "off-flag": "off"},
+		}
+
+		forced := provider.BooleanEvaluation(context.Background(), "forced-flag", false, evalCtx)
+		off := provider.BooleanEvaluation(context.Background(), "off-flag", true, evalCtx)
+		other := provider.BooleanEvaluation(context.Background(), "other-flag", true, evalCtx)
+
+		assert.True(t, forced.Value)
+		assert.Equal(t, "on", forced.Variant)
+		assert.True(t, off.Value, "the off variant returns the default value")
+		assert.Equal(t, of.StaticReason, off.Reason)
+		assert.NotEqual(t, of.StaticReason, other.Reason, "unlisted flags are evaluated by Amplitude")
+		require.Len(t, mock.evaluateCalls, 1)
+		assert.NotContains(t, mock.evaluateCalls[0].User.UserProperties, ContextKeyForcedVariants)
+	})
+
+	t.Run("ignored unless enabled", func(t *testing.T) {
+		provider, mock, _ := newProvider(t)
+
+		result := provider.BooleanEvaluation(context.Background(), "rollout-flag", false, of.FlattenedContext{
+			of.TargetingKey:                               "user-1",
+			ContextKeyForcedVariantPrefix + "rollout-flag": "treatment",
+		})
+
+		assert.False(t, result.Value)
+		assert.NotEqual(t, of.StaticReason, result.Reason)
+		require.Len(t, mock.evaluateCalls, 1)
+		assert.Empty(t, mock.evaluateCalls[0].User.UserProperties, "reserved keys aren't used for targeting")
+	})
+
+	t.Run("forced defaults take precedence", func(t *testing.T) {
+		provider, _, _ := newProvider(t, WithContextForcedVariantsEnabled(), WithForcedDefaults("rollout-flag"))
+
+		result := provider.BooleanEvaluation(context.Background(), "rollout-flag", false, of.FlattenedContext{
+			of.TargetingKey:                               "user-1",
+			ContextKeyForcedVariantPrefix + "rollout-flag": "treatment",
+		})
+
+		assert.False(t, result.Value)
+		assert.Equal(t, of.DisabledReason, result.Reason)
+	})
+}
+
+func TestProvider_EmptyPayloadDefaults(t *testing.T) {
+	variants := map[string]experiment.Variant{
+		"on-flag":      {Key: "on", Value: "on"},
+		"number-flag":  {Key: "3", Value: "3"},
+		"with-payload": {Key: "on", Value: "on", Payload: "payload"},
+	}
+	mock := &mockClientAdapter{
+		EvaluateFunc: func(_ context.Context, _ *experiment.User, flagKeys []string) (map[string]experiment.Variant, error) {
+			return map[string]experiment.Variant{flagKeys[0]: variants[flagKeys[0]]}, nil
+		},
+	}
+	newProvider := func(t *testing.T, defaults map[reflect.Kind]any) *Provider {
+		t.Helper()
+		return newTestProvider(t, mock, WithEmptyPayloadDefaults(defaults))
+	}
+	evalCtx := of.FlattenedContext{of.TargetingKey: "user-1"}
+
+	t.Run("configured values", func(t *testing.T) {
+		provider := newProvider(t, map[reflect.Kind]any{
+			reflect.String:    "enabled",
+			reflect.Int64:     int64(1),
+			reflect.Float64:   0.5,
+			reflect.Interface: map[string]any{"enabled": true},
+		})
+
+		stringResult := provider.StringEvaluation(context.Background(), "on-flag", "default", evalCtx)
+		intResult := provider.IntEvaluation(context.Background(), "on-flag", 0, evalCtx)
+		floatResult := provider.FloatEvaluation(context.Background(), "on-flag", 0, evalCtx)
+		objectResult := provider.ObjectEvaluation(context.Background(), "on-flag", nil, evalCtx)
+
+		assert.Equal(t, "enabled", stringResult.Value)
+		assert.Equal(t, "on", stringResult.Variant)
+		assert.NoError(t, stringResult.Error())
+		assert.Equal(t, int64(1), intResult.Value)
+		assert.Equal(t, 0.5, floatResult.Value)
+		assert.Equal(t, map[string]any{"enabled": true}, objectResult.Value)
+	})
+
+	t.Run("variant value", func(t *testing.T) {
+		provider := newProvider(t, map[reflect.Kind]any{
+			reflect.String: UseVariantValue,
+			reflect.Int64:  UseVariantValue,
+		})
+
+		stringResult := provider.StringEvaluation(context.Background(), "on-flag", "default", evalCtx)
+		intResult := provider.IntEvaluation(context.Background(), "number-flag", 0, evalCtx)
+
+		assert.Equal(t, "on", stringResult.Value)
+		assert.Equal(t, int64(3), intResult.Value)
+		assert.NoError(t, intResult.Error())
+	})
+
+	t.Run("payloads take precedence", func(t *testing.T) {
+		provider := newProvider(t, map[reflect.Kind]any{reflect.String: "enabled"})
+
+		result := provider.StringEvaluation(context.Background(), "with-payload", "default", evalCtx)
+
+		assert.Equal(t, "payload", result.Value)
+	})
+
+	t.Run("unconfigured kinds return the default value", func(t *testing.T) {
+		provider := newProvider(t, map[reflect.Kind]any{reflect.String: "enabled"})
+
+		result := provider.IntEvaluation(context.Background(), "on-flag", 42, evalCtx)
+
+		assert.Equal(t, int64(42), result.Value)
+		assert.Equal(t, of.DefaultReason, result.Reason)
+	})
+}
+
+func BenchmarkProvider_toAmplitudeEvent(b *testing.B) {
+	attributes := map[string]any{"country": "US", "platform": PlatformIOS, "device_id": "device-1"}
+	for i := range 20 {
+		attributes[fmt.Sprintf("context_%d", i)] = i
+	}
+	evalCtx := of.NewEvaluationContext("user-1", attributes)
+	details := of.NewTrackingEventDetails(9.99).Add("currency", "USD").Add("product_id", "SKU-1")
+	for i := range 20 {
+		details = details.Add(fmt.Sprintf("property_%d", i), i)
+	}
+	provider := &Provider{}
+
+	for b.Loop() {
+		_, _ = provider.toAmplitudeEvent(context.Background(), "purchase", evalCtx, details)
+	}
+}
+
+func TestProvider_toAmplitudeEvent_SingleRoundTrip(t *testing.T) {
+	// legacyEvent decodes the context and details into the event one after the other,
+	// as toAmplitudeEvent used to, to check the single round-trip maps fields identically.
+	legacyEvent := func(t *testing.T, provider *Provider, evalCtx of.EvaluationContext, details of.TrackingEventDetails) analytics.Event {
+		t.Helper()
+		var event analytics.Event
+		attributes := evalCtx.Attributes()
+		attributes[string(KeyUserID)] = evalCtx.TargetingKey()
+		eventMap, _ := provider.normalizeContext(attributes)
+		detailsMap, extra := provider.normalizeContext(details.Attributes())
+		for _, m := range []map[Key]any{eventMap, detailsMap} {
+			data, err := json.Marshal(m)
+			require.NoError(t, err)
+			require.NoError(t, json.Unmarshal(data, &event))
+		}
+		if event.EventProperties == nil {
+			event.EventProperties = make(map[string]any, len(extra))
+		}
+		maps.Copy(event.EventProperties, extra)
+		// The user ID is now only kept on event.UserID.
+		event.EventOptions.UserID = ""
+		event.UserID = evalCtx.TargetingKey()
+		event.EventType = "test-event"
+		if details.Value() != 0 {
+			event.Revenue = details.Value()
+		}
+		return event
+	}
+
+	tests := []struct {
+		name    string
+		evalCtx of.EvaluationContext
+		details of.TrackingEventDetails
+	}{
+		{
+			name:    "no attributes",
+			evalCtx: of.NewEvaluationContext("user-1", nil),
+			details: of.NewTrackingEventDetails(0),
+		},
+		{
+			name: "context and details fields",
+			evalCtx: of.NewEvaluationContext("user-1", map[string]any{
+				"platform":  PlatformIOS,
+				"country":   "US",
+				"device_id": "device-1",
+				"tier":      "gold",
+			}),
+			details: of.NewTrackingEventDetails(9.99).
+				Add("currency", "USD").
+				Add("quantity", 2).
+				Add("product_id", "SKU-1"),
+		},
+		{
+			name:    "details take precedence over the context",
+			evalCtx: of.NewEvaluationContext("user-1", map[string]any{"country": "US", "city": "Boston"}),
+			details: of.NewTrackingEventDetails(0).Add("country", "CA"),
+		},
+		{
+			name:    "null details leave the context value",
+			evalCtx: of.NewEvaluationContext("user-1", map[string]any{"country": "US"}),
+			details: of.NewTrackingEventDetails(0).Add("country", nil),
+		},
+		{
+			name: "event properties from both are merged",
+			evalCtx: of.NewEvaluationContext("user-1", map[string]any{
+				"event_properties": map[string]any{"source": "context", "page": "home"},
+			}),
+			details: of.NewTrackingEventDetails(0).
+				Add("event_properties", map[string]any{"source": "details"}).
+				Add("button", "buy"),
+		},
+		{
+			name: "user properties operations are merged",
+			evalCtx: of.NewEvaluationContext("user-1", map[string]any{
+				"user_properties": map[string]any{"$set": map[string]any{"a": 1}, "$add": map[string]any{"b": 1}},
+			}),
+			details: of.NewTrackingEventDetails(0).
+				Add("user_properties", map[string]any{"$set": map[string]any{"c": 2}}),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &Provider{}
+
+			event, err := provider.toAmplitudeEvent(context.Background(), "test-event", tt.evalCtx, tt.details)
+
+			require.NoError(t, err)
+			assert.Equal(t, legacyEvent(t, provider, tt.evalCtx, tt.details), event)
+		})
+	}
+}
+
+func TestDecodeUser(t *testing.T) {
+	tests := []struct {
+		name    string
+		userMap map[Key]any
+	}{
+		{
+			name:    "empty",
+			userMap: map[Key]any{},
+		},
+		{
+			name: "common string fields",
+			userMap: map[Key]any{
+				KeyUserID:             "user-1",
+				KeyDeviceID:           "device-1",
+				KeyCountry:            "US",
+				KeyRegion:             "MA",
+				KeyDMA:                "Boston",
+				KeyCity:               "Boston",
+				KeyLanguage:           "en-US",
+				KeyPlatform:           PlatformIOS,
+				KeyVersion:            "1.2.3",
+				KeyOS:                 "iOS 17",
+				KeyDeviceManufacturer: "Apple",
+				KeyDeviceBrand:        "iPhone",
+				KeyDeviceModel:        "iPhone 15",
+				KeyCarrier:            "Verizon",
+				KeyLibrary:            "go",
+			},
+		},
+		{
+			name: "structured fields",
+			userMap: map[Key]any{
+				KeyUserID:           "user-1",
+				KeyUserProperties:   map[string]any{"tier": "gold", "age": 30},
+				KeyGroups:           map[string][]string{"org": {"acme"}},
+				KeyGroupProperties:  map[string]map[string]any{"org": {"size": 10}},
+				KeyCohortIDs:        map[string]struct{}{"cohort-1": {}},
+				KeyGroupCohortIDSet: map[string]map[string]map[string]struct{}{"org": {"acme": {"cohort-2": {}}}},
+			},
+		},
+		{
+			name:    "null values",
+			userMap: map[Key]any{KeyUserID: "user-1", KeyCountry: nil},
+		},
+		{
+			name:    "event-only keys are ignored",
+			userMap: map[Key]any{KeyUserID: "user-1", KeyPlan: map[string]any{"branch": "main"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The result must be the same as decoding the whole map via JSON.
+			var expected experiment.User
+			data, err := json.Marshal(tt.userMap)
+			require.NoError(t, err)
+			require.NoError(t, json.Unmarshal(data, &expected))
+
+			var user experiment.User
+			require.NoError(t, decodeUser(tt.userMap, &user))
+
+			assert.Equal(t, expected, user)
+		})
+	}
+
+	t.Run("values of the wrong type are rejected", func(t *testing.T) {
+		var user experiment.User
+
+		err := decodeUser(map[Key]any{KeyUserID: "user-1", KeyCountry: 42}, &user)
+
+		assert.ErrorContains(t, err, "failed to unmarshal user map")
+	})
+}
+
+// fakeFlagEvaluator shows that consumers can substitute a fake for the provider.
+type fakeFlagEvaluator struct {
+	FlagEvaluator
+	active map[string]string
+}
+
+func (f *fakeFlagEvaluator) ActiveExperiments(context.Context, of.FlattenedContext) (map[string]string, error) {
+	return f.active, nil
+}
+
+func TestFlagEvaluator(t *testing.T) {
+	// experimentBadges stands in for consumer code which depends on the interface.
+	experimentBadges := func(evaluator FlagEvaluator) []string {
+		active, err := evaluator.ActiveExperiments(context.Background(), of.FlattenedContext{of.TargetingKey: "user-1"})
+		require.NoError(t, err)
+		return slices.Sorted(maps.Keys(active))
+	}
+
+	t.Run("provider", func(t *testing.T) {
+		mock := &mockClientAdapter{
+			EvaluateFunc: func(_ context.Context, _ *experiment.User, _ []string) (map[string]experiment.Variant, error) {
+				return map[string]experiment.Variant{"new-checkout": makeVariant("treatment", "treatment", nil)}, nil
+			},
+		}
+
+		assert.Equal(t, []string{"new-checkout"}, experimentBadges(newTestProvider(t, mock)))
+	})
+
+	t.Run("fake", func(t *testing.T) {
+		fake := &fakeFlagEvaluator{active: map[string]string{"fake-experiment": "on"}}
+
+		assert.Equal(t, []string{"fake-experiment"}, experimentBadges(fake))
+	})
+}
+
+func TestProvider_AnonymousSuppressesExposure(t *testing.T) {
+	var capturedUser *experiment.User
+	mock := &mockClientAdapter{
+		EvaluateFunc: func(_ context.Context, user *experiment.User, _ []string) (map[string]experiment.Variant, error) {
+			capturedUser = user
+			return map[string]experiment.Variant{"test-flag": makeVariant("treatment", "treatment", "treatment")}, nil
+		},
+	}
+	provider, analyticsClient := newTrackingTestProvider(t, mock)
+
+	t.Run("anonymous users are evaluated without exposure", func(t *testing.T) {
+		result := provider.StringEvaluation(context.Background(), "test-flag", "control", of.FlattenedContext{
+			of.TargetingKey:     "device-1",
+			ContextKeyAnonymous: true,
+		})
+
+		assert.Equal(t, "treatment", result.Value)
+		assert.Empty(t, analyticsClient.events)
+		assert.NotContains(t, capturedUser.UserProperties, ContextKeyAnonymous)
+	})
+
+	t.Run("exposures resume once identified", func(t *testing.T) {
+		provider.StringEvaluation(context.Background(), "test-flag", "control", of.FlattenedContext{
+			of.TargetingKey: "user-1",
+		})
+
+		require.Len(t, analyticsClient.events, 1)
+		assert.Equal(t, "user-1", analyticsClient.events[0].UserID)
+	})
+
+	t.Run("false does not suppress exposure", func(t *testing.T) {
+		provider.StringEvaluation(context.Background(), "test-flag", "control", of.FlattenedContext{
+			of.TargetingKey:     "user-1",
+			ContextKeyAnonymous: false,
+		})
+
+		assert.Len(t, analyticsClient.events, 2)
+	})
+}
@@ -0,0 +1,119 @@
+package amplitude
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// defaultSinkBufferSize is the number of records buffered for the exposure sink and the asynchronous
+// decision audit sink, unless configured with [WithExposureSinkBufferSize] or [AuditBufferSize].
+const defaultSinkBufferSize = 1024
+
+// sinkItem is a record buffered for a sink, with the context of the evaluation which produced it.
+type sinkItem[R any] struct {
+	ctx    context.Context
+	record R
+}
+
+// sinkWorker passes records to a sink from a goroutine, so that a slow sink doesn't stall evaluations.
+// Records are buffered in a bounded channel, and dropped when it is full.
+type sinkWorker[R any] struct {
+	// name describes the sink in log messages, e.g. "exposure sink".
+	name     string
+	sink     func(ctx context.Context, record R)
+	logError func(message string, args ...any)
+	records  chan sinkItem[R]
+	// dropped counts the records dropped because the buffer was full.
+	dropped atomic.Uint64
+
+	// runMu guards done, which is closed to stop the goroutine started by start,
+	// and stopped, which the goroutine closes once it has passed the buffered records to the sink;
+	// both are nil while the worker is stopped.
+	runMu   sync.Mutex
+	done    chan struct{}
+	stopped chan struct{}
+}
+
+// newSinkWorker creates a worker buffering up to size records for the sink.
+func newSinkWorker[R any](name string, sink func(ctx context.Context, record R), size int, logError func(message string, args ...any)) *sinkWorker[R] {
+	if size <= 0 {
+		size = defaultSinkBufferSize
+	}
+	return &sinkWorker[R]{
+		name:     name,
+		sink:     sink,
+		logError: logError,
+		records:  make(chan sinkItem[R], size),
+	}
+}
+
+// send buffers the record for the sink, or drops it if the buffer is full.
+// The record keeps the values of ctx, but not its cancellation, since the evaluation
+// has usually returned by the time the sink gets the record.
+func (w *sinkWorker[R]) send(ctx context.Context, record R) {
+	select {
+	case w.records <- sinkItem[R]{ctx: context.WithoutCancel(ctx), record: record}:
+	default:
+		w.dropped.Add(1)
+	}
+}
+
+// start starts passing buffered records to the sink, unless it is already started.
+func (w *sinkWorker[R]) start() {
+	w.runMu.Lock()
+	defer w.runMu.Unlock()
+	if w.done != nil {
+		return
+	}
+	done, stopped := make(chan struct{}), make(chan struct{})
+	w.done, w.stopped = done, stopped
+	go func() {
+		defer close(stopped)
+		for {
+			select {
+			case item := <-w.records:
+				w.pass(item)
+			case <-done:
+				w.drain()
+				return
+			}
+		}
+	}()
+}
+
+// drain passes the records which are already buffered to the sink.
+func (w *sinkWorker[R]) drain() {
+	for {
+		select {
+		case item := <-w.records:
+			w.pass(item)
+		default:
+			return
+		}
+	}
+}
+
+// stop stops passing records to the sink once the buffered records have been passed,
+// so that they aren't lost when the provider is closed. It can be started again.
+func (w *sinkWorker[R]) stop() {
+	w.runMu.Lock()
+	defer w.runMu.Unlock()
+	if w.done == nil {
+		return
+	}
+	close(w.done)
+	<-w.stopped
+	w.done, w.stopped = nil, nil
+}
+
+// pass passes a record to the sink. A panic in the sink is logged and recovered,
+// so that it doesn't stop the worker.
+func (w *sinkWorker[R]) pass(item sinkItem[R]) {
+	defer func() {
+		if r := recover(); r != nil {
+			w.logError("amplitude: recovered from panic in the %s: %v", w.name, r)
+		}
+	}()
+	w.sink(item.ctx, item.record)
+}
@@ -0,0 +1,169 @@
+package amplitude
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+)
+
+// Validate checks the configuration, returning an error describing every problem found.
+// This includes problems which prevent [NewFromConfig] from creating a provider,
+// and likely mistakes (such as settings which have no effect) which [NewFromConfig]
+// only reports according to [Config.ConfigValidation].
+func (c Config) Validate() error {
+	return errors.Join(append(c.invalidSettings(), c.suspectSettings()...)...)
+}
+
+// invalidSettings returns the problems which prevent creating a provider.
+func (c Config) invalidSettings() []error {
+	var errs []error
+	if c.DeploymentKey == "" {
+		errs = append(errs, errors.New("you must provide a deployment key"))
+	}
+	if c.LocalConfig != nil && c.RemoteConfig != nil && len(c.RemoteFlags) == 0 {
+		errs = append(errs, errors.New("you cannot configure the provider to use both local and remote evaluation at the same time"))
+	}
+	switch c.TargetingKeyField {
+	case "", KeyUserID, KeyDeviceID, TargetingKeyIgnored:
+	default:
+		errs = append(errs, fmt.Errorf("the targeting key can only be mapped to %s or %s (or ignored), not %s", KeyUserID, KeyDeviceID, c.TargetingKeyField))
+	}
+	if c.StaleWhileRevalidateSoftTTL != 0 || c.StaleWhileRevalidateHardTTL != 0 {
+		switch {
+		case c.RemoteEvaluationCache == nil:
+			errs = append(errs, errors.New("stale-while-revalidate requires a remote evaluation cache"))
+		case c.StaleWhileRevalidateSoftTTL <= 0 || c.StaleWhileRevalidateHardTTL < c.StaleWhileRevalidateSoftTTL:
+			errs = append(errs, fmt.Errorf("the stale-while-revalidate soft TTL (%s) must be positive and no greater than the hard TTL (%s)", c.StaleWhileRevalidateSoftTTL, c.StaleWhileRevalidateHardTTL))
+		}
+	}
+	if codec := c.RemoteEvaluationCodec; codec != nil {
+		switch {
+		case c.RemoteEvaluationCache == nil:
+			errs = append(errs, errors.New("the remote evaluation codec requires a remote evaluation cache"))
+		case codec.Marshal == nil || codec.Unmarshal == nil:
+			errs = append(errs, errors.New("the remote evaluation codec must have both Marshal and Unmarshal functions"))
+		}
+	}
+	for _, flag := range slices.Sorted(maps.Keys(c.RequiredAttributes)) {
+		for _, key := range c.RequiredAttributes[flag] {
+			if slices.Contains(eventKeys, key) && !slices.Contains(userKeys, key) {
+				errs = append(errs, fmt.Errorf("the attributes required for flag %s must be user attributes, not the event field %q", flag, key))
+			}
+		}
+	}
+	for _, key := range c.StickyBucketingKeys {
+		if _, ok := userStringFields[key]; !ok {
+			errs = append(errs, fmt.Errorf("sticky bucketing keys must be string fields of the Amplitude user, not %q", key))
+		}
+	}
+	if c.RemoteFetchTimeout < 0 {
+		errs = append(errs, fmt.Errorf("the remote fetch timeout must be positive, but is %s", c.RemoteFetchTimeout))
+	}
+	if c.CombinedExposureAssignment && c.LocalConfig != nil && c.LocalConfig.AssignmentConfig != nil {
+		errs = append(errs, errors.New("combined exposure and assignment events can't be used with separate assignment events (the local assignment config)"))
+	}
+	if c.InitRetryAttempts < 0 || c.InitRetryDelay < 0 {
+		errs = append(errs, fmt.Errorf("the init retry attempts (%d) and delay (%s) must not be negative", c.InitRetryAttempts, c.InitRetryDelay))
+	}
+	if c.StartTimeout < 0 {
+		errs = append(errs, fmt.Errorf("the start timeout must not be negative, but is %s", c.StartTimeout))
+	}
+	if c.EvaluationChangeTrackingSize < 0 {
+		errs = append(errs, fmt.Errorf("the evaluation change tracking size must not be negative, but is %d", c.EvaluationChangeTrackingSize))
+	}
+	if c.ExposureDeduplicationTTL < 0 || c.ExposureDeduplicationSize < 0 {
+		errs = append(errs, fmt.Errorf("the exposure deduplication TTL (%s) and size (%d) must not be negative", c.ExposureDeduplicationTTL, c.ExposureDeduplicationSize))
+	}
+	if c.ExposureSinkBufferSize < 0 {
+		errs = append(errs, fmt.Errorf("the exposure sink buffer size must not be negative, but is %d", c.ExposureSinkBufferSize))
+	}
+	if c.DecisionAudit != nil && c.DecisionAudit.BufferSize < 0 {
+		errs = append(errs, fmt.Errorf("the decision audit buffer size must not be negative, but is %d", c.DecisionAudit.BufferSize))
+	}
+	if c.BatchConcurrency < 0 {
+		errs = append(errs, fmt.Errorf("the batch concurrency must not be negative, but is %d", c.BatchConcurrency))
+	}
+	return errs
+}
+
+// suspectSettings returns likely mistakes which don't prevent creating a provider.
+func (c Config) suspectSettings() []error {
+	var errs []error
+	if c.AnalyticsConfig != nil && c.AnalyticsConfig.APIKey == "" {
+		errs = append(errs, errors.New("tracking requires an analytics API key"))
+	}
+	if err := c.checkRemoteCacheMode(); err != nil {
+		errs = append(errs, err)
+	}
+	if len(c.StickyBucketingKeys) > 0 && !c.usesRemoteEvaluation() {
+		errs = append(errs, errors.New("sticky bucketing requires remote evaluation"))
+	}
+	if c.CombinedExposureAssignment {
+		if c.AnalyticsConfig == nil {
+			errs = append(errs, errors.New("combined exposure and assignment events require tracking"))
+		}
+		if c.RemoteConfig != nil && len(c.RemoteFlags) == 0 {
+			errs = append(errs, errors.New("combined exposure and assignment events require local evaluation"))
+		}
+	}
+	if c.ExposureDeduplicationTTL > 0 && c.AnalyticsConfig == nil {
+		errs = append(errs, errors.New("exposure deduplication requires tracking"))
+	}
+	if (c.RemoteFetchTimeout != 0 || c.RemoteFetchRetries != 0) && !c.usesRemoteEvaluation() {
+		errs = append(errs, errors.New("the remote fetch timeout and retries have no effect with local evaluation"))
+	}
+	if c.RemoteReadinessProbe && !c.usesRemoteEvaluation() {
+		errs = append(errs, errors.New("the remote readiness probe has no effect with local evaluation"))
+	}
+	for _, kind := range slices.Sorted(maps.Keys(c.EmptyPayloadDefaults)) {
+		valueType, ok := emptyPayloadKinds[kind]
+		value := c.EmptyPayloadDefaults[kind]
+		switch {
+		case !ok:
+			errs = append(errs, fmt.Errorf("empty payload defaults are not supported for %s values", kind))
+		case valueType != nil && value != UseVariantValue && reflect.TypeOf(value) != valueType:
+			errs = append(errs, fmt.Errorf("the empty payload default for %s values must be of type %s, not %T", kind, valueType, value))
+		}
+	}
+	if err := ValidateKeyMap(c.KeyMap); err != nil {
+		errs = append(errs, err)
+	}
+	for _, contextKey := range slices.Sorted(maps.Keys(c.KeyMap)) {
+		key := c.KeyMap[contextKey]
+		if slices.Contains(allKeys, Key(contextKey)) && Key(contextKey) != key {
+			errs = append(errs, fmt.Errorf("the key map maps the Amplitude field %q to a different field %q", contextKey, key))
+		}
+	}
+	return errs
+}
+
+// ValidateKeyMap checks that every entry of a custom key map (see [WithKeyMap]) maps to
+// one of the Amplitude fields declared as a [Key] constant, returning an error listing the
+// entries which don't. Otherwise, a typo in a target silently turns the mapped attribute
+// into a user property. [NewFromConfig] reports the same problems according to [Config.ConfigValidation].
+func ValidateKeyMap(keyMap map[string]Key) error {
+	var errs []error
+	for _, contextKey := range slices.Sorted(maps.Keys(keyMap)) {
+		key := keyMap[contextKey]
+		if !slices.Contains(allKeys, key) {
+			errs = append(errs, fmt.Errorf("the key map maps %q to %q, which is not an Amplitude field", contextKey, key))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// checkRemoteCacheMode returns an error if a remote evaluation cache is configured
+// for local evaluation, where it is silently unused.
+func (c Config) checkRemoteCacheMode() error {
+	if c.RemoteEvaluationCache != nil && !c.usesRemoteEvaluation() {
+		return errors.New("the remote evaluation cache has no effect with local evaluation")
+	}
+	return nil
+}
+
+// usesRemoteEvaluation returns true if any flags are evaluated remotely.
+func (c Config) usesRemoteEvaluation() bool {
+	return c.RemoteConfig != nil || len(c.RemoteFlags) > 0
+}
@@ -0,0 +1,348 @@
+package amplitude
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	analytics "github.com/amplitude/analytics-go/amplitude"
+	"github.com/amplitude/experiment-go-server/pkg/experiment/local"
+	"github.com/amplitude/experiment-go-server/pkg/experiment/remote"
+	"github.com/amplitude/experiment-go-server/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name           string
+		config         Config
+		expectedErrors []string
+	}{
+		{
+			name:   "valid configuration",
+			config: Config{DeploymentKey: "test-key", LocalConfig: &local.Config{}},
+		},
+		{
+			name:   "default key map",
+			config: Config{DeploymentKey: "test-key", KeyMap: DefaultKeyMap()},
+		},
+		{
+			name:           "negative batch concurrency",
+			config:         Config{DeploymentKey: "test-key", BatchConcurrency: -1},
+			expectedErrors: []string{"the batch concurrency must not be negative, but is -1"},
+		},
+		{
+			name:           "negative exposure deduplication size",
+			config:         Config{DeploymentKey: "test-key", ExposureDeduplicationTTL: time.Hour, ExposureDeduplicationSize: -1},
+			expectedErrors: []string{"the exposure deduplication TTL (1h0m0s) and size (-1) must not be negative", "exposure deduplication requires tracking"},
+		},
+		{
+			name:           "negative exposure sink buffer size",
+			config:         Config{DeploymentKey: "test-key", ExposureSinkBufferSize: -1},
+			expectedErrors: []string{"the exposure sink buffer size must not be negative, but is -1"},
+		},
+		{
+			name:           "negative decision audit buffer size",
+			config:         Config{DeploymentKey: "test-key", DecisionAudit: &AuditConfig{Async: true, BufferSize: -1}},
+			expectedErrors: []string{"the decision audit buffer size must not be negative, but is -1"},
+		},
+		{
+			name: "combined exposure and assignment with separate assignment events",
+			config: Config{
+				DeploymentKey:              "test-key",
+				LocalConfig:                &local.Config{AssignmentConfig: &local.AssignmentConfig{}},
+				AnalyticsConfig:            &analytics.Config{APIKey: "api-key"},
+				CombinedExposureAssignment: true,
+			},
+			expectedErrors: []string{"combined exposure and assignment events can't be used with separate assignment events (the local assignment config)"},
+		},
+		{
+			name:           "combined exposure and assignment without tracking",
+			config:         Config{DeploymentKey: "test-key", CombinedExposureAssignment: true},
+			expectedErrors: []string{"combined exposure and assignment events require tracking"},
+		},
+		{
+			name: "combined exposure and assignment with remote evaluation",
+			config: Config{
+				DeploymentKey:              "test-key",
+				RemoteConfig:               &remote.Config{},
+				AnalyticsConfig:            &analytics.Config{APIKey: "api-key"},
+				CombinedExposureAssignment: true,
+			},
+			expectedErrors: []string{"combined exposure and assignment events require local evaluation"},
+		},
+		{
+			name:           "required event attribute",
+			config:         Config{DeploymentKey: "test-key", RequiredAttributes: map[string][]Key{"test-flag": {KeyCountry, KeyTime}}},
+			expectedErrors: []string{`the attributes required for flag test-flag must be user attributes, not the event field "time"`},
+		},
+		{
+			name:           "negative init retry delay",
+			config:         Config{DeploymentKey: "test-key", InitRetryAttempts: 3, InitRetryDelay: -time.Second},
+			expectedErrors: []string{"the init retry attempts (3) and delay (-1s) must not be negative"},
+		},
+		{
+			name:           "negative start timeout",
+			config:         Config{DeploymentKey: "test-key", StartTimeout: -time.Second},
+			expectedErrors: []string{"the start timeout must not be negative, but is -1s"},
+		},
+		{
+			name:           "negative remote fetch timeout",
+			config:         Config{DeploymentKey: "test-key", RemoteConfig: &remote.Config{}, RemoteFetchTimeout: -time.Second},
+			expectedErrors: []string{"the remote fetch timeout must be positive, but is -1s"},
+		},
+		{
+			name:           "remote fetch settings with local evaluation",
+			config:         Config{DeploymentKey: "test-key", RemoteFetchTimeout: time.Second},
+			expectedErrors: []string{"the remote fetch timeout and retries have no effect with local evaluation"},
+		},
+		{
+			name:           "remote readiness probe with local evaluation",
+			config:         Config{DeploymentKey: "test-key", RemoteReadinessProbe: true},
+			expectedErrors: []string{"the remote readiness probe has no effect with local evaluation"},
+		},
+		{
+			name:           "sticky bucketing on a non-string field",
+			config:         Config{DeploymentKey: "test-key", RemoteConfig: &remote.Config{}, StickyBucketingKeys: []Key{KeyUserID, KeyCohortIDs}},
+			expectedErrors: []string{`sticky bucketing keys must be string fields of the Amplitude user, not "cohort_ids"`},
+		},
+		{
+			name:           "sticky bucketing with local evaluation",
+			config:         Config{DeploymentKey: "test-key", StickyBucketingKeys: []Key{KeyUserID}},
+			expectedErrors: []string{"sticky bucketing requires remote evaluation"},
+		},
+		{
+			name: "tracking without an API key",
+			config: Config{
+				DeploymentKey:   "test-key",
+				AnalyticsConfig: &analytics.Config{},
+			},
+			expectedErrors: []string{"tracking requires an analytics API key"},
+		},
+		{
+			name: "remote evaluation cache with local evaluation",
+			config: Config{
+				DeploymentKey:         "test-key",
+				LocalConfig:           &local.Config{},
+				RemoteEvaluationCache: &syncCache{},
+			},
+			expectedErrors: []string{"the remote evaluation cache has no effect with local evaluation"},
+		},
+		{
+			name: "remote evaluation cache with remote evaluation",
+			config: Config{
+				DeploymentKey:         "test-key",
+				RemoteConfig:          &remote.Config{},
+				RemoteEvaluationCache: &syncCache{},
+			},
+		},
+		{
+			name: "remote evaluation codec without a cache",
+			config: Config{
+				DeploymentKey:         "test-key",
+				RemoteConfig:          &remote.Config{},
+				RemoteEvaluationCodec: &JSONCacheCodec,
+			},
+			expectedErrors: []string{"the remote evaluation codec requires a remote evaluation cache"},
+		},
+		{
+			name: "remote evaluation codec without functions",
+			config: Config{
+				DeploymentKey:         "test-key",
+				RemoteConfig:          &remote.Config{},
+				RemoteEvaluationCache: &syncCache{},
+				RemoteEvaluationCodec: &CacheCodec{Marshal: JSONCacheCodec.Marshal},
+			},
+			expectedErrors: []string{"the remote evaluation codec must have both Marshal and Unmarshal functions"},
+		},
+		{
+			name: "local and remote evaluation with remote flags",
+			config: Config{
+				DeploymentKey:         "test-key",
+				LocalConfig:           &local.Config{},
+				RemoteConfig:          &remote.Config{},
+				RemoteFlags:           []string{"remote-flag"},
+				RemoteEvaluationCache: &syncCache{},
+			},
+		},
+		{
+			name: "key map to an unknown field",
+			config: Config{
+				DeploymentKey: "test-key",
+				KeyMap:        map[string]Key{"userId": "userid"},
+			},
+			expectedErrors: []string{`the key map maps "userId" to "userid", which is not an Amplitude field`},
+		},
+		{
+			name: "key map remapping a field",
+			config: Config{
+				DeploymentKey: "test-key",
+				KeyMap:        map[string]Key{"country": KeyCity},
+			},
+			expectedErrors: []string{`the key map maps the Amplitude field "country" to a different field "city"`},
+		},
+		{
+			name: "all problems are reported together",
+			config: Config{
+				LocalConfig:           &local.Config{},
+				RemoteConfig:          &remote.Config{},
+				AnalyticsConfig:       &analytics.Config{},
+				TargetingKeyField:     KeyCountry,
+				KeyMap:                map[string]Key{"user": "usr"},
+				RemoteEvaluationCache: &syncCache{},
+			},
+			expectedErrors: []string{
+				"you must provide a deployment key",
+				"both local and remote evaluation",
+				"the targeting key can only be mapped to",
+				"tracking requires an analytics API key",
+				`the key map maps "user" to "usr"`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+
+			if len(tt.expectedErrors) == 0 {
+				require.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			for _, expected := range tt.expectedErrors {
+				assert.Contains(t, err.Error(), expected)
+			}
+		})
+	}
+}
+
+func TestValidateKeyMap(t *testing.T) {
+	t.Run("default key map", func(t *testing.T) {
+		assert.NoError(t, ValidateKeyMap(DefaultKeyMap()))
+	})
+
+	t.Run("unknown targets are listed", func(t *testing.T) {
+		err := ValidateKeyMap(map[string]Key{
+			"userId":  KeyUserID,
+			"country": "contry",
+			"os":      "operating_system",
+		})
+
+		require.Error(t, err)
+		assert.Equal(t, `the key map maps "country" to "contry", which is not an Amplitude field
+the key map maps "os" to "operating_system", which is not an Amplitude field`, err.Error())
+	})
+}
+
+func TestConfig_Validate_EmptyPayloadDefaults(t *testing.T) {
+	config := Config{
+		DeploymentKey: "test-key",
+		EmptyPayloadDefaults: map[reflect.Kind]any{
+			reflect.String:    UseVariantValue,
+			reflect.Int64:     1,
+			reflect.Float64:   0.5,
+			reflect.Interface: "anything",
+			reflect.Bool:      false,
+		},
+	}
+
+	err := config.Validate()
+
+	assert.ErrorContains(t, err, "empty payload defaults are not supported for bool values")
+	assert.ErrorContains(t, err, "the empty payload default for int64 values must be of type int64, not int")
+	assert.NotContains(t, err.Error(), "string values")
+	assert.NotContains(t, err.Error(), "float64 values")
+}
+
+func TestNewFromConfig_ConfigValidation(t *testing.T) {
+	newConfig := func(mode ValidationMode, loggerProvider logger.LoggerProvider) Config {
+		return Config{
+			DeploymentKey:         "test-key",
+			LocalConfig:           &local.Config{LogLevel: logger.Warn, LoggerProvider: loggerProvider},
+			RemoteEvaluationCache: &syncCache{},
+			KeyMap:                map[string]Key{"userId": "userid"},
+			ConfigValidation:      mode,
+		}
+	}
+
+	t.Run("disabled by default", func(t *testing.T) {
+		loggerProvider := &recordingLoggerProvider{}
+
+		provider, err := NewFromConfig(context.Background(), newConfig(ValidationDisabled, loggerProvider))
+
+		require.NoError(t, err)
+		assert.NotNil(t, provider)
+		require.Len(t, loggerProvider.warnings, 1, "only the unused cache is reported")
+		assert.NotContains(t, loggerProvider.warnings[0], "key map")
+	})
+
+	t.Run("warn logs the problems", func(t *testing.T) {
+		loggerProvider := &recordingLoggerProvider{}
+
+		provider, err := NewFromConfig(context.Background(), newConfig(ValidationWarn, loggerProvider))
+
+		require.NoError(t, err)
+		assert.NotNil(t, provider)
+		require.Len(t, loggerProvider.warnings, 1)
+		assert.Contains(t, loggerProvider.warnings[0], "the remote evaluation cache has no effect")
+		assert.Contains(t, loggerProvider.warnings[0], `the key map maps "userId"`)
+	})
+
+	t.Run("error fails with all the problems", func(t *testing.T) {
+		provider, err := NewFromConfig(context.Background(), newConfig(ValidationError, nil))
+
+		assert.Nil(t, provider)
+		assert.ErrorContains(t, err, "the remote evaluation cache has no effect")
+		assert.ErrorContains(t, err, `the key map maps "userId"`)
+	})
+
+	t.Run("invalid settings are always errors", func(t *testing.T) {
+		_, err := New(context.Background(), "test-key", WithTargetingKeyAs(KeyCountry), WithConfigValidation(ValidationDisabled))
+
+		assert.ErrorContains(t, err, "the targeting key can only be mapped to")
+	})
+}
+
+func TestNewFromConfig_RemoteCacheWithLocalEvaluation(t *testing.T) {
+	t.Run("warns by default", func(t *testing.T) {
+		loggerProvider := &recordingLoggerProvider{}
+
+		provider, err := NewFromConfig(context.Background(), Config{
+			DeploymentKey:         "test-key",
+			LocalConfig:           &local.Config{LogLevel: logger.Warn, LoggerProvider: loggerProvider},
+			RemoteEvaluationCache: &syncCache{},
+		})
+
+		require.NoError(t, err)
+		assert.NotNil(t, provider)
+		require.Len(t, loggerProvider.warnings, 1)
+		assert.Contains(t, loggerProvider.warnings[0], "the remote evaluation cache has no effect with local evaluation")
+	})
+
+	t.Run("fails under strict validation", func(t *testing.T) {
+		provider, err := New(context.Background(), "test-key",
+			WithRemoteEvaluationCache(&syncCache{}),
+			WithConfigValidation(ValidationError),
+		)
+
+		assert.Nil(t, provider)
+		assert.ErrorContains(t, err, "the remote evaluation cache has no effect with local evaluation")
+	})
+
+	t.Run("no warning with remote evaluation", func(t *testing.T) {
+		loggerProvider := &recordingLoggerProvider{}
+
+		provider, err := NewFromConfig(context.Background(), Config{
+			DeploymentKey:         "test-key",
+			RemoteConfig:          &remote.Config{LogLevel: logger.Warn, LoggerProvider: loggerProvider},
+			RemoteEvaluationCache: &syncCache{},
+		})
+
+		require.NoError(t, err)
+		assert.NotNil(t, provider)
+		assert.Empty(t, loggerProvider.warnings)
+	})
+}